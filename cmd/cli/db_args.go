@@ -5,6 +5,7 @@ import (
 	"flag"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/morphy76/g-fe-server/internal/options"
 )
@@ -13,6 +14,8 @@ type dbOptionsBuidler func() (*options.DbOptions, error)
 
 var errUnknownDbType = errors.New("unknown db type")
 var errRequiredMongoDbUrl = errors.New("mongo db url is required")
+var errRequiredSqlDsn = errors.New("sql data source name is required")
+var errInvalidCacheTTL = errors.New("cache time to live must be positive")
 
 func IsUnknownDbType(err error) bool {
 	return err == errUnknownDbType
@@ -22,6 +25,14 @@ func IsRequiredMongoDbUrl(err error) bool {
 	return err == errRequiredMongoDbUrl
 }
 
+func IsRequiredSqlDsn(err error) bool {
+	return err == errRequiredSqlDsn
+}
+
+func IsInvalidCacheTTL(err error) bool {
+	return err == errInvalidCacheTTL
+}
+
 const (
 	ENV_DB_TYPE                = "DB_TYPE"
 	ENV_DB_MONGO_URL           = "DB_MONGO_URL"
@@ -29,16 +40,36 @@ const (
 	ENV_DB_MONGO_PASS          = "DB_MONGO_PASSWORD"
 	ENV_DB_MONG_MAX_POOL_SIZE  = "DB_MONGO_MAX_POOL_SIZE"
 	ENV_DB_MONGO_MIN_POOL_SIZE = "DB_MONGO_MIN_POOL_SIZE"
+	ENV_DB_MONGO_MIGRATE       = "DB_MONGO_MIGRATE"
+	ENV_DB_SQL_DSN             = "DB_SQL_DSN"
+	ENV_DB_SQL_MAX_OPEN_CONNS  = "DB_SQL_MAX_OPEN_CONNS"
+	ENV_DB_MEMORY_SEED         = "DB_MEMORY_SEED"
+	ENV_DB_MEMORY_SNAPSHOT     = "DB_MEMORY_SNAPSHOT"
+	ENV_DB_MEMORY_SNAPSHOT_INT = "DB_MEMORY_SNAPSHOT_INTERVAL"
+	ENV_DB_CACHE_SIZE          = "DB_CACHE_SIZE"
+	ENV_DB_CACHE_TTL           = "DB_CACHE_TTL"
+	ENV_DB_CACHE_REDIS_URL     = "DB_CACHE_REDIS_URL"
+	ENV_DB_CACHE_REDIS_TTL     = "DB_CACHE_REDIS_TTL"
 )
 
 func DbOptionsBuilder() dbOptionsBuidler {
 
-	dbTypeArg := flag.String("db", "0", "type of the database: 0: memory - 1: mongo. Environment: "+ENV_DB_TYPE)
+	dbTypeArg := flag.String("db", "0", "type of the database: 0: memory - 1: mongo - 2: postgresql - 3: sqlite. Environment: "+ENV_DB_TYPE)
 	dbMongoUrlArg := flag.String("db-mongo-url", "", "mongo database URL in the form of mongodb://<user>:<pass>@<host>:<port>/<db>?<args>. Environment: "+ENV_DB_MONGO_URL)
 	dbMongoUserArg := flag.String("db-mongo-user", "", "mongo database username. Environment: "+ENV_DB_MONGO_USER)
 	dbMongoPasswordArg := flag.String("db-mongo-password", "", "mongo database password. Environment: "+ENV_DB_MONGO_PASS)
 	dbMongoMaxPoolSizeArg := flag.Uint64("db-mongo-max-pool-size", 100, "mongo database maximum pool size. Environment: "+ENV_DB_MONG_MAX_POOL_SIZE)
 	dbMongoMinPoolSizeArg := flag.Uint64("db-mongo-min-pool-size", 1, "mongo database minimum pool size. Environment: "+ENV_DB_MONGO_MIN_POOL_SIZE)
+	dbMongoMigrateArg := flag.Bool("db-mongo-migrate", false, "apply the pending mongo database migrations on start. Environment: "+ENV_DB_MONGO_MIGRATE)
+	dbSqlDsnArg := flag.String("db-sql-dsn", "", "postgresql connection string, e.g. postgres://<user>:<pass>@<host>:<port>/<db>, or sqlite file name. Environment: "+ENV_DB_SQL_DSN)
+	dbSqlMaxOpenConnsArg := flag.Int("db-sql-max-open-conns", 10, "maximum open connections to the sql database, sqlite always uses one. Environment: "+ENV_DB_SQL_MAX_OPEN_CONNS)
+	dbMemorySeedArg := flag.String("db-memory-seed", "", "JSON file with the initial data of the memory database, ignored when a snapshot is reloaded. Environment: "+ENV_DB_MEMORY_SEED)
+	dbMemorySnapshotArg := flag.String("db-memory-snapshot", "", "JSON file where the memory database is saved and reloaded from on start. Environment: "+ENV_DB_MEMORY_SNAPSHOT)
+	dbMemorySnapshotIntervalArg := flag.Duration("db-memory-snapshot-interval", time.Minute, "interval between snapshots of the memory database, 0 to save on shutdown only. Environment: "+ENV_DB_MEMORY_SNAPSHOT_INT)
+	dbCacheSizeArg := flag.Int("db-cache-size", 0, "entries of each in-process repository cache, 0 to disable. Environment: "+ENV_DB_CACHE_SIZE)
+	dbCacheTTLArg := flag.Duration("db-cache-ttl", 30*time.Second, "time to live of the in-process cache entries. Environment: "+ENV_DB_CACHE_TTL)
+	dbCacheRedisUrlArg := flag.String("db-cache-redis-url", "", "redis URL of the shared repository cache in the form of redis://<user>:<pass>@<host>:<port>/<db>, empty to disable. Environment: "+ENV_DB_CACHE_REDIS_URL)
+	dbCacheRedisTTLArg := flag.Duration("db-cache-redis-ttl", 5*time.Minute, "time to live of the shared cache entries. Environment: "+ENV_DB_CACHE_REDIS_TTL)
 
 	rv := func() (*options.DbOptions, error) {
 
@@ -46,7 +77,7 @@ func DbOptionsBuilder() dbOptionsBuidler {
 		if !found {
 			dbType = *dbTypeArg
 		}
-		if dbType != "0" && dbType != "1" {
+		if dbType != "0" && dbType != "1" && dbType != "2" && dbType != "3" {
 			return nil, errUnknownDbType
 		}
 
@@ -98,14 +129,116 @@ func DbOptionsBuilder() dbOptionsBuidler {
 			minPoolSizeAsInt = 1
 		}
 
+		migrate := *dbMongoMigrateArg
+		migrateStr, found := os.LookupEnv(ENV_DB_MONGO_MIGRATE)
+		if found {
+			migrate = migrateStr == "true"
+		}
+
+		dsn, found := os.LookupEnv(ENV_DB_SQL_DSN)
+		if !found {
+			dsn = *dbSqlDsnArg
+		}
+		if dsn == "" && useDbType.IsSQL() {
+			return nil, errRequiredSqlDsn
+		}
+
+		maxOpenConns := *dbSqlMaxOpenConnsArg
+		strMaxOpenConns, found := os.LookupEnv(ENV_DB_SQL_MAX_OPEN_CONNS)
+		if found {
+			maxOpenConns, err = strconv.Atoi(strMaxOpenConns)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if maxOpenConns <= 0 {
+			maxOpenConns = 10
+		}
+
+		seedFile, found := os.LookupEnv(ENV_DB_MEMORY_SEED)
+		if !found {
+			seedFile = *dbMemorySeedArg
+		}
+
+		snapshotFile, found := os.LookupEnv(ENV_DB_MEMORY_SNAPSHOT)
+		if !found {
+			snapshotFile = *dbMemorySnapshotArg
+		}
+
+		snapshotInterval := *dbMemorySnapshotIntervalArg
+		strSnapshotInterval, found := os.LookupEnv(ENV_DB_MEMORY_SNAPSHOT_INT)
+		if found {
+			snapshotInterval, err = time.ParseDuration(strSnapshotInterval)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if snapshotInterval < 0 {
+			snapshotInterval = 0
+		}
+
+		cacheSize := *dbCacheSizeArg
+		strCacheSize, found := os.LookupEnv(ENV_DB_CACHE_SIZE)
+		if found {
+			cacheSize, err = strconv.Atoi(strCacheSize)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if cacheSize < 0 {
+			cacheSize = 0
+		}
+
+		cacheTTL := *dbCacheTTLArg
+		strCacheTTL, found := os.LookupEnv(ENV_DB_CACHE_TTL)
+		if found {
+			cacheTTL, err = time.ParseDuration(strCacheTTL)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		cacheRedisUrl, found := os.LookupEnv(ENV_DB_CACHE_REDIS_URL)
+		if !found {
+			cacheRedisUrl = *dbCacheRedisUrlArg
+		}
+
+		cacheRedisTTL := *dbCacheRedisTTLArg
+		strCacheRedisTTL, found := os.LookupEnv(ENV_DB_CACHE_REDIS_TTL)
+		if found {
+			cacheRedisTTL, err = time.ParseDuration(strCacheRedisTTL)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if cacheTTL <= 0 || cacheRedisTTL <= 0 {
+			return nil, errInvalidCacheTTL
+		}
+
 		return &options.DbOptions{
 			Type: useDbType,
+			Cache: options.CacheOptions{
+				Size:     cacheSize,
+				TTL:      cacheTTL,
+				RedisUrl: cacheRedisUrl,
+				RedisTTL: cacheRedisTTL,
+			},
+			MemoryDbOptions: options.MemoryDbOptions{
+				SeedFile:         seedFile,
+				SnapshotFile:     snapshotFile,
+				SnapshotInterval: snapshotInterval,
+			},
 			MongoDbOptions: options.MongoDbOptions{
 				Url:         url,
 				User:        user,
 				Password:    password,
 				MaxPoolSize: maxPoolSizeAsInt,
 				MinPoolSize: minPoolSizeAsInt,
+				Migrate:     migrate,
+			},
+			SqlDbOptions: options.SqlDbOptions{
+				Dsn:          dsn,
+				MaxOpenConns: maxOpenConns,
 			},
 		}, nil
 	}
@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+type messagingOptionsBuidler func() (*options.MessagingOptions, error)
+
+const (
+	ENV_KAFKA_BROKERS        = "KAFKA_BROKERS"
+	ENV_KAFKA_CLIENT_ID      = "KAFKA_CLIENT_ID"
+	ENV_KAFKA_CONSUMER_GROUP = "KAFKA_CONSUMER_GROUP"
+	ENV_KAFKA_EXAMPLE_TOPIC  = "KAFKA_EXAMPLE_TOPIC"
+)
+
+func MessagingOptionsBuilder() messagingOptionsBuidler {
+
+	kafkaBrokersArg := flag.String("kafka-brokers", "", "comma separated kafka brokers, e.g. localhost:9092, empty to disable messaging. Environment: "+ENV_KAFKA_BROKERS)
+	kafkaClientIdArg := flag.String("kafka-client-id", "g-be-service", "kafka client id. Environment: "+ENV_KAFKA_CLIENT_ID)
+	kafkaConsumerGroupArg := flag.String("kafka-consumer-group", "g-be-service", "kafka consumer group of the service. Environment: "+ENV_KAFKA_CONSUMER_GROUP)
+	kafkaExampleTopicArg := flag.String("kafka-example-topic", "example-events", "kafka topic of the example change events. Environment: "+ENV_KAFKA_EXAMPLE_TOPIC)
+
+	rv := func() (*options.MessagingOptions, error) {
+
+		kafkaBrokers, found := os.LookupEnv(ENV_KAFKA_BROKERS)
+		if !found {
+			kafkaBrokers = *kafkaBrokersArg
+		}
+		brokers := make([]string, 0)
+		for _, broker := range strings.Split(kafkaBrokers, ",") {
+			if broker = strings.TrimSpace(broker); broker != "" {
+				brokers = append(brokers, broker)
+			}
+		}
+
+		clientId, found := os.LookupEnv(ENV_KAFKA_CLIENT_ID)
+		if !found {
+			clientId = *kafkaClientIdArg
+		}
+
+		consumerGroup, found := os.LookupEnv(ENV_KAFKA_CONSUMER_GROUP)
+		if !found {
+			consumerGroup = *kafkaConsumerGroupArg
+		}
+
+		exampleTopic, found := os.LookupEnv(ENV_KAFKA_EXAMPLE_TOPIC)
+		if !found {
+			exampleTopic = *kafkaExampleTopicArg
+		}
+
+		return &options.MessagingOptions{
+			Brokers:       brokers,
+			ClientId:      clientId,
+			ConsumerGroup: consumerGroup,
+			ExampleTopic:  exampleTopic,
+		}, nil
+	}
+
+	return rv
+}
@@ -14,6 +14,8 @@ type oidcOptionsBuidler func() (*options.OidcOptions, error)
 var errMissingIssuer = errors.New("OIDC issuer is required")
 var errMissingClientId = errors.New("OIDC client id is required")
 var errMissingClientSecret = errors.New("OIDC client secret is required")
+var errInvalidProfileClaims = errors.New("invalid OIDC profile claims mapping")
+var errUnmappedRolesField = errors.New("OIDC roles field is not mapped by the profile claims")
 
 func IsMissingIssuer(err error) bool {
 	return err == errMissingIssuer
@@ -27,13 +29,26 @@ func IsMissingClientSecret(err error) bool {
 	return err == errMissingClientSecret
 }
 
+func IsInvalidProfileClaims(err error) bool {
+	return err == errInvalidProfileClaims
+}
+
+func IsUnmappedRolesField(err error) bool {
+	return err == errUnmappedRolesField
+}
+
 const (
 	ENV_OIDC_ISSUER        = "OIDC_ISSUER"
 	ENV_OIDC_CLIENT_ID     = "OIDC_CLIENT_ID"
 	ENV_OIDC_CLIENT_SECRET = "OIDC_CLIENT_SECRET"
 	ENV_OIDC_SCOPES        = "OIDC_SCOPES"
+	ENV_OIDC_PROFILE       = "OIDC_PROFILE_CLAIMS"
+	ENV_OIDC_ROLES_FIELD   = "OIDC_ROLES_FIELD"
+	ENV_OIDC_ADMIN_ROLE    = "OIDC_ADMIN_ROLE"
 )
 
+const defaultProfileClaims = "email=email,family_name=family_name,given_name=given_name,name=name,preferred_username=preferred_username,roles=realm_access.roles,groups=groups,tenant=tenant"
+
 func OidcOptionsBuilder() oidcOptionsBuidler {
 
 	oidcDisabledArg := flag.Bool("oidc-disabled", false, "Disable OIDC.")
@@ -41,6 +56,9 @@ func OidcOptionsBuilder() oidcOptionsBuidler {
 	oidcClientIdArg := flag.String("oidc-client-id", " ", "OIDC client id. Environment: "+ENV_OIDC_CLIENT_ID)
 	oidcClientSecretArg := flag.String("oidc-client-secret", " ", "OIDC client secret. Environment: "+ENV_OIDC_CLIENT_SECRET)
 	oidcScopesArg := flag.String("oidc-scopes", " ", "OIDC scopes. Environment: "+ENV_OIDC_SCOPES)
+	oidcProfileArg := flag.String("oidc-profile-claims", defaultProfileClaims, "comma separated mapping of profile fields to ID token or userinfo claims, e.g. roles=realm_access.roles. Environment: "+ENV_OIDC_PROFILE)
+	oidcRolesFieldArg := flag.String("oidc-roles-field", "roles", "profile field, as mapped by the profile claims, holding the roles of the user. Environment: "+ENV_OIDC_ROLES_FIELD)
+	oidcAdminRoleArg := flag.String("oidc-admin-role", "admin", "role, as resolved in the profile roles, granting session administration. Environment: "+ENV_OIDC_ADMIN_ROLE)
 
 	rv := func() (*options.OidcOptions, error) {
 
@@ -75,14 +93,59 @@ func OidcOptionsBuilder() oidcOptionsBuidler {
 			oidcScopes = *oidcScopesArg
 		}
 
+		oidcProfile, found := os.LookupEnv(ENV_OIDC_PROFILE)
+		if !found {
+			oidcProfile = *oidcProfileArg
+		}
+		profileClaims, err := parseProfileClaims(oidcProfile)
+		if err != nil {
+			return nil, err
+		}
+
+		oidcRolesField, found := os.LookupEnv(ENV_OIDC_ROLES_FIELD)
+		if !found {
+			oidcRolesField = *oidcRolesFieldArg
+		}
+		if _, mapped := profileClaims[oidcRolesField]; !oidcDisabled && !mapped {
+			return nil, errUnmappedRolesField
+		}
+
+		oidcAdminRole, found := os.LookupEnv(ENV_OIDC_ADMIN_ROLE)
+		if !found {
+			oidcAdminRole = *oidcAdminRoleArg
+		}
+
 		return &options.OidcOptions{
-			Disabled:     oidcDisabled,
-			Issuer:       oidcIssuer,
-			ClientId:     oidcClientId,
-			ClientSecret: oidcClientSecret,
-			Scopes:       strings.Split(oidcScopes, ","),
+			Disabled:      oidcDisabled,
+			Issuer:        oidcIssuer,
+			ClientId:      oidcClientId,
+			ClientSecret:  oidcClientSecret,
+			Scopes:        strings.Split(oidcScopes, ","),
+			ProfileClaims: profileClaims,
+			RolesField:    oidcRolesField,
+			AdminRole:     oidcAdminRole,
 		}, nil
 	}
 
 	return rv
 }
+
+func parseProfileClaims(mapping string) (map[string]string, error) {
+
+	rv := make(map[string]string)
+	for _, entry := range strings.Split(mapping, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		target, claim, found := strings.Cut(entry, "=")
+		target = strings.TrimSpace(target)
+		claim = strings.TrimSpace(claim)
+		if !found || target == "" || claim == "" {
+			return nil, errInvalidProfileClaims
+		}
+		rv[target] = claim
+	}
+
+	return rv, nil
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+type outboxOptionsBuidler func() (*options.OutboxOptions, error)
+
+var errUnknownOutboxPublisher = errors.New("unknown outbox publisher")
+var errRequiredOutboxWebhookUrl = errors.New("outbox webhook url is required")
+var errInvalidOutboxInterval = errors.New("outbox intervals must be positive")
+
+func IsUnknownOutboxPublisher(err error) bool {
+	return err == errUnknownOutboxPublisher
+}
+
+func IsRequiredOutboxWebhookUrl(err error) bool {
+	return err == errRequiredOutboxWebhookUrl
+}
+
+func IsInvalidOutboxInterval(err error) bool {
+	return err == errInvalidOutboxInterval
+}
+
+const (
+	ENV_OUTBOX_ENABLED       = "OUTBOX_ENABLED"
+	ENV_OUTBOX_PUBLISHER     = "OUTBOX_PUBLISHER"
+	ENV_OUTBOX_WEBHOOK_URL   = "OUTBOX_WEBHOOK_URL"
+	ENV_OUTBOX_POLL_INTERVAL = "OUTBOX_POLL_INTERVAL"
+	ENV_OUTBOX_BATCH_SIZE    = "OUTBOX_BATCH_SIZE"
+	ENV_OUTBOX_RETRY_BACKOFF = "OUTBOX_RETRY_BACKOFF"
+	ENV_OUTBOX_MAX_BACKOFF   = "OUTBOX_MAX_BACKOFF"
+)
+
+func OutboxOptionsBuilder() outboxOptionsBuidler {
+
+	outboxEnabledArg := flag.Bool("outbox-enabled", false, "append the changes of the examples to the outbox and relay them downstream. Environment: "+ENV_OUTBOX_ENABLED)
+	outboxPublisherArg := flag.String("outbox-publisher", string(options.OutboxPublisherLog), "publisher of the outbox events: log - webhook - kafka, which requires the kafka brokers. Environment: "+ENV_OUTBOX_PUBLISHER)
+	outboxWebhookUrlArg := flag.String("outbox-webhook-url", "", "URL receiving the outbox events with the webhook publisher. Environment: "+ENV_OUTBOX_WEBHOOK_URL)
+	outboxPollIntervalArg := flag.Duration("outbox-poll-interval", time.Second, "interval between the polls of the outbox relay. Environment: "+ENV_OUTBOX_POLL_INTERVAL)
+	outboxBatchSizeArg := flag.Int("outbox-batch-size", 100, "events claimed by the outbox relay on every poll. Environment: "+ENV_OUTBOX_BATCH_SIZE)
+	outboxRetryBackoffArg := flag.Duration("outbox-retry-backoff", time.Second, "delay before the first retry of a failed event, doubled on every attempt. Environment: "+ENV_OUTBOX_RETRY_BACKOFF)
+	outboxMaxBackoffArg := flag.Duration("outbox-max-backoff", 5*time.Minute, "maximum delay between the retries of a failed event. Environment: "+ENV_OUTBOX_MAX_BACKOFF)
+
+	rv := func() (*options.OutboxOptions, error) {
+
+		enabled := *outboxEnabledArg
+		enabledStr, found := os.LookupEnv(ENV_OUTBOX_ENABLED)
+		if found {
+			enabled = enabledStr == "true"
+		}
+
+		publisher, found := os.LookupEnv(ENV_OUTBOX_PUBLISHER)
+		if !found {
+			publisher = *outboxPublisherArg
+		}
+		usePublisher := options.OutboxPublisher(publisher)
+		switch usePublisher {
+		case options.OutboxPublisherLog, options.OutboxPublisherWebhook, options.OutboxPublisherKafka:
+		default:
+			return nil, errUnknownOutboxPublisher
+		}
+
+		webhookUrl, found := os.LookupEnv(ENV_OUTBOX_WEBHOOK_URL)
+		if !found {
+			webhookUrl = *outboxWebhookUrlArg
+		}
+		if enabled && usePublisher == options.OutboxPublisherWebhook && webhookUrl == "" {
+			return nil, errRequiredOutboxWebhookUrl
+		}
+
+		var err error
+		pollInterval := *outboxPollIntervalArg
+		strPollInterval, found := os.LookupEnv(ENV_OUTBOX_POLL_INTERVAL)
+		if found {
+			pollInterval, err = time.ParseDuration(strPollInterval)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		batchSize := *outboxBatchSizeArg
+		strBatchSize, found := os.LookupEnv(ENV_OUTBOX_BATCH_SIZE)
+		if found {
+			batchSize, err = strconv.Atoi(strBatchSize)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if batchSize <= 0 {
+			batchSize = 100
+		}
+
+		retryBackoff := *outboxRetryBackoffArg
+		strRetryBackoff, found := os.LookupEnv(ENV_OUTBOX_RETRY_BACKOFF)
+		if found {
+			retryBackoff, err = time.ParseDuration(strRetryBackoff)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		maxBackoff := *outboxMaxBackoffArg
+		strMaxBackoff, found := os.LookupEnv(ENV_OUTBOX_MAX_BACKOFF)
+		if found {
+			maxBackoff, err = time.ParseDuration(strMaxBackoff)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if pollInterval <= 0 || retryBackoff <= 0 || maxBackoff < retryBackoff {
+			return nil, errInvalidOutboxInterval
+		}
+
+		return &options.OutboxOptions{
+			Enabled:      enabled,
+			Publisher:    usePublisher,
+			WebhookUrl:   webhookUrl,
+			PollInterval: pollInterval,
+			BatchSize:    batchSize,
+			RetryBackoff: retryBackoff,
+			MaxBackoff:   maxBackoff,
+		}, nil
+	}
+
+	return rv
+}
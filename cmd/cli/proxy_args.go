@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+type proxyOptionsBuilder func() (*options.ProxyOptions, error)
+
+var errInvalidHeaderPolicy = errors.New("invalid header policy")
+
+func IsInvalidHeaderPolicy(err error) bool {
+	return errors.Is(err, errInvalidHeaderPolicy)
+}
+
+const (
+	ENV_PROXY_HEADERS = "PROXY_HEADERS"
+)
+
+// hopByHopHeaders belong to a single connection, the proxy always strips them
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+func ProxyOptionsBuilder() proxyOptionsBuilder {
+
+	proxyHeadersArg := flag.String("proxy-headers", "", "JSON file with the header policies of the proxied resources. Environment: "+ENV_PROXY_HEADERS)
+
+	rv := func() (*options.ProxyOptions, error) {
+
+		proxyHeaders, found := os.LookupEnv(ENV_PROXY_HEADERS)
+		if !found {
+			proxyHeaders = *proxyHeadersArg
+		}
+
+		rv := &options.ProxyOptions{
+			HeaderPolicies: make(map[string]options.ResourceHeaderPolicy),
+		}
+		if proxyHeaders == "" {
+			return rv, nil
+		}
+
+		content, err := os.ReadFile(proxyHeaders)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(content, &rv.HeaderPolicies); err != nil {
+			return nil, fmt.Errorf("%w: %s", errInvalidHeaderPolicy, err)
+		}
+
+		for resource, policy := range rv.HeaderPolicies {
+			if err := validateHeaderPolicy(policy.Request); err != nil {
+				return nil, fmt.Errorf("%w: %s request: %s", errInvalidHeaderPolicy, resource, err)
+			}
+			if err := validateHeaderPolicy(policy.Response); err != nil {
+				return nil, fmt.Errorf("%w: %s response: %s", errInvalidHeaderPolicy, resource, err)
+			}
+		}
+
+		return rv, nil
+	}
+
+	return rv
+}
+
+func validateHeaderPolicy(policy options.HeaderPolicy) error {
+	for name, value := range policy.Add {
+		if slices.Contains(hopByHopHeaders, http.CanonicalHeaderKey(name)) {
+			return fmt.Errorf("hop-by-hop header %s cannot be added", name)
+		}
+		for _, placeholder := range options.PlaceholdersOf(value) {
+			if !slices.Contains(options.HeaderPlaceholders, placeholder) {
+				return fmt.Errorf("unknown placeholder {%s} in header %s", placeholder, name)
+			}
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/securecookie"
 	"github.com/morphy76/g-fe-server/internal/options"
@@ -44,6 +45,8 @@ const (
 	ENV_SESSION_SAME_SITE = "SESSION_SAME_SITE"
 	ENV_ANNOUNCING_PORT   = "ANNOUNCING_PORT"
 	EVN_ANNOUNCING_HOST   = "ANNOUNCING_HOST"
+	ENV_API_AUTH_REQUIRED = "API_AUTH_REQUIRED"
+	ENV_WS_IDLE_TIMEOUT   = "WS_IDLE_TIMEOUT"
 )
 
 func ServeOptionsBuilder() serveOptionsBuilder {
@@ -61,6 +64,8 @@ func ServeOptionsBuilder() serveOptionsBuilder {
 	sessionSameSiteArg := flag.String("session-same-site", "Lax", "session same site: Default, Lax, Strict or None. Environment: "+ENV_SESSION_SAME_SITE)
 	announcePortArg := flag.String("announce-port", "9999", "port to announce the server on. Environment: "+ENV_ANNOUNCING_PORT)
 	announceHostArg := flag.String("announce-host", "", "host to announce the server on, Use an headless service in k8s. Environment: "+EVN_ANNOUNCING_HOST)
+	apiAuthRequiredArg := flag.Bool("api-auth-required", false, "reject API calls without a bearer token or an API key. Environment: "+ENV_API_AUTH_REQUIRED)
+	wsIdleTimeoutArg := flag.Duration("ws-idle-timeout", 60*time.Second, "close proxied WebSocket connections idle for longer, 0 to disable. Environment: "+ENV_WS_IDLE_TIMEOUT)
 
 	rv := func() (*options.ServeOptions, error) {
 
@@ -166,6 +171,26 @@ func ServeOptionsBuilder() serveOptionsBuilder {
 			announceHost = *announceHostArg
 		}
 
+		var useApiAuthRequired bool
+		strApiAuthRequired, found := os.LookupEnv(ENV_API_AUTH_REQUIRED)
+		if !found {
+			useApiAuthRequired = *apiAuthRequiredArg
+		} else {
+			useApiAuthRequired = strApiAuthRequired == "true"
+		}
+
+		var useWsIdleTimeout time.Duration
+		strWsIdleTimeout, found := os.LookupEnv(ENV_WS_IDLE_TIMEOUT)
+		if !found {
+			useWsIdleTimeout = *wsIdleTimeoutArg
+		} else {
+			idleTimeout, err := time.ParseDuration(strWsIdleTimeout)
+			if err != nil {
+				return nil, err
+			}
+			useWsIdleTimeout = idleTimeout
+		}
+
 		return &options.ServeOptions{
 			ContextRoot:          ctxRoot,
 			StaticPath:           staticPath,
@@ -181,6 +206,8 @@ func ServeOptionsBuilder() serveOptionsBuilder {
 			SessionSameSite:      useSessionSameSite,
 			AnnouncePort:         announcePort,
 			AnnounceHost:         announceHost,
+			ApiAuthRequired:      useApiAuthRequired,
+			WebSocketIdleTimeout: useWsIdleTimeout,
 		}, nil
 	}
 
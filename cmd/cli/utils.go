@@ -18,7 +18,7 @@ func CreateTheOIDCContext(parentContext context.Context, oidcOptions *options.Oi
 		log.Trace().
 			Msg("OIDC disabled")
 	} else {
-		relyingParty, err := serve.SetupOIDC(serveOptions, oidcOptions)
+		relyingParty, err := serve.SetupOIDC(serveOptions, oidcOptions, app_http.ExtractOidcNonce)
 		if err != nil {
 			panic(err)
 		}
@@ -0,0 +1,130 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+type webhooksOptionsBuidler func() (*options.WebhooksOptions, error)
+
+var errInvalidWebhooksInterval = errors.New("webhooks intervals must be positive and the timeout below a minute")
+var errInvalidWebhooksAttempts = errors.New("webhooks attempts must be positive")
+
+func IsInvalidWebhooksInterval(err error) bool {
+	return err == errInvalidWebhooksInterval
+}
+
+func IsInvalidWebhooksAttempts(err error) bool {
+	return err == errInvalidWebhooksAttempts
+}
+
+const (
+	ENV_WEBHOOKS_ENABLED       = "WEBHOOKS_ENABLED"
+	ENV_WEBHOOKS_POLL_INTERVAL = "WEBHOOKS_POLL_INTERVAL"
+	ENV_WEBHOOKS_BATCH_SIZE    = "WEBHOOKS_BATCH_SIZE"
+	ENV_WEBHOOKS_TIMEOUT       = "WEBHOOKS_TIMEOUT"
+	ENV_WEBHOOKS_RETRY_BACKOFF = "WEBHOOKS_RETRY_BACKOFF"
+	ENV_WEBHOOKS_MAX_BACKOFF   = "WEBHOOKS_MAX_BACKOFF"
+	ENV_WEBHOOKS_MAX_ATTEMPTS  = "WEBHOOKS_MAX_ATTEMPTS"
+)
+
+func WebhooksOptionsBuilder() webhooksOptionsBuidler {
+
+	webhooksEnabledArg := flag.Bool("webhooks-enabled", false, "let tenants register webhooks notified of the outbox events, requires the outbox. Environment: "+ENV_WEBHOOKS_ENABLED)
+	webhooksPollIntervalArg := flag.Duration("webhooks-poll-interval", time.Second, "interval between the polls of the webhook dispatcher. Environment: "+ENV_WEBHOOKS_POLL_INTERVAL)
+	webhooksBatchSizeArg := flag.Int("webhooks-batch-size", 50, "deliveries claimed by the webhook dispatcher on every poll. Environment: "+ENV_WEBHOOKS_BATCH_SIZE)
+	webhooksTimeoutArg := flag.Duration("webhooks-timeout", 10*time.Second, "timeout of a webhook post. Environment: "+ENV_WEBHOOKS_TIMEOUT)
+	webhooksRetryBackoffArg := flag.Duration("webhooks-retry-backoff", 5*time.Second, "delay before the first retry of a failed delivery, doubled on every attempt. Environment: "+ENV_WEBHOOKS_RETRY_BACKOFF)
+	webhooksMaxBackoffArg := flag.Duration("webhooks-max-backoff", time.Hour, "maximum delay between the retries of a failed delivery. Environment: "+ENV_WEBHOOKS_MAX_BACKOFF)
+	webhooksMaxAttemptsArg := flag.Int("webhooks-max-attempts", 10, "attempts of a delivery before it is failed until replayed. Environment: "+ENV_WEBHOOKS_MAX_ATTEMPTS)
+
+	rv := func() (*options.WebhooksOptions, error) {
+
+		enabled := *webhooksEnabledArg
+		enabledStr, found := os.LookupEnv(ENV_WEBHOOKS_ENABLED)
+		if found {
+			enabled = enabledStr == "true"
+		}
+
+		var err error
+		pollInterval := *webhooksPollIntervalArg
+		strPollInterval, found := os.LookupEnv(ENV_WEBHOOKS_POLL_INTERVAL)
+		if found {
+			pollInterval, err = time.ParseDuration(strPollInterval)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		batchSize := *webhooksBatchSizeArg
+		strBatchSize, found := os.LookupEnv(ENV_WEBHOOKS_BATCH_SIZE)
+		if found {
+			batchSize, err = strconv.Atoi(strBatchSize)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if batchSize <= 0 {
+			batchSize = 50
+		}
+
+		timeout := *webhooksTimeoutArg
+		strTimeout, found := os.LookupEnv(ENV_WEBHOOKS_TIMEOUT)
+		if found {
+			timeout, err = time.ParseDuration(strTimeout)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		retryBackoff := *webhooksRetryBackoffArg
+		strRetryBackoff, found := os.LookupEnv(ENV_WEBHOOKS_RETRY_BACKOFF)
+		if found {
+			retryBackoff, err = time.ParseDuration(strRetryBackoff)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		maxBackoff := *webhooksMaxBackoffArg
+		strMaxBackoff, found := os.LookupEnv(ENV_WEBHOOKS_MAX_BACKOFF)
+		if found {
+			maxBackoff, err = time.ParseDuration(strMaxBackoff)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if pollInterval <= 0 || timeout <= 0 || timeout >= time.Minute || retryBackoff <= 0 || maxBackoff < retryBackoff {
+			return nil, errInvalidWebhooksInterval
+		}
+
+		maxAttempts := *webhooksMaxAttemptsArg
+		strMaxAttempts, found := os.LookupEnv(ENV_WEBHOOKS_MAX_ATTEMPTS)
+		if found {
+			maxAttempts, err = strconv.Atoi(strMaxAttempts)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if maxAttempts <= 0 {
+			return nil, errInvalidWebhooksAttempts
+		}
+
+		return &options.WebhooksOptions{
+			Enabled:      enabled,
+			PollInterval: pollInterval,
+			BatchSize:    batchSize,
+			Timeout:      timeout,
+			RetryBackoff: retryBackoff,
+			MaxBackoff:   maxBackoff,
+			MaxAttempts:  maxAttempts,
+		}, nil
+	}
+
+	return rv
+}
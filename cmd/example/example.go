@@ -14,12 +14,19 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"github.com/morphy76/g-fe-server/cmd/cli"
+	"github.com/morphy76/g-fe-server/internal/cache"
 	"github.com/morphy76/g-fe-server/internal/db"
 	"github.com/morphy76/g-fe-server/internal/example"
 	"github.com/morphy76/g-fe-server/internal/example/api"
 	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/messaging"
 	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/outbox"
+	outbox_repository "github.com/morphy76/g-fe-server/internal/outbox/repository"
 	"github.com/morphy76/g-fe-server/internal/serve"
+	"github.com/morphy76/g-fe-server/internal/webhook"
+	webhook_repository "github.com/morphy76/g-fe-server/internal/webhook/repository"
+	outbox_model "github.com/morphy76/g-fe-server/pkg/outbox"
 )
 
 func main() {
@@ -32,6 +39,9 @@ func main() {
 	otelOptionsBuilder := cli.OtelOptionsBuilder()
 	oidcOptionsBuilder := cli.OidcOptionsBuilder()
 	dbOptionsBuilder := cli.DbOptionsBuilder()
+	outboxOptionsBuilder := cli.OutboxOptionsBuilder()
+	messagingOptionsBuilder := cli.MessagingOptionsBuilder()
+	webhooksOptionsBuilder := cli.WebhooksOptionsBuilder()
 
 	help := flag.Bool("help", false, "prints help message")
 
@@ -47,6 +57,20 @@ func main() {
 		zerolog.SetGlobalLevel(zerolog.TraceLevel)
 	}
 
+	if flag.Arg(0) == "migrate" {
+		dbOptions, err := dbOptionsBuilder()
+		if err == nil {
+			err = migrate(dbOptions, flag.Args()[1:])
+		}
+		if err != nil {
+			log.Error().
+				Err(err).
+				Msg("Migration failed")
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	callbackUrl, found := os.LookupEnv("CALLBACK_URL")
 	if !found {
 		callbackUrl = *callbackUrlArg
@@ -92,11 +116,47 @@ func main() {
 		os.Exit(1)
 	}
 
+	outboxOptions, err := outboxOptionsBuilder()
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Error parsing outbox options")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	messagingOptions, err := messagingOptionsBuilder()
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Error parsing messaging options")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	webhooksOptions, err := webhooksOptionsBuilder()
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Error parsing webhooks options")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if webhooksOptions.Enabled && !outboxOptions.Enabled {
+		log.Error().
+			Msg("Webhooks require the outbox")
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	startServer(
 		serveOptions,
 		otelOptions,
 		oidcOptions,
 		dbOptions,
+		outboxOptions,
+		messagingOptions,
+		webhooksOptions,
 	)
 }
 
@@ -105,6 +165,9 @@ func startServer(
 	otelOptions *options.OtelOptions,
 	oidcOptions *options.OidcOptions,
 	dbOptions *options.DbOptions,
+	outboxOptions *options.OutboxOptions,
+	messagingOptions *options.MessagingOptions,
+	webhooksOptions *options.WebhooksOptions,
 ) {
 	start := time.Now()
 
@@ -121,14 +184,77 @@ func startServer(
 	if err != nil {
 		panic(err)
 	}
+	if err := migrateOnStart(dbOptions, dbClient); err != nil {
+		panic(err)
+	}
+	if memoryClient, ok := dbClient.(*db.MemoryDbClient); ok {
+		defer func() {
+			if err := memoryClient.Close(); err != nil {
+				log.Error().Err(err).Msg("Memory snapshot failed")
+			}
+		}()
+	}
+
+	repositoryCache, err := cache.New(dbOptions.Cache)
+	if err != nil {
+		panic(err)
+	}
+	defer repositoryCache.Close()
 
 	serverContext := app_http.InjectServeOptions(initialContext, serveOptions)
 	oidOptionsContext := app_http.InjectOidcOptions(serverContext, oidcOptions)
 	oidcContext := cli.CreateTheOIDCContext(oidOptionsContext, oidcOptions, serveOptions)
-	finalContext := db.InjectDb(db.InjectDbOptions(oidcContext, dbOptions), dbClient)
+	dbContext := cache.Inject(db.InjectDb(db.InjectDbOptions(oidcContext, dbOptions), dbClient), repositoryCache)
+	finalContext := webhook.InjectOptions(outbox.InjectOptions(dbContext, outboxOptions), webhooksOptions)
+
+	var broker messaging.Broker
+	if messagingOptions.Enabled() {
+		broker, err = messaging.NewKafkaBroker(messagingOptions)
+		if err != nil {
+			panic(err)
+		}
+		defer broker.Close()
+		finalContext = messaging.Inject(finalContext, broker)
+	}
 	log.Trace().
 		Msg("Application contextes ready")
 
+	if broker != nil {
+		consumer := example.NewEventsConsumer(broker, messagingOptions)
+		go func() {
+			if err := consumer.Run(finalContext); err != nil {
+				log.Error().Err(err).Msg("Example events consumer stopped")
+			}
+		}()
+		log.Trace().
+			Strs("brokers", messagingOptions.Brokers).
+			Str("group", messagingOptions.ConsumerGroup).
+			Msg("Example events consumer started")
+	}
+
+	if outboxOptions.Enabled {
+		relay, publisher, err := newOutboxRelay(finalContext, outboxOptions, messagingOptions, broker)
+		if err != nil {
+			panic(err)
+		}
+		defer publisher.Close()
+		go relay.Run(finalContext)
+		log.Trace().
+			Str("publisher", publisher.Name()).
+			Msg("Outbox relay started")
+	}
+
+	if webhooksOptions.Enabled {
+		webhookRepository, err := webhook_repository.NewRepository(finalContext)
+		if err != nil {
+			panic(err)
+		}
+		dispatcher := webhook.NewDispatcher(webhookRepository, webhook.NewClient(*webhooksOptions), *webhooksOptions)
+		go dispatcher.Run(finalContext)
+		log.Trace().
+			Msg("Webhook dispatcher started")
+	}
+
 	rootRouter := mux.NewRouter()
 	example.Handler(rootRouter, finalContext)
 	if log.Trace().Enabled() {
@@ -173,3 +299,32 @@ func startServer(
 		stop()
 	}
 }
+
+// newOutboxRelay builds the relay of the outbox of the storage of the application context
+func newOutboxRelay(
+	appContext context.Context,
+	outboxOptions *options.OutboxOptions,
+	messagingOptions *options.MessagingOptions,
+	broker messaging.Broker,
+) (*outbox.Relay, outbox_model.Publisher, error) {
+
+	outboxRepository, _, err := outbox_repository.NewRepository(appContext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publisher, err := outbox.NewPublisher(outboxOptions, messagingOptions, broker)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if webhook.Enabled(appContext) {
+		webhookRepository, err := webhook_repository.NewRepository(appContext)
+		if err != nil {
+			return nil, nil, err
+		}
+		publisher = webhook.NewFanoutPublisher(publisher, webhookRepository)
+	}
+
+	return outbox.NewRelay(outboxRepository, publisher, *outboxOptions), publisher, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up | down [steps] | status")
+
+// migrate runs the migrate up, down and status commands against the mongo database of the options
+func migrate(dbOptions *options.DbOptions, args []string) error {
+
+	if dbOptions.Type != options.RepositoryTypeMongoDB {
+		return fmt.Errorf("migrations are managed by this command for mongo only, sql schemas are migrated on start")
+	}
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	timeoutContext, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	database, disconnect, err := connectMongoDatabase(dbOptions)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+
+	switch args[0] {
+	case "up":
+		applied, err := db.MongoMigrateUp(timeoutContext, database)
+		if err != nil {
+			return err
+		}
+		log.Info().Ints64("versions", applied).Msg("Migrations applied")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps <= 0 {
+				return errMigrateUsage
+			}
+		}
+		reverted, err := db.MongoMigrateDown(timeoutContext, database, steps)
+		if err != nil {
+			return err
+		}
+		log.Info().Ints64("versions", reverted).Msg("Migrations reverted")
+	case "status":
+		statuses, err := db.MongoMigrationStatus(timeoutContext, database)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(os.Stdout, "%4d  %-20s  %s\n", status.Version, appliedAt, status.Description)
+		}
+	default:
+		return errMigrateUsage
+	}
+
+	return nil
+}
+
+// migrateOnStart applies the pending migrations of the mongo client when the options ask for it
+func migrateOnStart(dbOptions *options.DbOptions, dbClient db.DbClient) error {
+
+	mongoClient, ok := dbClient.(*mongo.Client)
+	if !ok || !dbOptions.Migrate {
+		return nil
+	}
+
+	database, err := db.MongoDatabase(mongoClient, dbOptions)
+	if err != nil {
+		return err
+	}
+
+	timeoutContext, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	_, err = db.MongoMigrateUp(timeoutContext, database)
+	return err
+}
+
+func connectMongoDatabase(dbOptions *options.DbOptions) (*mongo.Database, func(), error) {
+
+	dbClient, err := db.NewClient(dbOptions)
+	if err != nil {
+		return nil, nil, err
+	}
+	mongoClient := dbClient.(*mongo.Client)
+	disconnect := func() {
+		if err := mongoClient.Disconnect(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("Mongo disconnection failed")
+		}
+	}
+
+	database, err := db.MongoDatabase(mongoClient, dbOptions)
+	if err != nil {
+		disconnect()
+		return nil, nil, err
+	}
+	return database, disconnect, nil
+}
@@ -30,6 +30,7 @@ func main() {
 	serveOptionsBuilder := cli.ServeOptionsBuilder()
 	otelOptionsBuilder := cli.OtelOptionsBuilder()
 	oidcOptionsBuilder := cli.OidcOptionsBuilder()
+	proxyOptionsBuilder := cli.ProxyOptionsBuilder()
 
 	help := flag.Bool("help", false, "prints help message")
 
@@ -72,10 +73,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	proxyOptions, err := proxyOptionsBuilder()
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Error parsing proxy options")
+		flag.Usage()
+		os.Exit(1)
+	}
+
 	startServer(
 		serveOptions,
 		otelOptions,
 		oidcOptions,
+		proxyOptions,
 	)
 }
 
@@ -83,6 +94,7 @@ func startServer(
 	serveOptions *options.ServeOptions,
 	otelOptions *options.OtelOptions,
 	oidcOptions *options.OidcOptions,
+	proxyOptions *options.ProxyOptions,
 ) {
 	start := time.Now()
 
@@ -100,6 +112,7 @@ func startServer(
 	serverContext := app_http.InjectServeOptions(initialContext, serveOptions)
 	oidcOptionsContext := app_http.InjectOidcOptions(serverContext, oidcOptions)
 	sessionStoreContext := app_http.InjectSessionStore(oidcOptionsContext, sessionStore)
+	sessionStoreContext = app_http.InjectSessionIndex(sessionStoreContext, serve.NewSessionIndex(time.Duration(serveOptions.SessionMaxAge)*time.Second))
 	finalContext := cli.CreateTheOIDCContext(sessionStoreContext, oidcOptions, serveOptions)
 	log.Trace().
 		Msg("Application contextes ready")
@@ -135,7 +148,7 @@ func startServer(
 
 	go func() {
 		for newRoute := range newRoutesCh {
-			server.ProxyRoute(serveOptions.ContextRoot, apiRouter, string(newRoute))
+			server.ProxyRoute(serveOptions.ContextRoot, apiRouter, string(newRoute), proxyOptions)
 		}
 	}()
 
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/morphy76/g-fe-server/internal/apikey/repository"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	model "github.com/morphy76/g-fe-server/pkg/apikey"
+)
+
+type ContextualizedApiHandler func(zerolog.Logger, model.Repository) http.HandlerFunc
+
+func ContextualizedApi(apiHandler ContextualizedApiHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog := app_http.ExtractLogger(r.Context(), "apikey")
+		apiKeyRepository, err := repository.NewRepository(r.Context())
+		if err != nil {
+			useLog.Error().
+				Err(err).
+				Msg("Failed to create repository")
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to create repository")
+			return
+		}
+
+		apiHandler(useLog, apiKeyRepository)(w, r)
+	}
+}
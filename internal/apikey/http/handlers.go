@@ -0,0 +1,255 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/internal/apikey/api"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/pkg/apikey"
+)
+
+const (
+	pathParamApiKeyId = "apiKeyId"
+)
+
+type createRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+type createResponse struct {
+	apikey.ApiKey
+	Key string `json:"key"`
+}
+
+func ApiKeyHandlers(functionalRouter *mux.Router, app_context context.Context) {
+
+	serveOptions := app_http.ExtractServeOptions(app_context)
+	ctxRoot := serveOptions.ContextRoot
+
+	var (
+		apiRoot             = fmt.Sprintf("%s/api/apikeys", ctxRoot)
+		apiParamApiKeyId    = fmt.Sprintf("{%s}", pathParamApiKeyId)
+		apiResourceApiKeyId = fmt.Sprintf("%s/%s", apiRoot, apiParamApiKeyId)
+
+		keyRouter = functionalRouter.PathPrefix("/apikeys").Subrouter()
+	)
+	keyRouter.Use(RequirePrincipal)
+	keyRouter.Use(RequireScope(SCOPE_MANAGE))
+
+	keyRouter.Methods(http.MethodGet).HandlerFunc(onList).Path("").Name("GET " + apiRoot)
+	keyRouter.Methods(http.MethodPost).HandlerFunc(onCreate).Path("").Name("POST " + apiRoot)
+	keyRouter.Methods(http.MethodGet).HandlerFunc(onGet).Path("/" + apiParamApiKeyId).Name("GET " + apiResourceApiKeyId)
+	keyRouter.Methods(http.MethodDelete).HandlerFunc(onRevoke).Path("/" + apiParamApiKeyId).Name("DELETE " + apiResourceApiKeyId)
+}
+
+var onList = api.ContextualizedApi(onContextualizedList)
+var onCreate = api.ContextualizedApi(onContextualizedCreate)
+var onGet = api.ContextualizedApi(onContextualizedGet)
+var onRevoke = api.ContextualizedApi(onContextualizedRevoke)
+
+func onContextualizedList(
+	useLog zerolog.Logger,
+	repository apikey.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start listing API keys")
+		defer func() {
+			useLog.Info().Msg("End listing API keys")
+		}()
+
+		ownership := app_http.ExtractOwnership(r.Context())
+
+		keys, err := repository.FindAll(r.Context(), ownership.Tenant)
+		if err != nil {
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "FindAll failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(keys)
+	}
+}
+
+func onContextualizedCreate(
+	useLog zerolog.Logger,
+	repository apikey.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start creating API key")
+		defer func() {
+			useLog.Info().Msg("End creating API key")
+		}()
+
+		ownership := app_http.ExtractOwnership(r.Context())
+		principal, _ := app_http.ExtractPrincipal(r.Context())
+
+		var req createRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.Name == "" || len(req.Scopes) == 0 {
+			app_http.RespondProblem(w, r, http.StatusBadRequest, "name and scopes are required")
+			return
+		}
+		if ownership.Tenant == "" {
+			app_http.RespondProblem(w, r, http.StatusBadRequest, "tenant is required")
+			return
+		}
+		for _, scope := range req.Scopes {
+			if !principal.HasScope(scope) {
+				app_http.RespondProblem(w, r, http.StatusForbidden, fmt.Sprintf("scope %s cannot be granted", scope))
+				return
+			}
+		}
+
+		id, secret, key, err := apikey.GenerateKey()
+		if err != nil {
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		k := apikey.ApiKey{
+			Id:        id,
+			Name:      req.Name,
+			Tenant:    ownership.Tenant,
+			Scopes:    req.Scopes,
+			Hash:      apikey.HashSecret(secret),
+			CreatedBy: principal.Subject,
+			CreatedAt: time.Now().UTC(),
+		}
+
+		err = repository.Save(r.Context(), k)
+		if err != nil {
+			if apikey.IsAlreadyExists(err) {
+				app_http.RespondProblem(w, r, http.StatusConflict, err.Error())
+				return
+			}
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Create failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(&createResponse{
+			ApiKey: k,
+			Key:    key,
+		})
+	}
+}
+
+func onContextualizedGet(
+	useLog zerolog.Logger,
+	repository apikey.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start fetching API key")
+		defer func() {
+			useLog.Info().Msg("End fetching API key")
+		}()
+
+		k, ok := findOwned(w, r, useLog, repository)
+		if !ok {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(k)
+	}
+}
+
+func onContextualizedRevoke(
+	useLog zerolog.Logger,
+	repository apikey.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start revoking API key")
+		defer func() {
+			useLog.Info().Msg("End revoking API key")
+		}()
+
+		k, ok := findOwned(w, r, useLog, repository)
+		if !ok {
+			return
+		}
+
+		err := repository.Revoke(r.Context(), k.Id, time.Now().UTC())
+		if err != nil {
+			if apikey.IsNotFound(err) {
+				app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Revoke failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func findOwned(
+	w http.ResponseWriter,
+	r *http.Request,
+	useLog zerolog.Logger,
+	repository apikey.Repository,
+) (apikey.ApiKey, bool) {
+
+	ownership := app_http.ExtractOwnership(r.Context())
+	apiKeyId := mux.Vars(r)[pathParamApiKeyId]
+
+	k, err := repository.FindById(r.Context(), apiKeyId)
+	if err == nil && k.Tenant != ownership.Tenant {
+		err = apikey.ErrNotFound
+	}
+	if err != nil {
+		if apikey.IsNotFound(err) {
+			app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+			return k, false
+		}
+
+		span := trace.SpanFromContext(r.Context())
+		span.SetStatus(codes.Error, "Get failed")
+		span.RecordError(err)
+
+		useLog.Error().Msg(err.Error())
+		app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+		return k, false
+	}
+
+	return k, true
+}
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/zitadel/oidc/v3/pkg/client/rs"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+
+	"github.com/morphy76/g-fe-server/internal/apikey/repository"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	"github.com/morphy76/g-fe-server/pkg/apikey"
+)
+
+const (
+	API_KEY_HEADER = "X-API-Key"
+	TENANT_CLAIM   = "tenant"
+	SCOPE_MANAGE   = "apikeys:manage"
+)
+
+func ServiceAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		serveOptions := app_http.ExtractServeOptions(r.Context())
+		logger := app_http.ExtractLogger(r.Context(), "apikey")
+
+		var principal app_http.Principal
+		var ok bool
+
+		if key := extractApiKey(r); key != "" {
+			principal, ok = authenticateApiKey(r, key)
+		} else if token := extractBearer(r); token != "" {
+			principal, ok = authenticateBearer(r, token)
+		} else if serveOptions.ApiAuthRequired {
+			logger.Trace().Msg("Missing service credentials")
+			unauthorized(w, r)
+			return
+		} else {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+		// a service principal is always pinned to its own tenant, the X-Tenant header never applies
+		if principal.Tenant == "" {
+			logger.Warn().
+				Str("kind", string(principal.Kind)).
+				Str("subject", principal.Subject).
+				Msg("Service principal without tenant")
+			app_http.RespondProblem(w, r, http.StatusForbidden, "Principal without tenant")
+			return
+		}
+
+		useContext := app_http.InjectPrincipal(r.Context(), principal)
+		ownership := app_http.ExtractOwnership(useContext)
+		useContext = app_http.InjectOwnership(useContext, serve.Ownership{
+			Tenant:       principal.Tenant,
+			Subscription: ownership.Subscription,
+		})
+		logger.Trace().
+			Str("kind", string(principal.Kind)).
+			Str("subject", principal.Subject).
+			Msg("Service principal authenticated")
+
+		next.ServeHTTP(w, r.WithContext(useContext))
+	})
+}
+
+func RequirePrincipal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := app_http.ExtractPrincipal(r.Context()); !ok {
+			unauthorized(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireScope rejects service principals lacking the scope; calls without a principal (UI session or
+// anonymous ones, see -api-auth-required) are not checked and must be guarded by RequirePrincipal if needed
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := app_http.ExtractPrincipal(r.Context())
+			if ok && !principal.HasScope(scope) {
+				logger := app_http.ExtractLogger(r.Context(), "apikey")
+				logger.Warn().
+					Str("subject", principal.Subject).
+					Str("scope", scope).
+					Msg("Missing scope")
+				app_http.RespondProblem(w, r, http.StatusForbidden, "Insufficient scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func RequireMethodScopes(readScope string, writeScope string) func(http.Handler) http.Handler {
+	read := RequireScope(readScope)
+	write := RequireScope(writeScope)
+	return func(next http.Handler) http.Handler {
+		readNext := read(next)
+		writeNext := write(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				readNext.ServeHTTP(w, r)
+			default:
+				writeNext.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+func authenticateApiKey(r *http.Request, key string) (app_http.Principal, bool) {
+
+	logger := app_http.ExtractLogger(r.Context(), "apikey")
+
+	id, secret, err := apikey.ParseKey(key)
+	if err != nil {
+		logger.Trace().Msg("Malformed API key")
+		return app_http.Principal{}, false
+	}
+
+	apiKeyRepository, err := repository.NewRepository(r.Context())
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to create repository")
+		return app_http.Principal{}, false
+	}
+
+	stored, err := apiKeyRepository.FindById(r.Context(), id)
+	if err != nil {
+		if !apikey.IsNotFound(err) {
+			logger.Error().Err(err).Msg("Failed to load API key")
+		}
+		return app_http.Principal{}, false
+	}
+	if stored.IsRevoked() || !stored.Matches(secret) {
+		logger.Warn().Str("id", id).Msg("Rejected API key")
+		return app_http.Principal{}, false
+	}
+
+	return app_http.Principal{
+		Kind:    app_http.PrincipalApiKey,
+		Subject: stored.Id,
+		Tenant:  stored.Tenant,
+		Scopes:  stored.Scopes,
+	}, true
+}
+
+func authenticateBearer(r *http.Request, token string) (app_http.Principal, bool) {
+
+	logger := app_http.ExtractLogger(r.Context(), "apikey")
+	oidcOptions := app_http.ExtractOidcOptions(r.Context())
+	if oidcOptions.Disabled {
+		logger.Trace().Msg("Bearer token received with OIDC disabled")
+		return app_http.Principal{}, false
+	}
+	resourceServer := app_http.ExtractOidcResource(r.Context())
+
+	resp, err := rs.Introspect[*oidc.IntrospectionResponse](r.Context(), resourceServer, token)
+	if err != nil {
+		logger.Warn().Err(err).Msg("Failed to introspect token")
+		return app_http.Principal{}, false
+	}
+	if !resp.Active {
+		logger.Trace().Msg("Token is not active")
+		return app_http.Principal{}, false
+	}
+
+	// tokens of users carry their own subject, client-credentials ones either none or the client id
+	kind := app_http.PrincipalUser
+	subject := resp.Subject
+	if subject == "" || subject == resp.ClientID {
+		kind = app_http.PrincipalClient
+		subject = resp.ClientID
+	}
+	tenant, _ := resp.Claims[TENANT_CLAIM].(string)
+
+	return app_http.Principal{
+		Kind:    kind,
+		Subject: subject,
+		Tenant:  tenant,
+		Scopes:  resp.Scope,
+	}, true
+}
+
+func extractApiKey(r *http.Request) string {
+	if key := r.Header.Get(API_KEY_HEADER); key != "" {
+		return key
+	}
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "apikey ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
+
+func extractBearer(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "bearer ") {
+		return strings.TrimSpace(authorization[7:])
+	}
+	return ""
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+	app_http.RespondProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+}
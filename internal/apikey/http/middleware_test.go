@@ -0,0 +1,207 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/morphy76/g-fe-server/internal/apikey/repository"
+	"github.com/morphy76/g-fe-server/internal/audit"
+	"github.com/morphy76/g-fe-server/internal/db"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	"github.com/morphy76/g-fe-server/pkg/apikey"
+	audit_model "github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+// introspection stands for the resource server of the identity provider, answering every token with its response
+type introspection struct {
+	url      string
+	response string
+}
+
+func (i *introspection) IntrospectionURL() string {
+	return i.url
+}
+
+func (i *introspection) TokenEndpoint() string {
+	return ""
+}
+
+func (i *introspection) HttpClient() *http.Client {
+	return http.DefaultClient
+}
+
+func (i *introspection) AuthFn() (any, error) {
+	return nil, nil
+}
+
+// memoryClient is shared by the contexts of the suite, so that keys saved in one are found in the others
+var memoryClient = db.NewMemoryDbClient()
+
+func testContext(required bool) context.Context {
+	ctx := app_http.InjectServeOptions(context.Background(), &options.ServeOptions{ApiAuthRequired: required})
+	ctx = app_http.InjectOidcOptions(ctx, &options.OidcOptions{Disabled: true})
+	ctx = app_http.InjectLogger(ctx, zerolog.Nop())
+	ctx = app_http.InjectOwnership(ctx, serve.Ownership{Tenant: "header-tenant"})
+	ctx = db.InjectDbOptions(ctx, &options.DbOptions{Type: options.RepositoryTypeMemoryDB})
+	ctx = db.InjectDb(ctx, memoryClient)
+	return ctx
+}
+
+func TestMiddlewareSuite(t *testing.T) {
+	t.Log("Test Middleware Suite")
+
+	id, secret, key, err := apikey.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %s", err)
+	}
+	ctx := testContext(false)
+	repo, _ := repository.NewRepository(ctx)
+	repo.Save(ctx, apikey.ApiKey{
+		Id:        id,
+		Name:      "test",
+		Tenant:    "key-tenant",
+		Scopes:    []string{"example:read"},
+		Hash:      apikey.HashSecret(secret),
+		CreatedAt: time.Now(),
+	})
+
+	var seen app_http.Principal
+	var seenTenant string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = app_http.ExtractPrincipal(r.Context())
+		seenTenant = app_http.ExtractOwnership(r.Context()).Tenant
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mid := ServiceAuthentication(RequireMethodScopes("example:read", "example:write")(next))
+
+	call := func(ctx context.Context, method string, headers map[string]string) int {
+		r := httptest.NewRequest(method, "/", nil).WithContext(ctx)
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("Test anonymous", func(t *testing.T) {
+		t.Log("Test Middleware anonymous")
+
+		if code := call(testContext(false), http.MethodGet, nil); code != http.StatusNoContent {
+			t.Fatalf("Expected anonymous access, got %d", code)
+		}
+		if code := call(testContext(true), http.MethodGet, nil); code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401 when auth is required, got %d", code)
+		}
+	})
+
+	t.Run("Test API key", func(t *testing.T) {
+		t.Log("Test Middleware API key")
+
+		code := call(testContext(true), http.MethodGet, map[string]string{API_KEY_HEADER: key})
+		if code != http.StatusNoContent {
+			t.Fatalf("Expected valid key to pass, got %d", code)
+		}
+		if seen.Kind != app_http.PrincipalApiKey || seen.Subject != id {
+			t.Fatalf("Unexpected principal %#v", seen)
+		}
+		if seenTenant != "key-tenant" {
+			t.Fatalf("Expected key tenant, got %s", seenTenant)
+		}
+
+		code = call(testContext(true), http.MethodPost, map[string]string{"Authorization": "ApiKey " + key})
+		if code != http.StatusForbidden {
+			t.Fatalf("Expected missing write scope to be rejected, got %d", code)
+		}
+
+		code = call(testContext(false), http.MethodGet, map[string]string{API_KEY_HEADER: id + ".wrong"})
+		if code != http.StatusUnauthorized {
+			t.Fatalf("Expected wrong secret to be rejected, got %d", code)
+		}
+	})
+
+	t.Run("Test API key without tenant", func(t *testing.T) {
+		t.Log("Test Middleware API key without tenant")
+
+		orphanId, orphanSecret, orphanKey, err := apikey.GenerateKey()
+		if err != nil {
+			t.Fatalf("Failed to generate key: %s", err)
+		}
+		repo.Save(ctx, apikey.ApiKey{
+			Id:        orphanId,
+			Name:      "orphan",
+			Scopes:    []string{"example:read"},
+			Hash:      apikey.HashSecret(orphanSecret),
+			CreatedAt: time.Now(),
+		})
+
+		seenTenant = ""
+		code := call(testContext(false), http.MethodGet, map[string]string{API_KEY_HEADER: orphanKey})
+		if code != http.StatusForbidden {
+			t.Fatalf("Expected key without tenant to be rejected, got %d", code)
+		}
+		if seenTenant != "" {
+			t.Fatalf("Expected the handler not to be reached, got tenant %s", seenTenant)
+		}
+	})
+
+	t.Run("Test bearer with OIDC disabled", func(t *testing.T) {
+		t.Log("Test Middleware bearer")
+
+		code := call(testContext(false), http.MethodGet, map[string]string{"Authorization": "Bearer abc"})
+		if code != http.StatusUnauthorized {
+			t.Fatalf("Expected bearer to be rejected, got %d", code)
+		}
+	})
+
+	t.Run("Test bearer actor", func(t *testing.T) {
+		t.Log("Test Middleware bearer actor")
+
+		resourceServer := &introspection{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, resourceServer.response)
+		}))
+		defer server.Close()
+		resourceServer.url = server.URL
+
+		var entry audit_model.Entry
+		actorMid := ServiceAuthentication(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			entry, _ = audit.NewEntry(r.Context(), "example", "e1", audit_model.OpCreate, nil, map[string]string{"name": "e1"})
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		actorCall := func() int {
+			ctx := app_http.InjectOidcOptions(testContext(true), &options.OidcOptions{})
+			ctx = app_http.InjectOidcResource(ctx, resourceServer)
+			r := httptest.NewRequest(http.MethodPost, "/", nil).WithContext(ctx)
+			r.Header.Set("Authorization", "Bearer token")
+			w := httptest.NewRecorder()
+			actorMid.ServeHTTP(w, r)
+			return w.Code
+		}
+
+		resourceServer.response = `{"active":true,"sub":"user-1","client_id":"spa","tenant":"t1","scope":"example:write"}`
+		if code := actorCall(); code != http.StatusNoContent {
+			t.Fatalf("Expected the user token to pass, got %d", code)
+		}
+		if entry.Actor != "user-1" || entry.ActorKind != string(app_http.PrincipalUser) || entry.Tenant != "t1" {
+			t.Fatalf("Expected the user to be the actor, got %#v", entry)
+		}
+
+		resourceServer.response = `{"active":true,"client_id":"batch","tenant":"t1","scope":"example:write"}`
+		if code := actorCall(); code != http.StatusNoContent {
+			t.Fatalf("Expected the client token to pass, got %d", code)
+		}
+		if entry.Actor != "batch" || entry.ActorKind != string(app_http.PrincipalClient) {
+			t.Fatalf("Expected the client to be the actor, got %#v", entry)
+		}
+	})
+}
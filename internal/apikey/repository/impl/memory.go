@@ -0,0 +1,101 @@
+package apikey
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/apikey"
+)
+
+// MEMORY_STORE names the API keys in the stores of the memory client
+const MEMORY_STORE = "apikeys"
+
+type MemoryRepository struct {
+	lock *sync.RWMutex
+	db   map[string]apikey.ApiKey
+}
+
+func NewMemoryRepository(client *db.MemoryDbClient) (apikey.Repository, error) {
+
+	table, err := db.MemoryTableOf(client, MEMORY_STORE, func(k apikey.ApiKey) string { return k.Id })
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemoryRepository{
+		lock: &table.Lock,
+		db:   table.Rows,
+	}, nil
+}
+
+func (r *MemoryRepository) FindAll(ctx context.Context, tenant string) ([]apikey.ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	values := make([]apikey.ApiKey, 0, len(r.db))
+	for _, v := range r.db {
+		if v.Tenant == tenant {
+			values = append(values, v)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].CreatedAt.Before(values[j].CreatedAt)
+	})
+	return values, nil
+}
+
+func (r *MemoryRepository) FindById(ctx context.Context, id string) (apikey.ApiKey, error) {
+	if err := ctx.Err(); err != nil {
+		return apikey.ApiKey{}, err
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	rv, ok := r.db[id]
+	if !ok {
+		return rv, apikey.ErrNotFound
+	}
+	return rv, nil
+}
+
+func (r *MemoryRepository) Save(ctx context.Context, k apikey.ApiKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.db[k.Id]; ok {
+		return apikey.ErrAlreadyExists
+	}
+	r.db[k.Id] = k
+	return nil
+}
+
+func (r *MemoryRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	appo, ok := r.db[id]
+	if !ok {
+		return apikey.ErrNotFound
+	}
+	if appo.RevokedAt == nil {
+		appo.RevokedAt = &at
+		r.db[id] = appo
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package apikey
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/apikey"
+)
+
+func TestMemoryRepositorySuite(t *testing.T) {
+	t.Log("Test MemoryRepository Suite")
+
+	ctx := context.Background()
+	repo, _ := NewMemoryRepository(db.NewMemoryDbClient())
+	t.Logf("Repository URL: memory")
+
+	t.Run("Test Save and Revoke", func(t *testing.T) {
+		t.Log("Testing Memory Save and Revoke")
+
+		err := repo.Save(ctx, apikey.ApiKey{
+			Id:        "k1",
+			Name:      "Test",
+			Tenant:    "t1",
+			Scopes:    []string{"example:read"},
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("Error on Save: %s", err)
+		}
+		if err := repo.Save(ctx, apikey.ApiKey{Id: "k1"}); !apikey.IsAlreadyExists(err) {
+			t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+		}
+
+		items, err := repo.FindAll(ctx, "t1")
+		if err != nil {
+			t.Fatalf("Error on FindAll: %s", err)
+		}
+		if len(items) != 1 {
+			t.Fatalf("Expected 1 item, got %d", len(items))
+		}
+		if items, _ := repo.FindAll(ctx, "t2"); len(items) != 0 {
+			t.Fatalf("Expected no items for another tenant, got %d", len(items))
+		}
+
+		if err := repo.Revoke(ctx, "k1", time.Now()); err != nil {
+			t.Fatalf("Error on Revoke: %s", err)
+		}
+		k, err := repo.FindById(ctx, "k1")
+		if err != nil {
+			t.Fatalf("Error on FindById: %s", err)
+		}
+		if !k.IsRevoked() {
+			t.Fatal("Expected revoked key")
+		}
+
+		if err := repo.Revoke(ctx, "unknown", time.Now()); !apikey.IsNotFound(err) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
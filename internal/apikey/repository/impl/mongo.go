@@ -0,0 +1,109 @@
+package apikey
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_opts "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/pkg/apikey"
+)
+
+const MONGO_COLLECTION = "apikeys"
+
+type MongoRepository struct {
+	DbOptions  *options.DbOptions
+	Client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func (r *MongoRepository) FindAll(ctx context.Context, tenant string) ([]apikey.ApiKey, error) {
+
+	r.lazyBindCollection()
+
+	findOptions := mongo_opts.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := r.collection.Find(ctx, bson.D{{Key: "tenant", Value: tenant}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	rv := make([]apikey.ApiKey, 0)
+	err = cur.All(ctx, &rv)
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) FindById(ctx context.Context, id string) (apikey.ApiKey, error) {
+
+	r.lazyBindCollection()
+
+	rv := apikey.ApiKey{}
+
+	singleResult := r.collection.FindOne(ctx, bson.D{{Key: "id", Value: id}})
+
+	err := singleResult.Decode(&rv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return rv, apikey.ErrNotFound
+		}
+		return rv, err
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) Save(ctx context.Context, k apikey.ApiKey) error {
+
+	r.lazyBindCollection()
+
+	_, err := r.collection.InsertOne(ctx, k)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return apikey.ErrAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+
+	r.lazyBindCollection()
+
+	filter := bson.D{{Key: "id", Value: id}}
+	update := bson.D{{Key: "$min", Value: bson.D{{Key: "revoked_at", Value: at}}}}
+
+	updateResult, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if updateResult.MatchedCount == 0 {
+		return apikey.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) lazyBindCollection() {
+	if r.collection == nil {
+
+		useUrl, _ := url.Parse(r.DbOptions.Url)
+
+		if useUrl.User == nil {
+			useCredentials := url.UserPassword(r.DbOptions.User, r.DbOptions.Password)
+			useUrl.User = useCredentials
+		}
+
+		r.collection = r.Client.Database(path.Base(useUrl.Path)).Collection(MONGO_COLLECTION)
+	}
+}
@@ -0,0 +1,101 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/apikey"
+)
+
+const SQL_TABLE = "apikeys"
+
+var apiKeyColumns = db.Columns(apikey.ApiKey{})
+
+type SqlRepository struct {
+	Db      *sql.DB
+	Dialect db.Dialect
+}
+
+func (r *SqlRepository) FindAll(ctx context.Context, tenant string) ([]apikey.ApiKey, error) {
+	return r.query(ctx, "SELECT "+strings.Join(apiKeyColumns, ", ")+" FROM "+SQL_TABLE+" WHERE tenant = ? ORDER BY created_at", tenant)
+}
+
+func (r *SqlRepository) FindById(ctx context.Context, id string) (apikey.ApiKey, error) {
+
+	keys, err := r.query(ctx, "SELECT "+strings.Join(apiKeyColumns, ", ")+" FROM "+SQL_TABLE+" WHERE id = ?", id)
+	if err != nil {
+		return apikey.ApiKey{}, err
+	}
+	if len(keys) == 0 {
+		return apikey.ApiKey{}, apikey.ErrNotFound
+	}
+	return keys[0], nil
+}
+
+func (r *SqlRepository) Save(ctx context.Context, k apikey.ApiKey) error {
+
+	values, err := db.Values(k, apiKeyColumns...)
+	if err != nil {
+		return err
+	}
+
+	statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (id) DO NOTHING",
+		SQL_TABLE, strings.Join(apiKeyColumns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(apiKeyColumns)), ", "))
+	result, err := r.Db.ExecContext(ctx, r.Dialect.Rebind(statement), values...)
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return apikey.ErrAlreadyExists
+	}
+	return nil
+}
+
+// Revoke keeps the first revocation time of keys revoked more than once
+func (r *SqlRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+
+	result, err := r.Db.ExecContext(ctx, r.Dialect.Rebind("UPDATE "+SQL_TABLE+" SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?"), at.UTC(), id)
+	if err != nil {
+		return err
+	}
+	matched, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if matched == 0 {
+		return apikey.ErrNotFound
+	}
+	return nil
+}
+
+func (r *SqlRepository) query(ctx context.Context, statement string, args ...any) ([]apikey.ApiKey, error) {
+
+	rows, err := r.Db.QueryContext(ctx, r.Dialect.Rebind(statement), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]apikey.ApiKey, 0)
+	for rows.Next() {
+		k := apikey.ApiKey{}
+		targets, err := db.Targets(&k, apiKeyColumns...)
+		if err != nil {
+			return nil, err
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		rv = append(rv, k)
+	}
+
+	return rv, rows.Err()
+}
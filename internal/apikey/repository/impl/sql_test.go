@@ -0,0 +1,91 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/pkg/apikey"
+)
+
+func TestSqlRepositorySuite(t *testing.T) {
+	t.Log("Test SqlRepository Suite")
+
+	ctx := context.Background()
+
+	dbOptions := &options.DbOptions{
+		Type:         options.RepositoryTypeSQLite,
+		SqlDbOptions: options.SqlDbOptions{Dsn: filepath.Join(t.TempDir(), "apikeys.db")},
+	}
+	dbClient, err := db.NewClient(dbOptions)
+	if err != nil {
+		t.Fatalf("Failed to create the client: %s", err)
+	}
+	t.Cleanup(func() {
+		dbClient.(*sql.DB).Close()
+	})
+
+	repo := &SqlRepository{
+		Db:      dbClient.(*sql.DB),
+		Dialect: db.SQLite,
+	}
+	t.Logf("Repository URL: %s", dbOptions.Dsn)
+
+	t.Run("Test Save and Revoke", func(t *testing.T) {
+		t.Log("Testing SQL Save and Revoke")
+
+		err := repo.Save(ctx, apikey.ApiKey{
+			Id:        "k1",
+			Name:      "Test",
+			Tenant:    "t1",
+			Scopes:    []string{"example:read"},
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			t.Fatalf("Error on Save: %s", err)
+		}
+		if err := repo.Save(ctx, apikey.ApiKey{Id: "k1"}); !apikey.IsAlreadyExists(err) {
+			t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+		}
+
+		items, err := repo.FindAll(ctx, "t1")
+		if err != nil {
+			t.Fatalf("Error on FindAll: %s", err)
+		}
+		if len(items) != 1 {
+			t.Fatalf("Expected 1 item, got %d", len(items))
+		}
+		if items, _ := repo.FindAll(ctx, "t2"); len(items) != 0 {
+			t.Fatalf("Expected no items for another tenant, got %d", len(items))
+		}
+
+		if err := repo.Revoke(ctx, "k1", time.Now()); err != nil {
+			t.Fatalf("Error on Revoke: %s", err)
+		}
+		k, err := repo.FindById(ctx, "k1")
+		if err != nil {
+			t.Fatalf("Error on FindById: %s", err)
+		}
+		if !k.IsRevoked() {
+			t.Fatal("Expected revoked key")
+		}
+
+		if err := repo.Revoke(ctx, "k1", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Error on second Revoke: %s", err)
+		}
+		if again, _ := repo.FindById(ctx, "k1"); !again.RevokedAt.Equal(*k.RevokedAt) {
+			t.Fatalf("Expected the first revocation to be kept, got %v", again.RevokedAt)
+		}
+		if len(k.Scopes) != 1 || k.Scopes[0] != "example:read" {
+			t.Fatalf("Expected the scopes to be read back, got %v", k.Scopes)
+		}
+
+		if err := repo.Revoke(ctx, "unknown", time.Now()); !apikey.IsNotFound(err) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
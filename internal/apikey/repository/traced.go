@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	impl "github.com/morphy76/g-fe-server/internal/apikey/repository/impl"
+	model "github.com/morphy76/g-fe-server/pkg/apikey"
+)
+
+const tracerName = "github.com/morphy76/g-fe-server/internal/apikey/repository"
+
+type tracedRepository struct {
+	delegate model.Repository
+	system   string
+}
+
+func newTracedRepository(delegate model.Repository, system string) model.Repository {
+	return &tracedRepository{
+		delegate: delegate,
+		system:   system,
+	}
+}
+
+func (r *tracedRepository) FindAll(ctx context.Context, tenant string) ([]model.ApiKey, error) {
+	ctx, span := r.start(ctx, "FindAll")
+	rv, err := r.delegate.FindAll(ctx, tenant)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) FindById(ctx context.Context, id string) (model.ApiKey, error) {
+	ctx, span := r.start(ctx, "FindById")
+	rv, err := r.delegate.FindById(ctx, id)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) Save(ctx context.Context, k model.ApiKey) error {
+	ctx, span := r.start(ctx, "Save")
+	err := r.delegate.Save(ctx, k)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) Revoke(ctx context.Context, id string, at time.Time) error {
+	ctx, span := r.start(ctx, "Revoke")
+	err := r.delegate.Revoke(ctx, id, at)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "apikey."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", r.system),
+			attribute.String("db.collection.name", impl.MONGO_COLLECTION),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !model.IsNotFound(err) && !model.IsAlreadyExists(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	model "github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+const ANONYMOUS_ACTOR = "anonymous"
+
+// NewEntry describes the change of an entity on behalf of the principal, the tenant and the trace of the context
+func NewEntry(ctx context.Context, entity string, entityId string, operation model.Operation, before any, after any) (model.Entry, error) {
+
+	changes, err := model.Diff(before, after)
+	if err != nil {
+		return model.Entry{}, err
+	}
+
+	idBytes := make([]byte, 12)
+	if _, err := rand.Read(idBytes); err != nil {
+		return model.Entry{}, err
+	}
+
+	rv := model.Entry{
+		Id:        hex.EncodeToString(idBytes),
+		At:        time.Now().UTC(),
+		Actor:     ANONYMOUS_ACTOR,
+		Entity:    entity,
+		EntityId:  entityId,
+		Operation: operation,
+		Changes:   changes,
+	}
+
+	if principal, ok := app_http.ExtractPrincipal(ctx); ok {
+		rv.Actor = principal.Subject
+		rv.ActorKind = string(principal.Kind)
+	}
+	if ownership, ok := app_http.LookupOwnership(ctx); ok {
+		rv.Tenant = ownership.Tenant
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		rv.TraceId = spanContext.TraceID().String()
+	}
+
+	return rv, nil
+}
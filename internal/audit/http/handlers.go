@@ -0,0 +1,123 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	apikey_http "github.com/morphy76/g-fe-server/internal/apikey/http"
+	"github.com/morphy76/g-fe-server/internal/audit/api"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+const (
+	SCOPE_READ = "audit:read"
+
+	queryParamEntity    = "entity"
+	queryParamEntityId  = "entity_id"
+	queryParamActor     = "actor"
+	queryParamOperation = "operation"
+	queryParamSince     = "since"
+	queryParamUntil     = "until"
+	queryParamLimit     = "limit"
+)
+
+func AuditHandlers(functionalRouter *mux.Router, app_context context.Context) {
+
+	serveOptions := app_http.ExtractServeOptions(app_context)
+	ctxRoot := serveOptions.ContextRoot
+
+	var (
+		apiRoot = fmt.Sprintf("%s/api/audit", ctxRoot)
+
+		auditRouter = functionalRouter.PathPrefix("/audit").Subrouter()
+	)
+	auditRouter.Use(apikey_http.RequirePrincipal)
+	auditRouter.Use(apikey_http.RequireScope(SCOPE_READ))
+
+	auditRouter.Methods(http.MethodGet).HandlerFunc(onList).Path("").Name("GET " + apiRoot)
+}
+
+var onList = api.ContextualizedApi(onContextualizedList)
+
+func onContextualizedList(
+	useLog zerolog.Logger,
+	repository audit.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start listing audit entries")
+		defer func() {
+			useLog.Info().Msg("End listing audit entries")
+		}()
+
+		query, err := parseQuery(r)
+		if err != nil {
+			app_http.RespondProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		entries, err := repository.Find(r.Context(), query)
+		if err != nil {
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "List failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+// parseQuery reads the filters of the request, the tenant always comes from the ownership of the caller
+func parseQuery(r *http.Request) (audit.Query, error) {
+
+	params := r.URL.Query()
+	rv := audit.Query{
+		Tenant:    app_http.ExtractOwnership(r.Context()).Tenant,
+		Entity:    params.Get(queryParamEntity),
+		EntityId:  params.Get(queryParamEntityId),
+		Actor:     params.Get(queryParamActor),
+		Operation: audit.Operation(params.Get(queryParamOperation)),
+	}
+
+	switch rv.Operation {
+	case "", audit.OpCreate, audit.OpUpdate, audit.OpPatch, audit.OpDelete, audit.OpRestore:
+	default:
+		return rv, audit.ErrInvalidQuery
+	}
+
+	for param, target := range map[string]*time.Time{queryParamSince: &rv.Since, queryParamUntil: &rv.Until} {
+		if value := params.Get(param); value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return rv, audit.ErrInvalidQuery
+			}
+			*target = at
+		}
+	}
+
+	if limit := params.Get(queryParamLimit); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return rv, audit.ErrInvalidQuery
+		}
+		rv.Limit = value
+	}
+
+	return rv, nil
+}
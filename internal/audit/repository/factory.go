@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	impl "github.com/morphy76/g-fe-server/internal/audit/repository/impl"
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+	model "github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+func NewRepository(requestContext context.Context) (model.Repository, error) {
+
+	dbOptions := db.ExtractDbOptions(requestContext)
+	dbClient := db.ExtractDb(requestContext)
+
+	switch dbOptions.Type {
+	case options.RepositoryTypeMemoryDB:
+		memoryClient, ok := dbClient.(*db.MemoryDbClient)
+		if !ok {
+			return nil, errors.New("memory client not found in request context")
+		}
+
+		rv, err := impl.NewMemoryRepository(memoryClient)
+		if err != nil {
+			return nil, err
+		}
+
+		return newTracedRepository(rv, "memory"), nil
+	case options.RepositoryTypeMongoDB:
+		if dbClient == nil {
+			return nil, errors.New("MongoDB client not found in request context")
+		}
+
+		mongoClient := dbClient.(*mongo.Client)
+
+		var rv model.Repository = &impl.MongoRepository{
+			DbOptions: dbOptions,
+			Client:    mongoClient,
+		}
+
+		return newTracedRepository(rv, "mongodb"), nil
+	case options.RepositoryTypePostgreSQL, options.RepositoryTypeSQLite:
+		sqlDb, ok := dbClient.(*sql.DB)
+		if !ok {
+			return nil, errors.New("SQL client not found in request context")
+		}
+
+		dialect, err := db.DialectOf(dbOptions.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		var rv model.Repository = &impl.SqlRepository{
+			Db:      sqlDb,
+			Dialect: dialect,
+		}
+
+		return newTracedRepository(rv, dialect.Name), nil
+	default:
+		return nil, model.ErrUnknownRepositoryType
+	}
+}
@@ -0,0 +1,143 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+// watchBuffer is the backlog a watcher can accumulate before being dropped
+const watchBuffer = 64
+
+// MEMORY_STORE names the audit log in the stores of the memory client
+const MEMORY_STORE = "audit"
+
+// memoryLog is the audit log of a memory client, watchers are not part of its snapshots
+type memoryLog struct {
+	lock     sync.RWMutex
+	entries  []audit.Entry
+	watchers map[chan audit.Entry]audit.Query
+}
+
+func (l *memoryLog) Snapshot() (json.RawMessage, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	return json.Marshal(l.entries)
+}
+
+func (l *memoryLog) Restore(document json.RawMessage) error {
+	var entries []audit.Entry
+	if err := json.Unmarshal(document, &entries); err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.entries = append(l.entries, entries...)
+	return nil
+}
+
+type MemoryRepository struct {
+	lock     *sync.RWMutex
+	log      *[]audit.Entry
+	watchers map[chan audit.Entry]audit.Query
+}
+
+func NewMemoryRepository(client *db.MemoryDbClient) (audit.Repository, error) {
+
+	store, err := client.Store(MEMORY_STORE, func() db.MemoryStore {
+		return &memoryLog{
+			entries:  make([]audit.Entry, 0),
+			watchers: make(map[chan audit.Entry]audit.Query),
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log, ok := store.(*memoryLog)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", db.ErrMemoryStoreType, MEMORY_STORE)
+	}
+
+	return &MemoryRepository{
+		lock:     &log.lock,
+		log:      &log.entries,
+		watchers: log.watchers,
+	}, nil
+}
+
+func (r *MemoryRepository) Append(ctx context.Context, e audit.Entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	*r.log = append(*r.log, e)
+
+	for watcher, query := range r.watchers {
+		if !query.Matches(e) {
+			continue
+		}
+		select {
+		case watcher <- e:
+		default:
+			delete(r.watchers, watcher)
+			close(watcher)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Watch(ctx context.Context, query audit.Query) (<-chan audit.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	watcher := make(chan audit.Entry, watchBuffer)
+	r.watchers[watcher] = query
+
+	go func() {
+		<-ctx.Done()
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		// the watcher could have been dropped already
+		if _, ok := r.watchers[watcher]; ok {
+			delete(r.watchers, watcher)
+			close(watcher)
+		}
+	}()
+
+	return watcher, nil
+}
+
+func (r *MemoryRepository) Find(ctx context.Context, query audit.Query) ([]audit.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	limit := query.EffectiveLimit()
+	rv := make([]audit.Entry, 0)
+	// the log is in append order, newest last
+	for i := len(*r.log) - 1; i >= 0 && len(rv) < limit; i-- {
+		if query.Matches((*r.log)[i]) {
+			rv = append(rv, (*r.log)[i])
+		}
+	}
+	return rv, nil
+}
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+func TestMemoryRepositorySuite(t *testing.T) {
+	t.Log("Test MemoryRepository Suite")
+
+	ctx := context.Background()
+	repo, _ := NewMemoryRepository(db.NewMemoryDbClient())
+	t.Logf("Repository URL: memory")
+
+	t.Run("Test Append and Find", func(t *testing.T) {
+		t.Log("Testing Memory Append and Find")
+
+		at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, entry := range []audit.Entry{
+			{Id: "a1", At: at, Tenant: "t1", Entity: "example", EntityId: "e1", Actor: "alice", Operation: audit.OpCreate},
+			{Id: "a2", At: at.Add(time.Minute), Tenant: "t1", Entity: "example", EntityId: "e1", Actor: "bob", Operation: audit.OpUpdate},
+			{Id: "a3", At: at.Add(2 * time.Minute), Tenant: "t2", Entity: "example", EntityId: "e1", Actor: "alice", Operation: audit.OpDelete},
+		} {
+			if err := repo.Append(ctx, entry); err != nil {
+				t.Fatalf("Error on Append %d: %s", i, err)
+			}
+		}
+
+		entries, err := repo.Find(ctx, audit.Query{Tenant: "t1", EntityId: "e1"})
+		if err != nil {
+			t.Fatalf("Error on Find: %s", err)
+		}
+		if len(entries) != 2 || entries[0].Id != "a2" || entries[1].Id != "a1" {
+			t.Errorf("Expected the entries of t1 newest first, got %#v", entries)
+		}
+
+		entries, _ = repo.Find(ctx, audit.Query{Tenant: "t1", Actor: "alice"})
+		if len(entries) != 1 || entries[0].Id != "a1" {
+			t.Errorf("Expected the entry of alice, got %#v", entries)
+		}
+
+		entries, _ = repo.Find(ctx, audit.Query{Tenant: "t1", Since: at.Add(time.Minute)})
+		if len(entries) != 1 || entries[0].Id != "a2" {
+			t.Errorf("Expected the entries since the second one, got %#v", entries)
+		}
+
+		entries, _ = repo.Find(ctx, audit.Query{Tenant: "t1", Limit: 1})
+		if len(entries) != 1 || entries[0].Id != "a2" {
+			t.Errorf("Expected the newest entry only, got %#v", entries)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"net/url"
+	"path"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_opts "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+const MONGO_COLLECTION = "audit"
+
+type MongoRepository struct {
+	DbOptions  *options.DbOptions
+	Client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func (r *MongoRepository) Append(ctx context.Context, e audit.Entry) error {
+
+	r.lazyBindCollection()
+
+	_, err := r.collection.InsertOne(ctx, e)
+	return err
+}
+
+func (r *MongoRepository) Find(ctx context.Context, query audit.Query) ([]audit.Entry, error) {
+
+	r.lazyBindCollection()
+
+	findOptions := mongo_opts.Find().
+		SetSort(bson.D{{Key: "at", Value: -1}, {Key: "id", Value: -1}}).
+		SetLimit(int64(query.EffectiveLimit()))
+
+	cur, err := r.collection.Find(ctx, filterDocument(query), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	rv := make([]audit.Entry, 0)
+	err = cur.All(ctx, &rv)
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// Watch tails the change stream of the collection, which requires MongoDB to run as a replica set
+func (r *MongoRepository) Watch(ctx context.Context, query audit.Query) (<-chan audit.Entry, error) {
+
+	r.lazyBindCollection()
+
+	match := bson.D{{Key: "operationType", Value: "insert"}}
+	for _, condition := range filterDocument(query) {
+		match = append(match, bson.E{Key: "fullDocument." + condition.Key, Value: condition.Value})
+	}
+
+	stream, err := r.collection.Watch(ctx, mongo.Pipeline{{{Key: "$match", Value: match}}})
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make(chan audit.Entry)
+	go func() {
+		defer close(rv)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			var event struct {
+				FullDocument audit.Entry `bson:"fullDocument"`
+			}
+			if err := stream.Decode(&event); err != nil {
+				continue
+			}
+			select {
+			case rv <- event.FullDocument:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return rv, nil
+}
+
+func (r *MongoRepository) lazyBindCollection() {
+	if r.collection == nil {
+
+		useUrl, _ := url.Parse(r.DbOptions.Url)
+
+		if useUrl.User == nil {
+			useCredentials := url.UserPassword(r.DbOptions.User, r.DbOptions.Password)
+			useUrl.User = useCredentials
+		}
+
+		r.collection = r.Client.Database(path.Base(useUrl.Path)).Collection(MONGO_COLLECTION)
+	}
+}
+
+func filterDocument(query audit.Query) bson.D {
+	rv := bson.D{{Key: "tenant", Value: query.Tenant}}
+	for _, member := range []struct {
+		key   string
+		value string
+	}{
+		{"entity", query.Entity},
+		{"entity_id", query.EntityId},
+		{"actor", query.Actor},
+		{"operation", string(query.Operation)},
+	} {
+		if member.value != "" {
+			rv = append(rv, bson.E{Key: member.key, Value: member.value})
+		}
+	}
+
+	at := bson.D{}
+	if !query.Since.IsZero() {
+		at = append(at, bson.E{Key: "$gte", Value: query.Since})
+	}
+	if !query.Until.IsZero() {
+		at = append(at, bson.E{Key: "$lt", Value: query.Until})
+	}
+	if len(at) > 0 {
+		rv = append(rv, bson.E{Key: "at", Value: at})
+	}
+	return rv
+}
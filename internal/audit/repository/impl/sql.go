@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+const SQL_TABLE = "audit"
+
+const (
+	// watchPollInterval is the delay between the reads of a watcher
+	watchPollInterval = time.Second
+	// watchGrace covers entries committed after the ones with a later timestamp
+	watchGrace = 5 * time.Second
+)
+
+var entryColumns = db.Columns(audit.Entry{})
+
+type SqlRepository struct {
+	Db      *sql.DB
+	Dialect db.Dialect
+}
+
+func (r *SqlRepository) Append(ctx context.Context, e audit.Entry) error {
+
+	values, err := db.Values(e, entryColumns...)
+	if err != nil {
+		return err
+	}
+
+	statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		SQL_TABLE, strings.Join(entryColumns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(entryColumns)), ", "))
+	_, err = r.Db.ExecContext(ctx, r.Dialect.Rebind(statement), values...)
+	return err
+}
+
+func (r *SqlRepository) Find(ctx context.Context, query audit.Query) ([]audit.Entry, error) {
+	where, args := whereClause(query)
+	return r.query(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY at DESC, id DESC LIMIT %d",
+		strings.Join(entryColumns, ", "), SQL_TABLE, where, query.EffectiveLimit()), args...)
+}
+
+// Watch polls the table, entries are delivered once in timestamp order
+func (r *SqlRepository) Watch(ctx context.Context, query audit.Query) (<-chan audit.Entry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now().UTC()
+	rv := make(chan audit.Entry)
+
+	go func() {
+		defer close(rv)
+
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+
+		since := start
+		seen := make(map[string]time.Time)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			polled := query
+			polled.Since = since.Add(-watchGrace)
+			if polled.Since.Before(start) {
+				polled.Since = start
+			}
+			if !query.Since.IsZero() && polled.Since.Before(query.Since) {
+				polled.Since = query.Since
+			}
+			where, args := whereClause(polled)
+			entries, err := r.query(ctx, fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY at ASC, id ASC",
+				strings.Join(entryColumns, ", "), SQL_TABLE, where), args...)
+			if err != nil {
+				return
+			}
+
+			for _, e := range entries {
+				if _, ok := seen[e.Id]; ok {
+					continue
+				}
+				seen[e.Id] = e.At
+				if e.At.After(since) {
+					since = e.At
+				}
+				select {
+				case rv <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			for id, at := range seen {
+				if at.Before(since.Add(-watchGrace)) {
+					delete(seen, id)
+				}
+			}
+		}
+	}()
+
+	return rv, nil
+}
+
+func (r *SqlRepository) query(ctx context.Context, statement string, args ...any) ([]audit.Entry, error) {
+
+	rows, err := r.Db.QueryContext(ctx, r.Dialect.Rebind(statement), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]audit.Entry, 0)
+	for rows.Next() {
+		e := audit.Entry{}
+		targets, err := db.Targets(&e, entryColumns...)
+		if err != nil {
+			return nil, err
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		rv = append(rv, e)
+	}
+
+	return rv, rows.Err()
+}
+
+func whereClause(query audit.Query) (string, []any) {
+	conditions := []string{"tenant = ?"}
+	args := []any{query.Tenant}
+	for _, member := range []struct {
+		column string
+		value  string
+	}{
+		{"entity", query.Entity},
+		{"entity_id", query.EntityId},
+		{"actor", query.Actor},
+		{"operation", string(query.Operation)},
+	} {
+		if member.value != "" {
+			conditions = append(conditions, member.column+" = ?")
+			args = append(args, member.value)
+		}
+	}
+
+	if !query.Since.IsZero() {
+		conditions = append(conditions, "at >= ?")
+		args = append(args, query.Since.UTC())
+	}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, "at < ?")
+		args = append(args, query.Until.UTC())
+	}
+	return strings.Join(conditions, " AND "), args
+}
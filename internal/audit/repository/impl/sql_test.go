@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+func TestSqlRepositorySuite(t *testing.T) {
+	t.Log("Test SqlRepository Suite")
+
+	ctx := context.Background()
+
+	dbOptions := &options.DbOptions{
+		Type:         options.RepositoryTypeSQLite,
+		SqlDbOptions: options.SqlDbOptions{Dsn: filepath.Join(t.TempDir(), "audit.db")},
+	}
+	dbClient, err := db.NewClient(dbOptions)
+	if err != nil {
+		t.Fatalf("Failed to create the client: %s", err)
+	}
+	t.Cleanup(func() {
+		dbClient.(*sql.DB).Close()
+	})
+
+	repo := &SqlRepository{
+		Db:      dbClient.(*sql.DB),
+		Dialect: db.SQLite,
+	}
+	t.Logf("Repository URL: %s", dbOptions.Dsn)
+
+	t.Run("Test Append and Find", func(t *testing.T) {
+		t.Log("Testing SQL Append and Find")
+
+		at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, entry := range []audit.Entry{
+			{Id: "a1", At: at, Tenant: "t1", Entity: "example", EntityId: "e1", Actor: "alice", Operation: audit.OpCreate,
+				Changes: []audit.Change{{Field: "age", After: float64(1)}}},
+			{Id: "a2", At: at.Add(time.Minute), Tenant: "t1", Entity: "example", EntityId: "e1", Actor: "bob", Operation: audit.OpUpdate},
+			{Id: "a3", At: at.Add(2 * time.Minute), Tenant: "t2", Entity: "example", EntityId: "e1", Actor: "alice", Operation: audit.OpDelete},
+		} {
+			if err := repo.Append(ctx, entry); err != nil {
+				t.Fatalf("Error on Append %d: %s", i, err)
+			}
+		}
+
+		entries, err := repo.Find(ctx, audit.Query{Tenant: "t1", EntityId: "e1"})
+		if err != nil {
+			t.Fatalf("Error on Find: %s", err)
+		}
+		if len(entries) != 2 || entries[0].Id != "a2" || entries[1].Id != "a1" {
+			t.Errorf("Expected the entries of t1 newest first, got %#v", entries)
+		}
+
+		entries, _ = repo.Find(ctx, audit.Query{Tenant: "t1", Actor: "alice"})
+		if len(entries) != 1 || entries[0].Id != "a1" {
+			t.Errorf("Expected the entry of alice, got %#v", entries)
+		}
+
+		entries, _ = repo.Find(ctx, audit.Query{Tenant: "t1", Since: at.Add(time.Minute)})
+		if len(entries) != 1 || entries[0].Id != "a2" {
+			t.Errorf("Expected the entries since the second one, got %#v", entries)
+		}
+
+		entries, _ = repo.Find(ctx, audit.Query{Tenant: "t1", Limit: 1})
+		if len(entries) != 1 || entries[0].Id != "a2" {
+			t.Errorf("Expected the newest entry only, got %#v", entries)
+		}
+	})
+
+	t.Run("Test Changes", func(t *testing.T) {
+		t.Log("Testing SQL Changes")
+
+		entries, err := repo.Find(ctx, audit.Query{Tenant: "t1", Operation: audit.OpCreate})
+		if err != nil {
+			t.Fatalf("Error on Find: %s", err)
+		}
+		if len(entries) != 1 || len(entries[0].Changes) != 1 || entries[0].Changes[0].After != float64(1) {
+			t.Errorf("Expected the changes to be read back, got %#v", entries)
+		}
+	})
+
+	t.Run("Test Watch", func(t *testing.T) {
+		t.Log("Testing SQL Watch")
+
+		watchCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		entries, err := repo.Watch(watchCtx, audit.Query{Tenant: "t3"})
+		if err != nil {
+			t.Fatalf("Error on Watch: %s", err)
+		}
+
+		repo.Append(ctx, audit.Entry{Id: "w1", At: time.Now().UTC(), Tenant: "t4", Operation: audit.OpCreate})
+		repo.Append(ctx, audit.Entry{Id: "w2", At: time.Now().UTC(), Tenant: "t3", Operation: audit.OpCreate})
+
+		select {
+		case e := <-entries:
+			if e.Id != "w2" {
+				t.Errorf("Expected the entry of the watched tenant, got %#v", e)
+			}
+		case <-watchCtx.Done():
+			t.Fatal("Expected the appended entry to be watched")
+		}
+
+		cancel()
+		for range entries {
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	impl "github.com/morphy76/g-fe-server/internal/audit/repository/impl"
+	model "github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+const tracerName = "github.com/morphy76/g-fe-server/internal/audit/repository"
+
+type tracedRepository struct {
+	delegate model.Repository
+	system   string
+}
+
+func newTracedRepository(delegate model.Repository, system string) model.Repository {
+	return &tracedRepository{
+		delegate: delegate,
+		system:   system,
+	}
+}
+
+func (r *tracedRepository) Append(ctx context.Context, e model.Entry) error {
+	ctx, span := r.start(ctx, "Append")
+	err := r.delegate.Append(ctx, e)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) Find(ctx context.Context, query model.Query) ([]model.Entry, error) {
+	ctx, span := r.start(ctx, "Find")
+	span.SetAttributes(attribute.Int("db.query.limit", query.EffectiveLimit()))
+	rv, err := r.delegate.Find(ctx, query)
+	end(span, err)
+	return rv, err
+}
+
+// Watch traces the opening of the stream only, the stream is bound to the given context and outlives the span
+func (r *tracedRepository) Watch(ctx context.Context, query model.Query) (<-chan model.Entry, error) {
+	_, span := r.start(ctx, "Watch")
+	rv, err := r.delegate.Watch(ctx, query)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "audit."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", r.system),
+			attribute.String("db.collection.name", impl.MONGO_COLLECTION),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/serve"
+)
+
+const (
+	RESULT_LOCAL_HIT  = "local_hit"
+	RESULT_REMOTE_HIT = "remote_hit"
+	RESULT_MISS       = "miss"
+)
+
+// Cache holds the named read-through caches of the repositories, it lives as long as the application
+type Cache struct {
+	options options.CacheOptions
+	remote  Remote
+	lock    sync.Mutex
+	named   map[string]*namedCache
+}
+
+type namedCache struct {
+	name  string
+	local *lru
+	// generation is bumped by every invalidation, loads started before it do not fill the cache
+	generation atomic.Uint64
+	loads      singleflight.Group
+}
+
+// New builds the caches of the options, nil when they are disabled
+func New(cacheOptions options.CacheOptions) (*Cache, error) {
+	if !cacheOptions.Enabled() {
+		return nil, nil
+	}
+
+	rv := &Cache{
+		options: cacheOptions,
+		named:   make(map[string]*namedCache),
+	}
+	if cacheOptions.RedisUrl != "" {
+		remote, err := NewRedisRemote(cacheOptions.RedisUrl)
+		if err != nil {
+			return nil, err
+		}
+		rv.remote = remote
+	}
+	return rv, nil
+}
+
+// NewWithRemote builds the caches on the given shared tier
+func NewWithRemote(cacheOptions options.CacheOptions, remote Remote) *Cache {
+	return &Cache{
+		options: cacheOptions,
+		remote:  remote,
+		named:   make(map[string]*namedCache),
+	}
+}
+
+func (c *Cache) Close() error {
+	if c == nil || c.remote == nil {
+		return nil
+	}
+	return c.remote.Close()
+}
+
+func (c *Cache) cacheOf(name string) *namedCache {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	rv, ok := c.named[name]
+	if !ok {
+		rv = &namedCache{name: name}
+		if c.options.Size > 0 {
+			rv.local = newLru(c.options.Size, c.options.TTL, func() {
+				serve.CacheEvictionsTotal.WithLabelValues(name).Inc()
+			})
+		}
+		c.named[name] = rv
+	}
+	return rv
+}
+
+// ReadThrough looks the key up in the in-process tier, then in the shared one, and finally loads it; concurrent
+// misses of the same key share a single load. Errors are not cached, nor are the values of the shared tier which
+// can not be decoded.
+func ReadThrough[V any](ctx context.Context, c *Cache, name string, key string, load func(ctx context.Context) (V, error)) (V, error) {
+
+	named := c.cacheOf(name)
+	if named.local != nil {
+		if value, ok := named.local.get(key); ok {
+			serve.CacheRequestsTotal.WithLabelValues(name, RESULT_LOCAL_HIT).Inc()
+			return value.(V), nil
+		}
+	}
+
+	remoteKey := name + ":" + key
+	if c.remote != nil {
+		raw, err := c.remote.Get(ctx, remoteKey)
+		if err == nil {
+			var value V
+			if err := json.Unmarshal(raw, &value); err == nil {
+				serve.CacheRequestsTotal.WithLabelValues(name, RESULT_REMOTE_HIT).Inc()
+				if named.local != nil {
+					named.local.set(key, value)
+				}
+				return value, nil
+			}
+		} else if !IsMiss(err) {
+			log.Warn().Err(err).Str("cache", name).Msg("Shared cache lookup failed")
+		}
+	}
+
+	serve.CacheRequestsTotal.WithLabelValues(name, RESULT_MISS).Inc()
+
+	// a load started before a write must not be shared with readers coming after it
+	generation := named.generation.Load()
+	loaded, err, shared := named.loads.Do(fmt.Sprintf("%d:%s", generation, key), func() (any, error) {
+		value, err := load(context.WithoutCancel(ctx))
+		if err != nil {
+			return value, err
+		}
+		if named.generation.Load() == generation {
+			c.fill(ctx, named, key, remoteKey, value)
+		}
+		return value, nil
+	})
+	if shared {
+		serve.CacheCoalescedTotal.WithLabelValues(name).Inc()
+	}
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return loaded.(V), nil
+}
+
+func (c *Cache) fill(ctx context.Context, named *namedCache, key string, remoteKey string, value any) {
+	if named.local != nil {
+		named.local.set(key, value)
+	}
+	if c.remote == nil {
+		return
+	}
+
+	raw, err := json.Marshal(value)
+	if err == nil {
+		err = c.remote.Set(ctx, remoteKey, raw, c.options.RedisTTL)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("cache", named.name).Msg("Shared cache fill failed")
+	}
+}
+
+// Invalidate drops the keys from both tiers; other replicas keep their in-process entries until they expire
+func (c *Cache) Invalidate(ctx context.Context, name string, keys ...string) {
+
+	named := c.cacheOf(name)
+	named.generation.Add(1)
+	serve.CacheInvalidationsTotal.WithLabelValues(name).Add(float64(len(keys)))
+
+	remoteKeys := make([]string, len(keys))
+	for i, key := range keys {
+		if named.local != nil {
+			named.local.delete(key)
+		}
+		remoteKeys[i] = name + ":" + key
+	}
+
+	if c.remote != nil {
+		if err := c.remote.Delete(ctx, remoteKeys...); err != nil {
+			log.Warn().Err(err).Str("cache", name).Msg("Shared cache invalidation failed")
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+type value struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestCacheSuite(t *testing.T) {
+	t.Log("Test Cache Suite")
+
+	ctx := context.Background()
+	localOptions := options.CacheOptions{Size: 2, TTL: time.Minute}
+
+	t.Run("Test Disabled", func(t *testing.T) {
+		t.Log("Testing Disabled")
+
+		if c, err := New(options.CacheOptions{}); c != nil || err != nil {
+			t.Errorf("Expected no cache, got %v: %v", c, err)
+		}
+	})
+
+	t.Run("Test LRU", func(t *testing.T) {
+		t.Log("Testing LRU")
+
+		evicted := 0
+		local := newLru(2, 50*time.Millisecond, func() { evicted++ })
+		local.set("a", 1)
+		local.set("b", 2)
+		local.get("a")
+		local.set("c", 3)
+		if _, ok := local.get("b"); ok || evicted != 1 {
+			t.Errorf("Expected the least recently used entry to be evicted, got %d evictions", evicted)
+		}
+		if v, ok := local.get("a"); !ok || v != 1 {
+			t.Errorf("Expected the recently used entry, got %v", v)
+		}
+
+		time.Sleep(60 * time.Millisecond)
+		if _, ok := local.get("a"); ok {
+			t.Errorf("Expected the entry to expire")
+		}
+	})
+
+	t.Run("Test Read Through", func(t *testing.T) {
+		t.Log("Testing Read Through")
+
+		c, _ := New(localOptions)
+		loads := 0
+		load := func(context.Context) (value, error) {
+			loads++
+			return value{Name: "a", Age: loads}, nil
+		}
+
+		ReadThrough(ctx, c, "values", "a", load)
+		if v, _ := ReadThrough(ctx, c, "values", "a", load); loads != 1 || v.Age != 1 {
+			t.Errorf("Expected the cached value, got %v after %d loads", v, loads)
+		}
+
+		c.Invalidate(ctx, "values", "a")
+		if v, _ := ReadThrough(ctx, c, "values", "a", load); loads != 2 || v.Age != 2 {
+			t.Errorf("Expected a load after the invalidation, got %v after %d loads", v, loads)
+		}
+
+		failing := errors.New("failed")
+		if _, err := ReadThrough(ctx, c, "values", "b", func(context.Context) (value, error) { return value{}, failing }); err != failing {
+			t.Errorf("Expected the load error, got %v", err)
+		}
+		if v, err := ReadThrough(ctx, c, "values", "b", load); err != nil || v.Age != 3 {
+			t.Errorf("Expected errors not to be cached, got %v: %v", v, err)
+		}
+	})
+
+	t.Run("Test Stampede", func(t *testing.T) {
+		t.Log("Testing Stampede")
+
+		c, _ := New(localOptions)
+		var loads atomic.Int32
+		release := make(chan struct{})
+		load := func(context.Context) (value, error) {
+			loads.Add(1)
+			<-release
+			return value{Name: "hot"}, nil
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if v, err := ReadThrough(ctx, c, "values", "hot", load); err != nil || v.Name != "hot" {
+					t.Errorf("Expected the loaded value, got %v: %v", v, err)
+				}
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if loads.Load() != 1 {
+			t.Errorf("Expected concurrent misses to share a load, got %d loads", loads.Load())
+		}
+	})
+
+	t.Run("Test Invalidation During Load", func(t *testing.T) {
+		t.Log("Testing Invalidation During Load")
+
+		c, _ := New(localOptions)
+		ReadThrough(ctx, c, "values", "raced", func(context.Context) (value, error) {
+			// a write lands while the stale value is being read
+			c.Invalidate(ctx, "values", "raced")
+			return value{Age: 1}, nil
+		})
+
+		if v, _ := ReadThrough(ctx, c, "values", "raced", func(context.Context) (value, error) { return value{Age: 2}, nil }); v.Age != 2 {
+			t.Errorf("Expected the stale load not to be cached, got %v", v)
+		}
+	})
+
+	t.Run("Test Redis", func(t *testing.T) {
+		t.Log("Testing Redis")
+
+		redisServer := miniredis.RunT(t)
+		redisOptions := options.CacheOptions{Size: 2, TTL: time.Minute, RedisUrl: "redis://" + redisServer.Addr(), RedisTTL: time.Minute}
+
+		replicaA, err := New(redisOptions)
+		if err != nil {
+			t.Fatalf("Failed to create the cache: %s", err)
+		}
+		defer replicaA.Close()
+		replicaB, _ := New(redisOptions)
+		defer replicaB.Close()
+
+		ReadThrough(ctx, replicaA, "values", "shared", func(context.Context) (value, error) { return value{Name: "shared", Age: 7}, nil })
+		if ttl := redisServer.TTL(REDIS_KEY_PREFIX + "values:shared"); ttl != time.Minute {
+			t.Errorf("Expected the shared entry to expire in a minute, got %s", ttl)
+		}
+
+		v, err := ReadThrough(ctx, replicaB, "values", "shared", func(context.Context) (value, error) {
+			t.Error("Expected the shared tier to answer")
+			return value{}, nil
+		})
+		if err != nil || v.Age != 7 {
+			t.Errorf("Expected the shared value, got %v: %v", v, err)
+		}
+
+		replicaA.Invalidate(ctx, "values", "shared")
+		if redisServer.Exists(REDIS_KEY_PREFIX + "values:shared") {
+			t.Errorf("Expected the shared entry to be invalidated")
+		}
+
+		if label, status := CreateHealthCheck(replicaA)(ctx); label != "Redis" || status != app_http.Active {
+			t.Errorf("Expected an active Redis, got %s %v", label, status)
+		}
+		redisServer.Close()
+		if _, status := CreateHealthCheck(replicaA)(ctx); status != app_http.Inactive {
+			t.Errorf("Expected an inactive Redis, got %v", status)
+		}
+		if v, err := ReadThrough(ctx, replicaA, "values", "down", func(context.Context) (value, error) { return value{Age: 9}, nil }); err != nil || v.Age != 9 {
+			t.Errorf("Expected loads to go on without Redis, got %v: %v", v, err)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+)
+
+type ContextCacheKey string
+
+const ctx_CACHE_KEY ContextCacheKey = "cache"
+
+// Extract returns the caches of the context, nil when caching is disabled
+func Extract(ctx context.Context) *Cache {
+	rv, _ := ctx.Value(ctx_CACHE_KEY).(*Cache)
+	return rv
+}
+
+func Inject(ctx context.Context, c *Cache) context.Context {
+	return context.WithValue(ctx, ctx_CACHE_KEY, c)
+}
+
+// CreateHealthCheck pings the shared tier, nil when there is none
+func CreateHealthCheck(c *Cache) app_http.HealthCheckFn {
+	if c == nil || c.remote == nil {
+		return nil
+	}
+
+	return func(requestContext context.Context) (string, app_http.Status) {
+		timeoutContext, cancel := context.WithTimeout(requestContext, 5*time.Second)
+		defer cancel()
+
+		if err := c.remote.Ping(timeoutContext); err != nil {
+			return "Redis", app_http.Inactive
+		}
+		return "Redis", app_http.Active
+	}
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrMiss tells that a remote cache does not hold the key
+var ErrMiss = errors.New("cache miss")
+
+func IsMiss(err error) bool {
+	return errors.Is(err, ErrMiss)
+}
+
+// Remote is the tier shared by the replicas, values are JSON documents
+type Remote interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// REDIS_KEY_PREFIX keeps the cache keys apart from other uses of the same database
+const REDIS_KEY_PREFIX = "g-fe-server:cache:"
+
+type redisRemote struct {
+	client *redis.Client
+}
+
+// NewRedisRemote connects lazily to the redis of the URL
+func NewRedisRemote(redisUrl string) (Remote, error) {
+	redisOptions, err := redis.ParseURL(redisUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &redisRemote{client: redis.NewClient(redisOptions)}, nil
+}
+
+func (r *redisRemote) Get(ctx context.Context, key string) ([]byte, error) {
+	rv, err := r.client.Get(ctx, REDIS_KEY_PREFIX+key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrMiss
+	}
+	return rv, err
+}
+
+func (r *redisRemote) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, REDIS_KEY_PREFIX+key, value, ttl).Err()
+}
+
+func (r *redisRemote) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = REDIS_KEY_PREFIX + key
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+func (r *redisRemote) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *redisRemote) Close() error {
+	return r.client.Close()
+}
@@ -11,12 +11,13 @@ import (
 )
 
 type DbClient any
-type MemoryDbClient struct{}
 
 var ErrMissingDbOptions = errors.New("missing db options")
 
 var ErrUnknownDbType = errors.New("unknown database type")
 
+var ErrUnknownColumn = errors.New("unknown column")
+
 func IsMissingDbOptions(err error) bool {
 	return err == ErrMissingDbOptions
 }
@@ -25,12 +26,15 @@ func IsUnknownDbType(err error) bool {
 	return err == ErrUnknownDbType
 }
 
+func IsUnknownColumn(err error) bool {
+	return errors.Is(err, ErrUnknownColumn)
+}
+
 func NewClient(dbOptions *options.DbOptions) (DbClient, error) {
 	if dbOptions == nil {
 		return nil, ErrMissingDbOptions
 	} else if dbOptions.Type == options.RepositoryTypeMemoryDB {
-		var rv *MemoryDbClient = new(MemoryDbClient)
-		return rv, nil
+		return newMemoryClient(dbOptions.MemoryDbOptions)
 	} else if dbOptions.Type == options.RepositoryTypeMongoDB {
 		var clientOpts *mongo_opts.ClientOptions
 		serverAPI := mongo_opts.ServerAPI(mongo_opts.ServerAPIVersion1)
@@ -57,6 +61,8 @@ func NewClient(dbOptions *options.DbOptions) (DbClient, error) {
 		}
 
 		return mongoClient, nil
+	} else if dbOptions.Type.IsSQL() {
+		return newSqlClient(dbOptions)
 	} else {
 		return nil, ErrUnknownDbType
 	}
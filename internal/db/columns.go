@@ -0,0 +1,163 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// fieldsCache holds the db tagged fields of each struct type, by column name
+var fieldsCache sync.Map
+
+type columnFields struct {
+	columns []string
+	index   map[string][]int
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func fieldsOf(t reflect.Type) *columnFields {
+	if cached, ok := fieldsCache.Load(t); ok {
+		return cached.(*columnFields)
+	}
+
+	rv := &columnFields{index: make(map[string][]int)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		column := field.Tag.Get("db")
+		if column == "" || column == "-" || !field.IsExported() {
+			continue
+		}
+		rv.columns = append(rv.columns, column)
+		rv.index[column] = field.Index
+	}
+
+	fieldsCache.Store(t, rv)
+	return rv
+}
+
+func structValue(v any) reflect.Value {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// Columns lists the db tagged fields of a struct, in declaration order
+func Columns(v any) []string {
+	return fieldsOf(structValue(v).Type()).columns
+}
+
+// Values returns the values of the given columns of a struct, ready to be bound to a query: nil pointers
+// are NULL, times are UTC and slices, maps and structs are JSON documents
+func Values(v any, columns ...string) ([]any, error) {
+	sv := structValue(v)
+	fields := fieldsOf(sv.Type())
+
+	rv := make([]any, 0, len(columns))
+	for _, column := range columns {
+		index, ok := fields.index[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+		value, err := columnValue(sv.FieldByIndex(index))
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, value)
+	}
+	return rv, nil
+}
+
+func columnValue(field reflect.Value) (any, error) {
+	if field.Kind() == reflect.Pointer {
+		if field.IsNil() {
+			return nil, nil
+		}
+		field = field.Elem()
+	}
+
+	if field.Type() == timeType {
+		return field.Interface().(time.Time).UTC(), nil
+	}
+	if _, ok := field.Interface().(driver.Valuer); ok {
+		return field.Interface(), nil
+	}
+
+	switch field.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Struct, reflect.Array:
+		if field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8 {
+			return field.Bytes(), nil
+		}
+		bytes, err := json.Marshal(field.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return string(bytes), nil
+	case reflect.String:
+		return field.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(field.Uint()), nil
+	case reflect.Bool:
+		return field.Bool(), nil
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), nil
+	}
+	return field.Interface(), nil
+}
+
+// Targets returns the scan destinations of the given columns into the struct pointed by ptr
+func Targets(ptr any, columns ...string) ([]any, error) {
+	sv := structValue(ptr)
+	fields := fieldsOf(sv.Type())
+
+	rv := make([]any, 0, len(columns))
+	for _, column := range columns {
+		index, ok := fields.index[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownColumn, column)
+		}
+		field := sv.FieldByIndex(index)
+
+		elemType := field.Type()
+		if elemType.Kind() == reflect.Pointer {
+			elemType = elemType.Elem()
+		}
+		switch {
+		case elemType == timeType:
+			rv = append(rv, field.Addr().Interface())
+		case elemType.Kind() == reflect.Slice && elemType.Elem().Kind() == reflect.Uint8:
+			rv = append(rv, field.Addr().Interface())
+		case elemType.Kind() == reflect.Slice, elemType.Kind() == reflect.Map,
+			elemType.Kind() == reflect.Struct, elemType.Kind() == reflect.Array:
+			rv = append(rv, &jsonColumn{target: field.Addr().Interface()})
+		default:
+			rv = append(rv, field.Addr().Interface())
+		}
+	}
+	return rv, nil
+}
+
+// jsonColumn decodes the JSON documents written by Values
+type jsonColumn struct {
+	target any
+}
+
+func (c *jsonColumn) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(value, c.target)
+	case string:
+		return json.Unmarshal([]byte(value), c.target)
+	default:
+		return fmt.Errorf("unexpected json column of type %T", src)
+	}
+}
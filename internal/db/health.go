@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"reflect"
 	"time"
 
@@ -49,6 +50,16 @@ func testDbStatus(requestContext context.Context) (string, app_http.Status) {
 				}
 			}
 		}
+	} else if dbOptions.Type.IsSQL() {
+		label = "PostgreSQL"
+		if dbOptions.Type == options.RepositoryTypeSQLite {
+			label = "SQLite"
+		}
+		if sqlDb, ok := ExtractDb(requestContext).(*sql.DB); ok {
+			if err := sqlDb.PingContext(timeoutContext); err == nil {
+				dbStatus = app_http.Active
+			}
+		}
 	}
 
 	return label, dbStatus
@@ -0,0 +1,235 @@
+package db
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+var ErrMemoryStoreType = errors.New("memory store of another type")
+
+func IsMemoryStoreType(err error) bool {
+	return errors.Is(err, ErrMemoryStoreType)
+}
+
+// MemoryStore is a named collection of a MemoryDbClient, its state is snapshotted and restored as a JSON document
+type MemoryStore interface {
+	Snapshot() (json.RawMessage, error)
+	Restore(document json.RawMessage) error
+}
+
+// MemoryDbClient owns the stores of the memory database; repositories created from the same client share
+// them, distinct clients are isolated from each other
+type MemoryDbClient struct {
+	lock   sync.Mutex
+	stores map[string]MemoryStore
+	// loaded holds the snapshot or seed documents of the stores which were not requested yet
+	loaded  map[string]json.RawMessage
+	options options.MemoryDbOptions
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// NewMemoryDbClient returns an empty client, without seed nor snapshots
+func NewMemoryDbClient() *MemoryDbClient {
+	return &MemoryDbClient{
+		stores: make(map[string]MemoryStore),
+		loaded: make(map[string]json.RawMessage),
+	}
+}
+
+// newMemoryClient reloads the snapshot file if any, the seed file otherwise, and starts the periodic snapshots
+func newMemoryClient(memoryOptions options.MemoryDbOptions) (*MemoryDbClient, error) {
+
+	rv := NewMemoryDbClient()
+	rv.options = memoryOptions
+
+	source := memoryOptions.SeedFile
+	if memoryOptions.SnapshotFile != "" {
+		if _, err := os.Stat(memoryOptions.SnapshotFile); err == nil {
+			source = memoryOptions.SnapshotFile
+		}
+	}
+	if source != "" {
+		raw, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &rv.loaded); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+	}
+
+	if memoryOptions.SnapshotFile != "" && memoryOptions.SnapshotInterval > 0 {
+		rv.stop = make(chan struct{})
+		rv.stopped.Add(1)
+		go rv.snapshotLoop(memoryOptions.SnapshotInterval)
+	}
+
+	return rv, nil
+}
+
+// Store returns the store of the given name, creating it with newStore and restoring its loaded document on first use
+func (c *MemoryDbClient) Store(name string, newStore func() MemoryStore) (MemoryStore, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if store, ok := c.stores[name]; ok {
+		return store, nil
+	}
+
+	store := newStore()
+	if document, ok := c.loaded[name]; ok {
+		if err := store.Restore(document); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		delete(c.loaded, name)
+	}
+	c.stores[name] = store
+	return store, nil
+}
+
+// Snapshot writes every store to the snapshot file, atomically replacing the previous one
+func (c *MemoryDbClient) Snapshot() error {
+	if c.options.SnapshotFile == "" {
+		return nil
+	}
+
+	c.lock.Lock()
+	documents := make(map[string]json.RawMessage, len(c.stores)+len(c.loaded))
+	for name, document := range c.loaded {
+		documents[name] = document
+	}
+	stores := make(map[string]MemoryStore, len(c.stores))
+	for name, store := range c.stores {
+		stores[name] = store
+	}
+	c.lock.Unlock()
+
+	for name, store := range stores {
+		document, err := store.Snapshot()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		documents[name] = document
+	}
+
+	raw, err := json.MarshalIndent(documents, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.options.SnapshotFile), filepath.Base(c.options.SnapshotFile)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.options.SnapshotFile)
+}
+
+// Close stops the periodic snapshots and writes the last one
+func (c *MemoryDbClient) Close() error {
+	if c.stop != nil {
+		close(c.stop)
+		c.stopped.Wait()
+		c.stop = nil
+	}
+	return c.Snapshot()
+}
+
+func (c *MemoryDbClient) snapshotLoop(interval time.Duration) {
+	defer c.stopped.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if err := c.Snapshot(); err != nil {
+				log.Error().Err(err).Str("file", c.options.SnapshotFile).Msg("Memory snapshot failed")
+			}
+		}
+	}
+}
+
+// MemoryTable is a MemoryStore of values by key, Lock guards Rows; the map is never replaced so it can be aliased
+type MemoryTable[K comparable, V any] struct {
+	Lock sync.RWMutex
+	Rows map[K]V
+	key  func(V) K
+}
+
+func NewMemoryTable[K comparable, V any](key func(V) K) *MemoryTable[K, V] {
+	return &MemoryTable[K, V]{
+		Rows: make(map[K]V),
+		key:  key,
+	}
+}
+
+// MemoryTableOf returns the table of the given name of the client
+func MemoryTableOf[K comparable, V any](client *MemoryDbClient, name string, key func(V) K) (*MemoryTable[K, V], error) {
+
+	store, err := client.Store(name, func() MemoryStore { return NewMemoryTable(key) })
+	if err != nil {
+		return nil, err
+	}
+
+	rv, ok := store.(*MemoryTable[K, V])
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMemoryStoreType, name)
+	}
+	return rv, nil
+}
+
+// Snapshot lists the rows ordered by key, so that snapshots of the same state are equal
+func (t *MemoryTable[K, V]) Snapshot() (json.RawMessage, error) {
+	t.Lock.RLock()
+	defer t.Lock.RUnlock()
+
+	keys := make([]K, 0, len(t.Rows))
+	for k := range t.Rows {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+
+	rows := make([]V, 0, len(keys))
+	for _, k := range keys {
+		rows = append(rows, t.Rows[k])
+	}
+	return json.Marshal(rows)
+}
+
+func (t *MemoryTable[K, V]) Restore(document json.RawMessage) error {
+	var rows []V
+	if err := json.Unmarshal(document, &rows); err != nil {
+		return err
+	}
+
+	t.Lock.Lock()
+	defer t.Lock.Unlock()
+
+	for _, row := range rows {
+		t.Rows[t.key(row)] = row
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+type item struct {
+	Id    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+func itemId(i item) string {
+	return i.Id
+}
+
+func TestMemorySuite(t *testing.T) {
+	t.Log("Test Memory Suite")
+
+	t.Run("Test Concurrent Writes", func(t *testing.T) {
+		t.Log("Testing Concurrent Writes")
+
+		table, err := MemoryTableOf(NewMemoryDbClient(), "items", itemId)
+		if err != nil {
+			t.Fatalf("Failed to bind the table: %s", err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				table.Lock.Lock()
+				defer table.Lock.Unlock()
+				current := table.Rows["shared"]
+				current.Id = "shared"
+				current.Count++
+				table.Rows["shared"] = current
+			}()
+		}
+		wg.Wait()
+
+		if table.Rows["shared"].Count != 50 {
+			t.Errorf("Expected 50 increments, got %d", table.Rows["shared"].Count)
+		}
+	})
+
+	t.Run("Test Store Type", func(t *testing.T) {
+		t.Log("Testing Store Type")
+
+		client := NewMemoryDbClient()
+		MemoryTableOf(client, "items", itemId)
+		if _, err := MemoryTableOf(client, "items", func(s string) string { return s }); !IsMemoryStoreType(err) {
+			t.Errorf("Expected a store type mismatch, got %v", err)
+		}
+	})
+
+	t.Run("Test Seed and Snapshot", func(t *testing.T) {
+		t.Log("Testing Seed and Snapshot")
+
+		dir := t.TempDir()
+		seed := filepath.Join(dir, "seed.json")
+		os.WriteFile(seed, []byte(`{"items": [{"id": "seeded", "count": 1}], "untouched": [1, 2]}`), 0644)
+
+		memoryOptions := options.MemoryDbOptions{
+			SeedFile:     seed,
+			SnapshotFile: filepath.Join(dir, "snapshot.json"),
+		}
+		client, err := newMemoryClient(memoryOptions)
+		if err != nil {
+			t.Fatalf("Failed to create the client: %s", err)
+		}
+		table, _ := MemoryTableOf(client, "items", itemId)
+		if table.Rows["seeded"].Count != 1 {
+			t.Fatalf("Expected the seeded item, got %v", table.Rows)
+		}
+		table.Rows["added"] = item{Id: "added", Count: 2}
+		if err := client.Close(); err != nil {
+			t.Fatalf("Failed to close the client: %s", err)
+		}
+
+		// the snapshot wins over the seed
+		os.WriteFile(seed, []byte(`{"items": []}`), 0644)
+		reloaded, err := newMemoryClient(memoryOptions)
+		if err != nil {
+			t.Fatalf("Failed to reload the client: %s", err)
+		}
+		table, _ = MemoryTableOf(reloaded, "items", itemId)
+		if len(table.Rows) != 2 || table.Rows["added"].Count != 2 {
+			t.Errorf("Expected the snapshotted items, got %v", table.Rows)
+		}
+		if _, ok := reloaded.loaded["untouched"]; !ok {
+			t.Errorf("Expected the stores never used to be kept in the snapshot")
+		}
+	})
+
+	t.Run("Test Invalid Seed", func(t *testing.T) {
+		t.Log("Testing Invalid Seed")
+
+		seed := filepath.Join(t.TempDir(), "seed.json")
+		os.WriteFile(seed, []byte(`[]`), 0644)
+		if _, err := newMemoryClient(options.MemoryDbOptions{SeedFile: seed}); err == nil {
+			t.Errorf("Expected an invalid seed to be refused")
+		}
+	})
+}
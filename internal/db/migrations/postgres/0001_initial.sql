@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS examples (
+    name VARCHAR(64) COLLATE "C" PRIMARY KEY,
+    age INTEGER NOT NULL,
+    version BIGINT NOT NULL,
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS examples_age_name ON examples (age, name);
+
+CREATE TABLE IF NOT EXISTS audit (
+    id VARCHAR(64) PRIMARY KEY,
+    at TIMESTAMPTZ NOT NULL,
+    actor TEXT NOT NULL,
+    actor_kind TEXT NOT NULL,
+    tenant TEXT NOT NULL,
+    entity TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    changes TEXT NOT NULL,
+    trace_id TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS audit_tenant_at ON audit (tenant, at, id);
+
+CREATE TABLE IF NOT EXISTS apikeys (
+    id VARCHAR(64) PRIMARY KEY,
+    name TEXT NOT NULL,
+    tenant TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    created_by TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS apikeys_tenant ON apikeys (tenant, created_at);
+
+CREATE TABLE IF NOT EXISTS entities (
+    kind VARCHAR(64) NOT NULL,
+    id VARCHAR(256) NOT NULL,
+    version BIGINT NOT NULL,
+    document TEXT NOT NULL,
+    PRIMARY KEY (kind, id)
+);
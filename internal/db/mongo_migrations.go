@@ -0,0 +1,381 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_opts "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+const (
+	MONGO_MIGRATIONS_COLLECTION      = "migrations"
+	MONGO_MIGRATIONS_LOCK_COLLECTION = "migrations_lock"
+)
+
+// mongoMigrationLease bounds how long a crashed migrator keeps the others waiting, the holder renews it every
+// third of the lease while it migrates
+const mongoMigrationLease = time.Minute
+
+var ErrIrreversibleMigration = errors.New("migration can not be reverted")
+
+func IsIrreversibleMigration(err error) bool {
+	return errors.Is(err, ErrIrreversibleMigration)
+}
+
+// MongoMigration is a versioned change of the schema, Down is nil when the change can not be reverted
+type MongoMigration struct {
+	Version     int64
+	Description string
+	Up          func(ctx context.Context, database *mongo.Database) error
+	Down        func(ctx context.Context, database *mongo.Database) error
+}
+
+// MigrationStatus tells whether a migration is applied, AppliedAt is nil when it is pending
+type MigrationStatus struct {
+	Version     int64
+	Description string
+	AppliedAt   *time.Time
+}
+
+type migrationRecord struct {
+	Version     int64     `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// mongoMigrations are compiled in the binary, in ascending version order
+var mongoMigrations = []MongoMigration{
+	{
+		Version:     1,
+		Description: "create the examples, apikeys and audit collections",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for _, name := range []string{"examples", "apikeys", "audit"} {
+				if err := createCollection(ctx, database, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "index examples by name and age, apikeys by id and tenant, audit by tenant",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for name, models := range mongoIndexes {
+				if _, err := database.Collection(name).Indexes().CreateMany(ctx, models); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			for name, models := range mongoIndexes {
+				for _, model := range models {
+					_, err := database.Collection(name).Indexes().DropOne(ctx, *model.Options.Name)
+					if err != nil && !isMongoCode(err, 27) {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     3,
+		Description: "create the outbox collection, indexed by id and by due events, sent events expire after a week",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			if err := createCollection(ctx, database, "outbox"); err != nil {
+				return err
+			}
+			_, err := database.Collection("outbox").Indexes().CreateMany(ctx, outboxIndexes)
+			return err
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			return database.Collection("outbox").Drop(ctx)
+		},
+	},
+	{
+		Version:     4,
+		Description: "create the webhooks and webhook_deliveries collections, deliveries expire after a month",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for name, models := range webhookIndexes {
+				if err := createCollection(ctx, database, name); err != nil {
+					return err
+				}
+				if _, err := database.Collection(name).Indexes().CreateMany(ctx, models); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, database *mongo.Database) error {
+			for name := range webhookIndexes {
+				if err := database.Collection(name).Drop(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+var webhookIndexes = map[string][]mongo.IndexModel{
+	"webhooks": {
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: mongo_opts.Index().SetName("id_1").SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "event_types", Value: 1}}, Options: mongo_opts.Index().SetName("tenant_1_event_types_1")},
+	},
+	"webhook_deliveries": {
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: mongo_opts.Index().SetName("id_1").SetUnique(true)},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}, Options: mongo_opts.Index().SetName("status_1_next_attempt_at_1")},
+		{Keys: bson.D{{Key: "subscription_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "id", Value: -1}}, Options: mongo_opts.Index().SetName("subscription_id_1_created_at_-1_id_-1")},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}, Options: mongo_opts.Index().SetName("created_at_1").SetExpireAfterSeconds(30 * 24 * 60 * 60)},
+	},
+}
+
+var outboxIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "id", Value: 1}}, Options: mongo_opts.Index().SetName("id_1").SetUnique(true)},
+	{Keys: bson.D{{Key: "sent_at", Value: 1}, {Key: "next_attempt_at", Value: 1}}, Options: mongo_opts.Index().SetName("sent_at_1_next_attempt_at_1")},
+	{Keys: bson.D{{Key: "sent_at", Value: 1}}, Options: mongo_opts.Index().SetName("sent_at_1").SetExpireAfterSeconds(7 * 24 * 60 * 60)},
+}
+
+// mongoIndexes keep the default names so that indexes created by the former init scripts are recognized
+var mongoIndexes = map[string][]mongo.IndexModel{
+	"examples": {
+		{Keys: bson.D{{Key: "name", Value: 1}}, Options: mongo_opts.Index().SetName("name_1").SetUnique(true)},
+		{Keys: bson.D{{Key: "age", Value: 1}, {Key: "name", Value: 1}}, Options: mongo_opts.Index().SetName("age_1_name_1")},
+	},
+	"apikeys": {
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: mongo_opts.Index().SetName("id_1").SetUnique(true)},
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "created_at", Value: 1}}, Options: mongo_opts.Index().SetName("tenant_1_created_at_1")},
+	},
+	"audit": {
+		{Keys: bson.D{{Key: "tenant", Value: 1}, {Key: "at", Value: -1}, {Key: "id", Value: -1}}, Options: mongo_opts.Index().SetName("tenant_1_at_-1_id_-1")},
+	},
+}
+
+// MongoDatabase returns the database named by the path of the mongo URL
+func MongoDatabase(client *mongo.Client, dbOptions *options.DbOptions) (*mongo.Database, error) {
+	useUrl, err := url.Parse(dbOptions.Url)
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(path.Base(useUrl.Path)), nil
+}
+
+// MongoMigrateUp applies the migrations which are not recorded in the migrations collection yet and returns
+// their versions; concurrent replicas serialize on a lock document
+func MongoMigrateUp(ctx context.Context, database *mongo.Database) ([]int64, error) {
+
+	release, err := lockMigrations(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	applied, err := appliedMigrations(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]int64, 0)
+	for _, migration := range mongoMigrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := migration.Up(ctx, database); err != nil {
+			return rv, fmt.Errorf("migration %d: %w", migration.Version, err)
+		}
+		_, err := database.Collection(MONGO_MIGRATIONS_COLLECTION).InsertOne(ctx, migrationRecord{
+			Version:     migration.Version,
+			Description: migration.Description,
+			AppliedAt:   time.Now().UTC(),
+		})
+		if err != nil {
+			return rv, fmt.Errorf("migration %d: %w", migration.Version, err)
+		}
+		log.Info().Int64("version", migration.Version).Str("description", migration.Description).Msg("Migration applied")
+		rv = append(rv, migration.Version)
+	}
+
+	return rv, nil
+}
+
+// MongoMigrateDown reverts the last steps applied migrations, latest first, and returns their versions
+func MongoMigrateDown(ctx context.Context, database *mongo.Database, steps int) ([]int64, error) {
+
+	release, err := lockMigrations(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	applied, err := appliedMigrations(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]int64, 0)
+	for i := len(mongoMigrations) - 1; i >= 0 && len(rv) < steps; i-- {
+		migration := mongoMigrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return rv, fmt.Errorf("migration %d: %w", migration.Version, ErrIrreversibleMigration)
+		}
+		if err := migration.Down(ctx, database); err != nil {
+			return rv, fmt.Errorf("migration %d: %w", migration.Version, err)
+		}
+		_, err := database.Collection(MONGO_MIGRATIONS_COLLECTION).DeleteOne(ctx, bson.D{{Key: "_id", Value: migration.Version}})
+		if err != nil {
+			return rv, fmt.Errorf("migration %d: %w", migration.Version, err)
+		}
+		log.Info().Int64("version", migration.Version).Str("description", migration.Description).Msg("Migration reverted")
+		rv = append(rv, migration.Version)
+	}
+
+	return rv, nil
+}
+
+// MongoMigrationStatus lists the known migrations and the recorded ones unknown to this binary, by version
+func MongoMigrationStatus(ctx context.Context, database *mongo.Database) ([]MigrationStatus, error) {
+
+	applied, err := appliedMigrations(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]MigrationStatus, 0, len(mongoMigrations))
+	for _, migration := range mongoMigrations {
+		status := MigrationStatus{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, migration.Version)
+		}
+		rv = append(rv, status)
+	}
+	for _, record := range applied {
+		rv = append(rv, MigrationStatus{Version: record.Version, Description: record.Description, AppliedAt: &record.AppliedAt})
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Version < rv[j].Version })
+
+	return rv, nil
+}
+
+func appliedMigrations(ctx context.Context, database *mongo.Database) (map[int64]migrationRecord, error) {
+
+	cur, err := database.Collection(MONGO_MIGRATIONS_COLLECTION).Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var records []migrationRecord
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	rv := make(map[int64]migrationRecord, len(records))
+	for _, record := range records {
+		rv[record.Version] = record
+	}
+	return rv, nil
+}
+
+// lockMigrations takes the lease of the lock document, waiting for the current holder until ctx is done
+func lockMigrations(ctx context.Context, database *mongo.Database) (func(), error) {
+
+	collection := database.Collection(MONGO_MIGRATIONS_LOCK_COLLECTION)
+	hostname, _ := os.Hostname()
+	owner := hostname + ":" + strconv.Itoa(os.Getpid()) + ":" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	for {
+		now := time.Now().UTC()
+		_, err := collection.UpdateOne(ctx,
+			bson.D{{Key: "_id", Value: "migrations"}, {Key: "expires_at", Value: bson.D{{Key: "$lt", Value: now}}}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "owner", Value: owner}, {Key: "expires_at", Value: now.Add(mongoMigrationLease)}}}},
+			mongo_opts.Update().SetUpsert(true),
+		)
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, err
+		}
+
+		log.Debug().Msg("Waiting for the migrations lock")
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	stop := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		renewMigrationsLock(ctx, collection, owner, stop)
+	}()
+
+	return func() {
+		close(stop)
+		<-renewed
+		_, err := collection.DeleteOne(context.WithoutCancel(ctx), bson.D{{Key: "_id", Value: "migrations"}, {Key: "owner", Value: owner}})
+		if err != nil {
+			log.Warn().Err(err).Msg("Migrations lock release failed")
+		}
+	}, nil
+}
+
+// renewMigrationsLock extends the lease of the owner until stopped, so that long index builds do not let another
+// replica take the lock and run the same migrations
+func renewMigrationsLock(ctx context.Context, collection *mongo.Collection, owner string, stop <-chan struct{}) {
+
+	ticker := time.NewTicker(mongoMigrationLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := collection.UpdateOne(ctx,
+				bson.D{{Key: "_id", Value: "migrations"}, {Key: "owner", Value: owner}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "expires_at", Value: time.Now().UTC().Add(mongoMigrationLease)}}}},
+			)
+			if err != nil {
+				log.Warn().Err(err).Msg("Migrations lock renewal failed")
+			}
+		}
+	}
+}
+
+// createCollection tolerates collections created by the former init scripts
+func createCollection(ctx context.Context, database *mongo.Database, name string) error {
+	err := database.CreateCollection(ctx, name)
+	if err != nil && !isMongoCode(err, 48) {
+		return err
+	}
+	return nil
+}
+
+// isMongoCode tells a server error by code, e.g. 27 IndexNotFound or 48 NamespaceExists
+func isMongoCode(err error, code int) bool {
+	var serverError mongo.ServerError
+	return errors.As(err, &serverError) && serverError.HasErrorCode(code)
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMongoMigrationsSuite(t *testing.T) {
+	t.Log("Test Mongo Migrations Suite")
+
+	t.Run("Test Versions", func(t *testing.T) {
+		t.Log("Testing Versions")
+
+		var previous int64
+		for _, migration := range mongoMigrations {
+			if migration.Version <= previous {
+				t.Errorf("Expected ascending versions, got %d after %d", migration.Version, previous)
+			}
+			if migration.Up == nil || migration.Description == "" {
+				t.Errorf("Expected migration %d to be described and applicable", migration.Version)
+			}
+			previous = migration.Version
+		}
+	})
+
+	t.Run("Test Named Indexes", func(t *testing.T) {
+		t.Log("Testing Named Indexes")
+
+		for collection, models := range mongoIndexes {
+			for _, model := range models {
+				if model.Options == nil || model.Options.Name == nil {
+					t.Errorf("Expected the indexes of %s to be named to be dropped", collection)
+				}
+			}
+		}
+	})
+
+	t.Run("Test Database", func(t *testing.T) {
+		t.Log("Testing Database")
+
+		database, err := MongoDatabase(&mongo.Client{}, &options.DbOptions{
+			MongoDbOptions: options.MongoDbOptions{Url: "mongodb://localhost:27017/go_db?w=1"},
+		})
+		if err != nil {
+			t.Fatalf("Error on MongoDatabase: %s", err)
+		}
+		if database.Name() != "go_db" {
+			t.Errorf("Expected the database of the URL path, got %s", database.Name())
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// Dialect hides the differences between the supported sql databases, queries are written with ? placeholders
+type Dialect struct {
+	Name     string
+	Driver   string
+	numbered bool
+}
+
+var (
+	PostgreSQL = Dialect{Name: "postgres", Driver: "pgx", numbered: true}
+	SQLite     = Dialect{Name: "sqlite", Driver: "sqlite3"}
+)
+
+// DialectOf returns the dialect of a sql repository type
+func DialectOf(repositoryType options.RepositoryType) (Dialect, error) {
+	switch repositoryType {
+	case options.RepositoryTypePostgreSQL:
+		return PostgreSQL, nil
+	case options.RepositoryTypeSQLite:
+		return SQLite, nil
+	default:
+		return Dialect{}, ErrUnknownDbType
+	}
+}
+
+// Rebind turns the ? placeholders of query into the ones of the dialect
+func (d Dialect) Rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+// Querier is satisfied by both *sql.DB and *sql.Tx
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func newSqlClient(dbOptions *options.DbOptions) (*sql.DB, error) {
+
+	dialect, err := DialectOf(dbOptions.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDb, err := sql.Open(dialect.Driver, dbOptions.Dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if dialect == SQLite {
+		// sqlite serializes writers, a single connection avoids busy errors
+		sqlDb.SetMaxOpenConns(1)
+	} else {
+		sqlDb.SetMaxOpenConns(dbOptions.MaxOpenConns)
+	}
+
+	timeoutContext, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := sqlDb.PingContext(timeoutContext); err != nil {
+		sqlDb.Close()
+		return nil, err
+	}
+	if err := Migrate(timeoutContext, sqlDb, dialect); err != nil {
+		sqlDb.Close()
+		return nil, err
+	}
+
+	return sqlDb, nil
+}
+
+// Migrate applies the embedded schema migrations of the dialect which are not recorded in schema_migrations yet
+func Migrate(ctx context.Context, sqlDb *sql.DB, dialect Dialect) error {
+
+	_, err := sqlDb.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL
+)`)
+	if err != nil {
+		return err
+	}
+
+	dir := path.Join("migrations", dialect.Name)
+	files, err := fs.ReadDir(migrations, dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	for _, file := range files {
+		version, err := migrationVersion(file.Name())
+		if err != nil {
+			return err
+		}
+		script, err := fs.ReadFile(migrations, path.Join(dir, file.Name()))
+		if err != nil {
+			return err
+		}
+		if err := applyMigration(ctx, sqlDb, dialect, version, string(script)); err != nil {
+			return fmt.Errorf("migration %s: %w", file.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration runs a script once, concurrent replicas serialize on an advisory lock in postgres
+func applyMigration(ctx context.Context, sqlDb *sql.DB, dialect Dialect, version int64, script string) error {
+
+	tx, err := sqlDb.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if dialect == PostgreSQL {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(4400)"); err != nil {
+			return err
+		}
+	}
+
+	var applied int
+	err = tx.QueryRowContext(ctx, dialect.Rebind("SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), version).Scan(&applied)
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, dialect.Rebind("INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)"), version, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrationVersion reads the numeric prefix of a migration file, e.g. 1 from 0001_initial.sql
+func migrationVersion(name string) (int64, error) {
+	prefix, _, _ := strings.Cut(name, "_")
+	return strconv.ParseInt(strings.TrimSuffix(prefix, ".sql"), 10, 64)
+}
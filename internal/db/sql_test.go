@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+type row struct {
+	Name      string     `db:"name"`
+	Tags      []string   `db:"tags"`
+	DeletedAt *time.Time `db:"deleted_at"`
+	Ignored   string
+}
+
+func TestSqlSuite(t *testing.T) {
+	t.Log("Test SQL Suite")
+
+	t.Run("Test Rebind", func(t *testing.T) {
+		t.Log("Testing Rebind")
+
+		query := "SELECT a FROM b WHERE c = ? AND d = ?"
+		if rebound := PostgreSQL.Rebind(query); rebound != "SELECT a FROM b WHERE c = $1 AND d = $2" {
+			t.Errorf("Expected numbered placeholders, got %s", rebound)
+		}
+		if rebound := SQLite.Rebind(query); rebound != query {
+			t.Errorf("Expected the query as is, got %s", rebound)
+		}
+	})
+
+	t.Run("Test Columns", func(t *testing.T) {
+		t.Log("Testing Columns")
+
+		columns := Columns(row{})
+		if len(columns) != 3 || columns[0] != "name" || columns[2] != "deleted_at" {
+			t.Fatalf("Expected the tagged columns, got %v", columns)
+		}
+
+		values, err := Values(row{Name: "n", Tags: []string{"a"}}, columns...)
+		if err != nil {
+			t.Fatalf("Error on Values: %s", err)
+		}
+		if values[0] != "n" || values[1] != `["a"]` || values[2] != nil {
+			t.Errorf("Expected the column values, got %#v", values)
+		}
+		if _, err := Values(row{}, "missing"); !IsUnknownColumn(err) {
+			t.Errorf("Expected unknown column, got %v", err)
+		}
+
+		scanned := row{}
+		targets, err := Targets(&scanned, columns...)
+		if err != nil {
+			t.Fatalf("Error on Targets: %s", err)
+		}
+		if err := targets[1].(sql.Scanner).Scan(`["b","c"]`); err != nil || len(scanned.Tags) != 2 {
+			t.Errorf("Expected the JSON column to be decoded, got %v: %v", scanned.Tags, err)
+		}
+	})
+
+	t.Run("Test Migrate and Health", func(t *testing.T) {
+		t.Log("Testing Migrate and Health")
+
+		dbOptions := &options.DbOptions{
+			Type:         options.RepositoryTypeSQLite,
+			SqlDbOptions: options.SqlDbOptions{Dsn: filepath.Join(t.TempDir(), "migrated.db")},
+		}
+		dbClient, err := NewClient(dbOptions)
+		if err != nil {
+			t.Fatalf("Failed to create the client: %s", err)
+		}
+		sqlDb := dbClient.(*sql.DB)
+		defer sqlDb.Close()
+
+		if err := Migrate(context.Background(), sqlDb, SQLite); err != nil {
+			t.Fatalf("Expected migrations to be applied once, got %s", err)
+		}
+		var applied int
+		if err := sqlDb.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&applied); err != nil || applied != 1 {
+			t.Errorf("Expected 1 applied migration, got %d: %v", applied, err)
+		}
+
+		ctx := InjectDb(InjectDbOptions(context.Background(), dbOptions), sqlDb)
+		if label, status := CreateHealthCheck(dbOptions)(ctx); label != "SQLite" || status != app_http.Active {
+			t.Errorf("Expected an active SQLite, got %s %v", label, status)
+		}
+
+		sqlDb.Close()
+		if _, status := CreateHealthCheck(dbOptions)(ctx); status != app_http.Inactive {
+			t.Errorf("Expected a closed database to be inactive, got %v", status)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/morphy76/g-fe-server/internal/entity/repository"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+type ContextualizedApiHandler[T any, ID comparable] func(zerolog.Logger, entity.Repository[T, ID]) http.HandlerFunc
+
+type RepositoryFactory[T any, ID comparable] func(requestContext context.Context) (entity.Repository[T, ID], error)
+
+// ContextualizedApi builds the repository of the kind for each request, with newRepository or,
+// when nil, with the generic repositories
+func ContextualizedApi[T any, ID comparable](
+	kind entity.Kind[T, ID],
+	newRepository RepositoryFactory[T, ID],
+	apiHandler ContextualizedApiHandler[T, ID],
+) http.HandlerFunc {
+	if newRepository == nil {
+		newRepository = func(requestContext context.Context) (entity.Repository[T, ID], error) {
+			return repository.NewRepository(requestContext, kind)
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog := app_http.ExtractLogger(r.Context(), kind.Name)
+		entityRepository, err := newRepository(r.Context())
+		if err != nil {
+			useLog.Error().
+				Err(err).
+				Msg("Failed to create repository")
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to create repository")
+			return
+		}
+
+		apiHandler(useLog, entityRepository)(w, r)
+	}
+}
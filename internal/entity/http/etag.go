@@ -0,0 +1,111 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+const (
+	HEADER_ETAG          = "ETag"
+	HEADER_IF_MATCH      = "If-Match"
+	HEADER_IF_NONE_MATCH = "If-None-Match"
+)
+
+type entityTag struct {
+	version int64
+	weak    bool
+}
+
+func FormatETag(version int64) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// parseETags reads a conditional header, tags which are not versions are ignored since they can never match
+func parseETags(header string) ([]entityTag, bool) {
+
+	rv := make([]entityTag, 0)
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "*" {
+			return nil, true
+		}
+
+		weak := strings.HasPrefix(part, "W/")
+		part = strings.TrimPrefix(part, "W/")
+		if len(part) < 2 || part[0] != '"' || part[len(part)-1] != '"' {
+			continue
+		}
+		version, err := strconv.ParseInt(part[1:len(part)-1], 10, 64)
+		if err != nil {
+			continue
+		}
+		rv = append(rv, entityTag{version: version, weak: weak})
+	}
+
+	return rv, false
+}
+
+// NotModified implements If-None-Match with the weak comparison
+func NotModified(r *http.Request, current int64) bool {
+
+	header := r.Header.Get(HEADER_IF_NONE_MATCH)
+	if header == "" {
+		return false
+	}
+
+	tags, any := parseETags(header)
+	if any {
+		return true
+	}
+	for _, tag := range tags {
+		if tag.version == current {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectedVersion implements If-Match with the strong comparison, returning the version the write is conditional on;
+// 0 means unconditional, ErrVersionConflict means that the precondition can not be satisfied. If-Match is opt-in,
+// writes without it are unconditional and the last one wins
+func ExpectedVersion[T any, ID comparable](r *http.Request, repository entity.Repository[T, ID], kind entity.Kind[T, ID], id ID) (int64, error) {
+
+	header := r.Header.Get(HEADER_IF_MATCH)
+	if header == "" {
+		return 0, nil
+	}
+
+	tags, any := parseETags(header)
+	if any {
+		return 0, nil
+	}
+
+	strong := make([]int64, 0, len(tags))
+	for _, tag := range tags {
+		if !tag.weak {
+			strong = append(strong, tag.version)
+		}
+	}
+
+	switch len(strong) {
+	case 0:
+		return 0, entity.ErrVersionConflict
+	case 1:
+		return strong[0], nil
+	}
+
+	current, err := repository.FindById(r.Context(), id)
+	if err != nil {
+		return 0, err
+	}
+	for _, version := range strong {
+		if version == kind.VersionOf(current) {
+			return version, nil
+		}
+	}
+	return 0, entity.ErrVersionConflict
+}
@@ -0,0 +1,69 @@
+package http
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	impl "github.com/morphy76/g-fe-server/internal/entity/repository/impl"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+func TestETagSuite(t *testing.T) {
+	t.Log("Test ETag Suite")
+
+	repo, _ := impl.NewMemoryRepository(db.NewMemoryDbClient(), widgetKind)
+	repo.Save(context.Background(), widget{Id: "etag", Size: 1})
+
+	t.Run("Test If-None-Match", func(t *testing.T) {
+		t.Log("Testing If-None-Match")
+
+		for header, expected := range map[string]bool{
+			"":             false,
+			`"3"`:          true,
+			`W/"3"`:        true,
+			`"1", "3"`:     true,
+			`"2"`:          false,
+			`*`:            true,
+			`"not-a-tag"`:  false,
+			`unquoted, 3"`: false,
+		} {
+			r := httptest.NewRequest("GET", "/widget/etag", nil)
+			if header != "" {
+				r.Header.Set(HEADER_IF_NONE_MATCH, header)
+			}
+			if NotModified(r, 3) != expected {
+				t.Errorf("Expected %t for If-None-Match %q", expected, header)
+			}
+		}
+	})
+
+	t.Run("Test If-Match", func(t *testing.T) {
+		t.Log("Testing If-Match")
+
+		for header, expected := range map[string]int64{
+			"":         0,
+			"*":        0,
+			`"7"`:      7,
+			`"7", "1"`: 1,
+		} {
+			r := httptest.NewRequest("PUT", "/widget/etag", nil)
+			if header != "" {
+				r.Header.Set(HEADER_IF_MATCH, header)
+			}
+			version, err := ExpectedVersion(r, repo, widgetKind, "etag")
+			if err != nil || version != expected {
+				t.Errorf("Expected version %d for If-Match %q, got %d, %v", expected, header, version, err)
+			}
+		}
+
+		for _, header := range []string{`W/"1"`, `"7", "8"`} {
+			r := httptest.NewRequest("PUT", "/widget/etag", nil)
+			r.Header.Set(HEADER_IF_MATCH, header)
+			if _, err := ExpectedVersion(r, repo, widgetKind, "etag"); !entity.IsVersionConflict(err) {
+				t.Errorf("Expected version conflict for If-Match %q, got %v", header, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,348 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/internal/entity/api"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/validation"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+// Resource exposes the entities of a kind with the generic CRUD handlers
+type Resource[T any, ID comparable] struct {
+	Kind entity.Kind[T, ID]
+	// NewRepository builds the repository of each request, the generic repositories when nil
+	NewRepository api.RepositoryFactory[T, ID]
+	// Validate checks created and replaced entities, validation.Struct when nil
+	Validate func(T) error
+}
+
+// PathParamId is the name of the route variable holding the id, e.g. exampleId
+func (res Resource[T, ID]) PathParamId() string {
+	return res.Kind.Name + "Id"
+}
+
+// CrudHandlers registers list, create, get, replace and delete routes under /<kind name>, the returned
+// router accepts the middlewares of the resource
+func CrudHandlers[T any, ID comparable](functionalRouter *mux.Router, app_context context.Context, resource Resource[T, ID]) *mux.Router {
+
+	serveOptions := app_http.ExtractServeOptions(app_context)
+	ctxRoot := serveOptions.ContextRoot
+
+	var (
+		apiRoot       = fmt.Sprintf("%s/api/%s", ctxRoot, resource.Kind.Name)
+		apiParamId    = fmt.Sprintf("{%s}", resource.PathParamId())
+		apiResourceId = fmt.Sprintf("%s/%s", apiRoot, apiParamId)
+
+		itemRouter = functionalRouter.PathPrefix("/" + resource.Kind.Name).Subrouter()
+	)
+
+	itemRouter.Methods(http.MethodGet).HandlerFunc(resource.OnList()).Path("").Name("GET " + apiRoot)
+	itemRouter.Methods(http.MethodPost).HandlerFunc(resource.OnCreate()).Path("").Name("POST " + apiRoot)
+	itemRouter.Methods(http.MethodGet).HandlerFunc(resource.OnGet()).Path("/" + apiParamId).Name("GET " + apiResourceId)
+	itemRouter.Methods(http.MethodDelete).HandlerFunc(resource.OnDelete()).Path("/" + apiParamId).Name("DELETE " + apiResourceId)
+	itemRouter.Methods(http.MethodPut).HandlerFunc(resource.OnPut()).Path("/" + apiParamId).Name("PUT " + apiResourceId)
+
+	return itemRouter
+}
+
+func (res Resource[T, ID]) OnList() http.HandlerFunc {
+	return api.ContextualizedApi(res.Kind, res.NewRepository, res.onContextualizedList)
+}
+
+func (res Resource[T, ID]) OnCreate() http.HandlerFunc {
+	return api.ContextualizedApi(res.Kind, res.NewRepository, res.onContextualizedCreate)
+}
+
+func (res Resource[T, ID]) OnGet() http.HandlerFunc {
+	return api.ContextualizedApi(res.Kind, res.NewRepository, res.onContextualizedGet)
+}
+
+func (res Resource[T, ID]) OnPut() http.HandlerFunc {
+	return api.ContextualizedApi(res.Kind, res.NewRepository, res.onContextualizedPut)
+}
+
+func (res Resource[T, ID]) OnDelete() http.HandlerFunc {
+	return api.ContextualizedApi(res.Kind, res.NewRepository, res.onContextualizedDelete)
+}
+
+func (res Resource[T, ID]) onContextualizedList(
+	useLog zerolog.Logger,
+	repository entity.Repository[T, ID],
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msgf("Start listing %ss", res.Kind.Name)
+		defer func() {
+			useLog.Info().Msgf("End listing %ss", res.Kind.Name)
+		}()
+
+		items, err := repository.FindAll(r.Context())
+		if err != nil {
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "FindAll failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if len(items) > 0 {
+			json.NewEncoder(w).Encode(items)
+		} else {
+			w.Write([]byte("[]"))
+		}
+	}
+}
+
+func (res Resource[T, ID]) onContextualizedCreate(
+	useLog zerolog.Logger,
+	repository entity.Repository[T, ID],
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msgf("Start creating %s", res.Kind.Name)
+		defer func() {
+			useLog.Info().Msgf("End creating %s", res.Kind.Name)
+		}()
+
+		var e T
+		err := validation.DecodeJSON(r.Body, &e)
+		if err == nil {
+			err = res.validate(e)
+		}
+		if err != nil {
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Create failed")
+			span.RecordError(err)
+
+			useLog.Debug().Msg(err.Error())
+			RespondInvalid(w, r, err)
+			return
+		}
+
+		err = repository.Save(r.Context(), e)
+		if err != nil {
+			if entity.IsAlreadyExists(err) {
+				app_http.RespondProblem(w, r, http.StatusConflict, err.Error())
+				return
+			}
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Create failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	}
+}
+
+func (res Resource[T, ID]) onContextualizedGet(
+	useLog zerolog.Logger,
+	repository entity.Repository[T, ID],
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msgf("Start fetching %s", res.Kind.Name)
+		defer func() {
+			useLog.Info().Msgf("End fetching %s", res.Kind.Name)
+		}()
+
+		id, ok := res.pathId(w, r)
+		if !ok {
+			return
+		}
+
+		e, err := repository.FindById(r.Context(), id)
+		if err != nil {
+			if entity.IsNotFound(err) {
+				app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Get failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if res.Kind.Versioned() {
+			version := res.Kind.Version(e)
+			w.Header().Set(HEADER_ETAG, FormatETag(version))
+			if NotModified(r, version) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(e)
+	}
+}
+
+func (res Resource[T, ID]) onContextualizedDelete(
+	useLog zerolog.Logger,
+	repository entity.Repository[T, ID],
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msgf("Start deleting %s", res.Kind.Name)
+		defer func() {
+			useLog.Info().Msgf("End deleting %s", res.Kind.Name)
+		}()
+
+		id, ok := res.pathId(w, r)
+		if !ok {
+			return
+		}
+
+		version, err := res.expectedVersion(r, repository, id)
+		if err == nil {
+			err = repository.Delete(r.Context(), id, version)
+		}
+		if err != nil {
+			if entity.IsNotFound(err) {
+				app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+			if entity.IsVersionConflict(err) {
+				app_http.RespondProblem(w, r, http.StatusPreconditionFailed, err.Error())
+				return
+			}
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Delete failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (res Resource[T, ID]) onContextualizedPut(
+	useLog zerolog.Logger,
+	repository entity.Repository[T, ID],
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msgf("Start updating %s", res.Kind.Name)
+		defer func() {
+			useLog.Info().Msgf("End updating %s", res.Kind.Name)
+		}()
+
+		id, ok := res.pathId(w, r)
+		if !ok {
+			return
+		}
+
+		var e T
+		err := validation.DecodeJSON(r.Body, &e)
+		if err == nil {
+			e = res.Kind.SetId(e, id)
+			err = res.validate(e)
+		}
+		if err != nil {
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Put failed")
+			span.RecordError(err)
+
+			useLog.Debug().Msg(err.Error())
+			RespondInvalid(w, r, err)
+			return
+		}
+
+		version, err := res.expectedVersion(r, repository, id)
+		if err == nil {
+			e, err = repository.Update(r.Context(), res.Kind.WithVersion(e, version))
+		}
+		if err != nil {
+			if entity.IsNotFound(err) {
+				app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+			if entity.IsVersionConflict(err) {
+				app_http.RespondProblem(w, r, http.StatusPreconditionFailed, err.Error())
+				return
+			}
+
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Put failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if res.Kind.Versioned() {
+			w.Header().Set(HEADER_ETAG, FormatETag(res.Kind.Version(e)))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (res Resource[T, ID]) validate(e T) error {
+	if res.Validate != nil {
+		return res.Validate(e)
+	}
+	return validation.Struct(e)
+}
+
+// expectedVersion ignores If-Match for kinds without a version
+func (res Resource[T, ID]) expectedVersion(r *http.Request, repository entity.Repository[T, ID], id ID) (int64, error) {
+	if !res.Kind.Versioned() {
+		return 0, nil
+	}
+	return ExpectedVersion(r, repository, res.Kind, id)
+}
+
+// pathId reads the id of the route, answering 404 to ids which can not belong to any entity
+func (res Resource[T, ID]) pathId(w http.ResponseWriter, r *http.Request) (ID, bool) {
+	id, err := res.Kind.ParseId(mux.Vars(r)[res.PathParamId()])
+	if err != nil {
+		app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+		return id, false
+	}
+	return id, true
+}
+
+// RespondInvalid answers requests whose body failed decoding or validation
+func RespondInvalid(w http.ResponseWriter, r *http.Request, err error) {
+
+	fieldErrors, ok := validation.IsInvalid(err)
+	if !ok {
+		app_http.RespondProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	problem := app_http.NewProblem(r, http.StatusUnprocessableEntity, "validation failed")
+	problem.Type = app_http.ProblemTypeValidation
+	problem.Errors = fieldErrors
+	app_http.WriteProblem(w, problem)
+}
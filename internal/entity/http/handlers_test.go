@@ -0,0 +1,146 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	impl "github.com/morphy76/g-fe-server/internal/entity/repository/impl"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+type widget struct {
+	Id      string `json:"id" validate:"required"`
+	Size    int    `json:"size" validate:"required,min=1"`
+	Version int64  `json:"version"`
+}
+
+var widgetKind = entity.Kind[widget, string]{
+	Name:         "widget",
+	Collection:   "widgets",
+	IdField:      "id",
+	VersionField: "version",
+	Id:           func(w widget) string { return w.Id },
+	SetId: func(w widget, id string) widget {
+		w.Id = id
+		return w
+	},
+	ParseId:    entity.StringId,
+	Version:    func(w widget) int64 { return w.Version },
+	SetVersion: func(w widget, version int64) widget { w.Version = version; return w },
+}
+
+func TestHandlersSuite(t *testing.T) {
+	t.Log("Test Handlers Suite")
+
+	kind := widgetKind
+	kind.Name = "handled"
+
+	appContext := app_http.InjectServeOptions(context.Background(), &options.ServeOptions{ContextRoot: "/be"})
+	memoryClient := db.NewMemoryDbClient()
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(app_http.InjectLogger(r.Context(), zerolog.Nop())))
+		})
+	})
+	CrudHandlers(router.PathPrefix("/be/api").Subrouter(), appContext, Resource[widget, string]{
+		Kind: kind,
+		NewRepository: func(context.Context) (entity.Repository[widget, string], error) {
+			return impl.NewMemoryRepository(memoryClient, kind)
+		},
+	})
+
+	call := func(method string, path string, body string, headers ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/be/api/handled"+path, strings.NewReader(body))
+		for i := 0; i+1 < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("Test Create", func(t *testing.T) {
+		t.Log("Testing Create")
+
+		if w := call(http.MethodPost, "", `{"id":"w1","size":3}`); w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+		if w := call(http.MethodPost, "", `{"id":"w1","size":3}`); w.Code != http.StatusConflict {
+			t.Fatalf("Expected status %d, got %d", http.StatusConflict, w.Code)
+		}
+		if w := call(http.MethodPost, "", `{"id":"w2","size":0}`); w.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("Expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+		}
+	})
+
+	t.Run("Test Get", func(t *testing.T) {
+		t.Log("Testing Get")
+
+		w := call(http.MethodGet, "/w1", "")
+		if w.Code != http.StatusOK || w.Header().Get(HEADER_ETAG) != `"1"` {
+			t.Fatalf("Expected the widget at version 1, got %d %s", w.Code, w.Header().Get(HEADER_ETAG))
+		}
+		if !strings.Contains(w.Body.String(), `"size":3`) {
+			t.Fatalf("Expected the widget, got %s", w.Body.String())
+		}
+		if w := call(http.MethodGet, "/w1", "", HEADER_IF_NONE_MATCH, `"1"`); w.Code != http.StatusNotModified {
+			t.Fatalf("Expected status %d, got %d", http.StatusNotModified, w.Code)
+		}
+		if w := call(http.MethodGet, "/missing", ""); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("Test Put", func(t *testing.T) {
+		t.Log("Testing Put")
+
+		w := call(http.MethodPut, "/w1", `{"size":5}`, HEADER_IF_MATCH, `"1"`)
+		if w.Code != http.StatusNoContent || w.Header().Get(HEADER_ETAG) != `"2"` {
+			t.Fatalf("Expected the widget at version 2, got %d %s", w.Code, w.Header().Get(HEADER_ETAG))
+		}
+		if w := call(http.MethodPut, "/w1", `{"size":6}`, HEADER_IF_MATCH, `"1"`); w.Code != http.StatusPreconditionFailed {
+			t.Fatalf("Expected status %d, got %d", http.StatusPreconditionFailed, w.Code)
+		}
+		// If-Match is opt-in, unconditional writes win whatever the version
+		w = call(http.MethodPut, "/w1", `{"size":7}`)
+		if w.Code != http.StatusNoContent || w.Header().Get(HEADER_ETAG) != `"3"` {
+			t.Fatalf("Expected the unconditional write at version 3, got %d %s", w.Code, w.Header().Get(HEADER_ETAG))
+		}
+		if w := call(http.MethodPut, "/missing", `{"size":6}`); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+
+	t.Run("Test List", func(t *testing.T) {
+		t.Log("Testing List")
+
+		w := call(http.MethodGet, "", "")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"id":"w1"`) {
+			t.Fatalf("Expected the widgets, got %d %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Test Delete", func(t *testing.T) {
+		t.Log("Testing Delete")
+
+		if w := call(http.MethodDelete, "/w1", "", HEADER_IF_MATCH, `"1"`); w.Code != http.StatusPreconditionFailed {
+			t.Fatalf("Expected status %d, got %d", http.StatusPreconditionFailed, w.Code)
+		}
+		if w := call(http.MethodDelete, "/w1", ""); w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+		if w := call(http.MethodGet, "", ""); w.Body.String() != "[]" {
+			t.Fatalf("Expected no widgets, got %s", w.Body.String())
+		}
+	})
+}
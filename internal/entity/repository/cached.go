@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/morphy76/g-fe-server/internal/cache"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+// cachedRepository reads entities by id through the cache named after the kind, writes invalidate their id
+type cachedRepository[T any, ID comparable] struct {
+	delegate entity.Repository[T, ID]
+	kind     entity.Kind[T, ID]
+	cache    *cache.Cache
+}
+
+func newCachedRepository[T any, ID comparable](delegate entity.Repository[T, ID], kind entity.Kind[T, ID], c *cache.Cache) entity.Repository[T, ID] {
+	return &cachedRepository[T, ID]{
+		delegate: delegate,
+		kind:     kind,
+		cache:    c,
+	}
+}
+
+func (r *cachedRepository[T, ID]) FindAll(ctx context.Context) ([]T, error) {
+	return r.delegate.FindAll(ctx)
+}
+
+func (r *cachedRepository[T, ID]) FindById(ctx context.Context, id ID) (T, error) {
+	return cache.ReadThrough(ctx, r.cache, r.kind.Name, fmt.Sprint(id), func(ctx context.Context) (T, error) {
+		return r.delegate.FindById(ctx, id)
+	})
+}
+
+func (r *cachedRepository[T, ID]) Save(ctx context.Context, e T) error {
+	defer r.cache.Invalidate(ctx, r.kind.Name, fmt.Sprint(r.kind.Id(e)))
+	return r.delegate.Save(ctx, e)
+}
+
+func (r *cachedRepository[T, ID]) Update(ctx context.Context, e T) (T, error) {
+	defer r.cache.Invalidate(ctx, r.kind.Name, fmt.Sprint(r.kind.Id(e)))
+	return r.delegate.Update(ctx, e)
+}
+
+func (r *cachedRepository[T, ID]) Delete(ctx context.Context, id ID, version int64) error {
+	defer r.cache.Invalidate(ctx, r.kind.Name, fmt.Sprint(id))
+	return r.delegate.Delete(ctx, id, version)
+}
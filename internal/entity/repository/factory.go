@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/morphy76/g-fe-server/internal/cache"
+	"github.com/morphy76/g-fe-server/internal/db"
+	impl "github.com/morphy76/g-fe-server/internal/entity/repository/impl"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+// NewRepository builds the repository of the kind on the storage configured in the request context, reading
+// through the cache when there is one
+func NewRepository[T any, ID comparable](requestContext context.Context, kind entity.Kind[T, ID]) (entity.Repository[T, ID], error) {
+
+	rv, err := newStorageRepository(requestContext, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	if c := cache.Extract(requestContext); c != nil {
+		return newCachedRepository(rv, kind, c), nil
+	}
+	return rv, nil
+}
+
+func newStorageRepository[T any, ID comparable](requestContext context.Context, kind entity.Kind[T, ID]) (entity.Repository[T, ID], error) {
+
+	dbOptions := db.ExtractDbOptions(requestContext)
+	dbClient := db.ExtractDb(requestContext)
+
+	switch dbOptions.Type {
+	case options.RepositoryTypeMemoryDB:
+		memoryClient, ok := dbClient.(*db.MemoryDbClient)
+		if !ok {
+			return nil, errors.New("memory client not found in request context")
+		}
+
+		rv, err := impl.NewMemoryRepository(memoryClient, kind)
+		if err != nil {
+			return nil, err
+		}
+
+		return newTracedRepository(rv, kind, "memory"), nil
+	case options.RepositoryTypeMongoDB:
+		if dbClient == nil {
+			return nil, errors.New("MongoDB client not found in request context")
+		}
+
+		mongoClient := dbClient.(*mongo.Client)
+
+		var rv entity.Repository[T, ID] = &impl.MongoRepository[T, ID]{
+			Kind:      kind,
+			DbOptions: dbOptions,
+			Client:    mongoClient,
+		}
+
+		return newTracedRepository(rv, kind, "mongodb"), nil
+	case options.RepositoryTypePostgreSQL, options.RepositoryTypeSQLite:
+		sqlDb, ok := dbClient.(*sql.DB)
+		if !ok {
+			return nil, errors.New("SQL client not found in request context")
+		}
+
+		dialect, err := db.DialectOf(dbOptions.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		var rv entity.Repository[T, ID] = &impl.SqlRepository[T, ID]{
+			Kind:    kind,
+			Db:      sqlDb,
+			Dialect: dialect,
+		}
+
+		return newTracedRepository(rv, kind, dialect.Name), nil
+	default:
+		return nil, entity.ErrUnknownRepositoryType
+	}
+}
@@ -0,0 +1,116 @@
+package entity
+
+import (
+	"context"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+type MemoryRepository[T any, ID comparable] struct {
+	kind  entity.Kind[T, ID]
+	store *db.MemoryTable[ID, T]
+}
+
+// NewMemoryRepository binds the store named after the collection of the kind, repositories are created per request
+// and share it through the client
+func NewMemoryRepository[T any, ID comparable](client *db.MemoryDbClient, kind entity.Kind[T, ID]) (entity.Repository[T, ID], error) {
+
+	store, err := db.MemoryTableOf(client, kind.Collection, kind.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemoryRepository[T, ID]{
+		kind:  kind,
+		store: store,
+	}, nil
+}
+
+func (r *MemoryRepository[T, ID]) FindAll(ctx context.Context) ([]T, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.store.Lock.RLock()
+	defer r.store.Lock.RUnlock()
+
+	values := make([]T, 0, len(r.store.Rows))
+	for _, v := range r.store.Rows {
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+func (r *MemoryRepository[T, ID]) FindById(ctx context.Context, id ID) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	r.store.Lock.RLock()
+	defer r.store.Lock.RUnlock()
+
+	rv, ok := r.store.Rows[id]
+	if !ok {
+		return zero, entity.ErrNotFound
+	}
+	return rv, nil
+}
+
+func (r *MemoryRepository[T, ID]) Save(ctx context.Context, e T) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.Lock.Lock()
+	defer r.store.Lock.Unlock()
+
+	id := r.kind.Id(e)
+	if _, ok := r.store.Rows[id]; ok {
+		return entity.ErrAlreadyExists
+	}
+	r.store.Rows[id] = r.kind.WithVersion(e, 1)
+	return nil
+}
+
+func (r *MemoryRepository[T, ID]) Update(ctx context.Context, e T) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	r.store.Lock.Lock()
+	defer r.store.Lock.Unlock()
+
+	id := r.kind.Id(e)
+	appo, ok := r.store.Rows[id]
+	if !ok {
+		return zero, entity.ErrNotFound
+	}
+	if r.kind.Conflicts(appo, r.kind.VersionOf(e)) {
+		return zero, entity.ErrVersionConflict
+	}
+	e = r.kind.WithVersion(e, r.kind.VersionOf(appo)+1)
+	r.store.Rows[id] = e
+	return e, nil
+}
+
+func (r *MemoryRepository[T, ID]) Delete(ctx context.Context, id ID, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.store.Lock.Lock()
+	defer r.store.Lock.Unlock()
+
+	appo, ok := r.store.Rows[id]
+	if !ok {
+		return entity.ErrNotFound
+	}
+	if r.kind.Conflicts(appo, version) {
+		return entity.ErrVersionConflict
+	}
+	delete(r.store.Rows, id)
+	return nil
+}
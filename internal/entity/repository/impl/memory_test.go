@@ -0,0 +1,169 @@
+package entity
+
+import (
+	"context"
+	"testing"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+type widget struct {
+	Id      string `json:"id" bson:"id"`
+	Size    int    `json:"size" bson:"size"`
+	Version int64  `json:"version" bson:"version"`
+}
+
+func widgetKind(name string, versioned bool) entity.Kind[widget, string] {
+	rv := entity.Kind[widget, string]{
+		Name:         name,
+		Collection:   name + "s",
+		IdField:      "id",
+		VersionField: "version",
+		Id:           func(w widget) string { return w.Id },
+		SetId: func(w widget, id string) widget {
+			w.Id = id
+			return w
+		},
+		ParseId: entity.StringId,
+	}
+	if versioned {
+		rv.Version = func(w widget) int64 { return w.Version }
+		rv.SetVersion = func(w widget, version int64) widget {
+			w.Version = version
+			return w
+		}
+	}
+	return rv
+}
+
+// testRepository runs the scenarios shared by the implementations
+func testRepository(t *testing.T, repo entity.Repository[widget, string], versioned bool) {
+
+	ctx := context.Background()
+
+	t.Run("Test Save", func(t *testing.T) {
+		t.Log("Testing Save")
+
+		if err := repo.Save(ctx, widget{Id: "w1", Size: 1, Version: 7}); err != nil {
+			t.Fatalf("Failed to save: %s", err)
+		}
+		if err := repo.Save(ctx, widget{Id: "w1", Size: 2}); !entity.IsAlreadyExists(err) {
+			t.Fatalf("Expected already exists, got %v", err)
+		}
+
+		found, err := repo.FindById(ctx, "w1")
+		if err != nil {
+			t.Fatalf("Failed to find: %s", err)
+		}
+		if versioned && found.Version != 1 {
+			t.Fatalf("Expected version 1, got %d", found.Version)
+		}
+		if !versioned && found.Version != 7 {
+			t.Fatalf("Expected the version to be left alone, got %d", found.Version)
+		}
+	})
+
+	t.Run("Test Update", func(t *testing.T) {
+		t.Log("Testing Update")
+
+		updated, err := repo.Update(ctx, widget{Id: "w1", Size: 3})
+		if err != nil {
+			t.Fatalf("Failed to update: %s", err)
+		}
+		if updated.Size != 3 {
+			t.Fatalf("Expected the updated widget, got %v", updated)
+		}
+		if _, err := repo.Update(ctx, widget{Id: "missing", Size: 3}); !entity.IsNotFound(err) {
+			t.Fatalf("Expected not found, got %v", err)
+		}
+
+		if !versioned {
+			return
+		}
+		if updated.Version != 2 {
+			t.Fatalf("Expected version 2, got %d", updated.Version)
+		}
+		if _, err := repo.Update(ctx, widget{Id: "w1", Size: 4, Version: 1}); !entity.IsVersionConflict(err) {
+			t.Fatalf("Expected version conflict, got %v", err)
+		}
+		if updated, err = repo.Update(ctx, widget{Id: "w1", Size: 4, Version: 2}); err != nil || updated.Version != 3 {
+			t.Fatalf("Expected version 3, got %v: %v", updated, err)
+		}
+	})
+
+	t.Run("Test FindAll", func(t *testing.T) {
+		t.Log("Testing FindAll")
+
+		repo.Save(ctx, widget{Id: "w2", Size: 1})
+		all, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Fatalf("Failed to find all: %s", err)
+		}
+		if len(all) != 2 {
+			t.Fatalf("Expected 2 widgets, got %d", len(all))
+		}
+	})
+
+	t.Run("Test Delete", func(t *testing.T) {
+		t.Log("Testing Delete")
+
+		if versioned {
+			if err := repo.Delete(ctx, "w1", 1); !entity.IsVersionConflict(err) {
+				t.Fatalf("Expected version conflict, got %v", err)
+			}
+		}
+		if err := repo.Delete(ctx, "w1", 0); err != nil {
+			t.Fatalf("Failed to delete: %s", err)
+		}
+		if err := repo.Delete(ctx, "w1", 0); !entity.IsNotFound(err) {
+			t.Fatalf("Expected not found, got %v", err)
+		}
+		if _, err := repo.FindById(ctx, "w1"); !entity.IsNotFound(err) {
+			t.Fatalf("Expected not found, got %v", err)
+		}
+	})
+}
+
+func TestMemoryRepositorySuite(t *testing.T) {
+	t.Log("Test MemoryRepository Suite")
+
+	t.Run("Test Versioned", func(t *testing.T) {
+		repo, _ := NewMemoryRepository(db.NewMemoryDbClient(), widgetKind("versioned", true))
+		testRepository(t, repo, true)
+	})
+
+	t.Run("Test Unversioned", func(t *testing.T) {
+		repo, _ := NewMemoryRepository(db.NewMemoryDbClient(), widgetKind("unversioned", false))
+		testRepository(t, repo, false)
+	})
+
+	t.Run("Test Shared Store", func(t *testing.T) {
+		t.Log("Testing Shared Store")
+
+		kind := widgetKind("shared", true)
+		client := db.NewMemoryDbClient()
+		saving, _ := NewMemoryRepository(client, kind)
+		saving.Save(context.Background(), widget{Id: "w1", Size: 1})
+
+		finding, _ := NewMemoryRepository(client, kind)
+		if _, err := finding.FindById(context.Background(), "w1"); err != nil {
+			t.Fatalf("Expected repositories of the same client to share the store, got %v", err)
+		}
+		isolated, _ := NewMemoryRepository(db.NewMemoryDbClient(), kind)
+		if _, err := isolated.FindById(context.Background(), "w1"); !entity.IsNotFound(err) {
+			t.Fatalf("Expected repositories of another client to be isolated, got %v", err)
+		}
+	})
+
+	t.Run("Test Store Type", func(t *testing.T) {
+		t.Log("Testing Store Type")
+
+		client := db.NewMemoryDbClient()
+		NewMemoryRepository(client, widgetKind("typed", true))
+		other := entity.Kind[int, string]{Name: "typed", Collection: "typeds", Id: func(int) string { return "" }}
+		if _, err := NewMemoryRepository(client, other); !db.IsMemoryStoreType(err) {
+			t.Fatalf("Expected a store type mismatch, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package entity
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_opts "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+// updateAttempts bounds the read-replace cycles of unconditional updates of versioned kinds
+const updateAttempts = 3
+
+// indexed remembers the collections whose unique id index is in place
+var indexed sync.Map
+
+type MongoRepository[T any, ID comparable] struct {
+	Kind       entity.Kind[T, ID]
+	DbOptions  *options.DbOptions
+	Client     *mongo.Client
+	collection *mongo.Collection
+}
+
+func (r *MongoRepository[T, ID]) FindAll(ctx context.Context) ([]T, error) {
+
+	if err := r.lazyBindCollection(ctx); err != nil {
+		return nil, err
+	}
+
+	cur, err := r.collection.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	rv := make([]T, 0)
+	err = cur.All(ctx, &rv)
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository[T, ID]) FindById(ctx context.Context, id ID) (T, error) {
+
+	var rv T
+	if err := r.lazyBindCollection(ctx); err != nil {
+		return rv, err
+	}
+
+	err := r.collection.FindOne(ctx, r.idFilter(id)).Decode(&rv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return rv, entity.ErrNotFound
+		}
+		return rv, err
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository[T, ID]) Save(ctx context.Context, e T) error {
+
+	if err := r.lazyBindCollection(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.collection.InsertOne(ctx, r.Kind.WithVersion(e, 1))
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return entity.ErrAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *MongoRepository[T, ID]) Update(ctx context.Context, e T) (T, error) {
+
+	var zero T
+	if err := r.lazyBindCollection(ctx); err != nil {
+		return zero, err
+	}
+
+	id := r.Kind.Id(e)
+	if !r.Kind.Versioned() {
+		result, err := r.collection.ReplaceOne(ctx, r.idFilter(id), e)
+		if err != nil {
+			return zero, err
+		}
+		if result.MatchedCount == 0 {
+			return zero, entity.ErrNotFound
+		}
+		return e, nil
+	}
+
+	expected := r.Kind.VersionOf(e)
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		version := expected
+		if version == 0 {
+			current, err := r.FindById(ctx, id)
+			if err != nil {
+				return zero, err
+			}
+			version = r.Kind.VersionOf(current)
+		}
+
+		replacement := r.Kind.WithVersion(e, version+1)
+		result, err := r.collection.ReplaceOne(ctx, r.versionedFilter(id, version), replacement)
+		if err != nil {
+			return zero, err
+		}
+		if result.MatchedCount == 1 {
+			return replacement, nil
+		}
+		if expected != 0 {
+			return zero, r.missOrConflict(ctx, id)
+		}
+	}
+
+	return zero, entity.ErrVersionConflict
+}
+
+func (r *MongoRepository[T, ID]) Delete(ctx context.Context, id ID, version int64) error {
+
+	if err := r.lazyBindCollection(ctx); err != nil {
+		return err
+	}
+
+	filter := r.idFilter(id)
+	if r.Kind.Versioned() && version != 0 {
+		filter = r.versionedFilter(id, version)
+	}
+
+	result, err := r.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return r.missOrConflict(ctx, id)
+	}
+
+	return nil
+}
+
+// missOrConflict explains a conditional write which matched nothing
+func (r *MongoRepository[T, ID]) missOrConflict(ctx context.Context, id ID) error {
+	count, err := r.collection.CountDocuments(ctx, r.idFilter(id), mongo_opts.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return entity.ErrNotFound
+	}
+	return entity.ErrVersionConflict
+}
+
+func (r *MongoRepository[T, ID]) idFilter(id ID) bson.D {
+	return bson.D{{Key: r.Kind.IdField, Value: id}}
+}
+
+func (r *MongoRepository[T, ID]) versionedFilter(id ID, version int64) bson.D {
+	return bson.D{{Key: r.Kind.IdField, Value: id}, {Key: r.Kind.VersionField, Value: version}}
+}
+
+// lazyBindCollection binds the collection of the kind and makes sure that ids are unique
+func (r *MongoRepository[T, ID]) lazyBindCollection(ctx context.Context) error {
+	if r.collection != nil {
+		return nil
+	}
+
+	useUrl, _ := url.Parse(r.DbOptions.Url)
+
+	if useUrl.User == nil {
+		useCredentials := url.UserPassword(r.DbOptions.User, r.DbOptions.Password)
+		useUrl.User = useCredentials
+	}
+
+	collection := r.Client.Database(path.Base(useUrl.Path)).Collection(r.Kind.Collection)
+
+	key := collection.Database().Name() + "." + collection.Name()
+	if _, ok := indexed.Load(key); !ok {
+		_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: r.Kind.IdField, Value: 1}},
+			Options: mongo_opts.Index().SetUnique(true),
+		})
+		if err != nil {
+			return err
+		}
+		indexed.Store(key, true)
+	}
+
+	r.collection = collection
+	return nil
+}
@@ -0,0 +1,88 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+const db_name = "go_db"
+
+func TestMongoRepositorySuite(t *testing.T) {
+	t.Log("Test MongoRepository Suite")
+
+	ctx := context.Background()
+
+	mongoC, err := mongodb.RunContainer(ctx,
+		testcontainers.WithImage("mongo:7"),
+		testcontainers.WithEnv(map[string]string{
+			"MONGO_INITDB_DATABASE":      db_name,
+			"MONGO_INITDB_ROOT_USERNAME": "go_root",
+			"MONGO_INITDB_ROOT_PASSWORD": "go_password",
+		}),
+		testcontainers.CustomizeRequest(testcontainers.GenericContainerRequest{
+			ContainerRequest: testcontainers.ContainerRequest{
+				Files: []testcontainers.ContainerFile{
+					{
+						HostFilePath:      "./test_resources/init.js",
+						ContainerFilePath: "/docker-entrypoint-initdb.d/init.js",
+						FileMode:          0644,
+					},
+				},
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Failed to start container: %s", err)
+	}
+	t.Cleanup(func() {
+		if err := mongoC.Terminate(ctx); err != nil {
+			t.Logf("Could not stop MongoDB: %s", err)
+		}
+	})
+
+	host, err := mongoC.Host(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get host: %s", err)
+	}
+	ports, err := mongoC.Ports(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get ports: %s", err)
+	}
+
+	dbOptions := &options.DbOptions{
+		Type: options.RepositoryTypeMongoDB,
+		MongoDbOptions: options.MongoDbOptions{
+			Url:      fmt.Sprintf("mongodb://%s:%s/%s", host, ports["27017/tcp"][0].HostPort, db_name),
+			User:     "go",
+			Password: "go",
+		},
+	}
+	dbClient, err := db.NewClient(dbOptions)
+	if err != nil {
+		t.Fatalf("Failed to create the client: %s", err)
+	}
+
+	t.Run("Test Versioned", func(t *testing.T) {
+		testRepository(t, &MongoRepository[widget, string]{
+			Kind:      widgetKind("versioned", true),
+			DbOptions: dbOptions,
+			Client:    dbClient.(*mongo.Client),
+		}, true)
+	})
+
+	t.Run("Test Unversioned", func(t *testing.T) {
+		testRepository(t, &MongoRepository[widget, string]{
+			Kind:      widgetKind("unversioned", false),
+			DbOptions: dbOptions,
+			Client:    dbClient.(*mongo.Client),
+		}, false)
+	})
+}
@@ -0,0 +1,181 @@
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+// SQL_TABLE stores the entities of every kind as JSON documents, keyed by kind name and id
+const SQL_TABLE = "entities"
+
+type SqlRepository[T any, ID comparable] struct {
+	Kind    entity.Kind[T, ID]
+	Db      *sql.DB
+	Dialect db.Dialect
+}
+
+func (r *SqlRepository[T, ID]) FindAll(ctx context.Context) ([]T, error) {
+
+	rows, err := r.Db.QueryContext(ctx, r.Dialect.Rebind("SELECT document FROM "+SQL_TABLE+" WHERE kind = ?"), r.Kind.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]T, 0)
+	for rows.Next() {
+		var document string
+		if err := rows.Scan(&document); err != nil {
+			return nil, err
+		}
+		var e T
+		if err := json.Unmarshal([]byte(document), &e); err != nil {
+			return nil, err
+		}
+		rv = append(rv, e)
+	}
+
+	return rv, rows.Err()
+}
+
+func (r *SqlRepository[T, ID]) FindById(ctx context.Context, id ID) (T, error) {
+
+	var rv T
+	var document string
+	err := r.Db.QueryRowContext(ctx, r.Dialect.Rebind("SELECT document FROM "+SQL_TABLE+" WHERE kind = ? AND id = ?"), r.Kind.Name, r.key(id)).Scan(&document)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return rv, entity.ErrNotFound
+		}
+		return rv, err
+	}
+
+	err = json.Unmarshal([]byte(document), &rv)
+	return rv, err
+}
+
+func (r *SqlRepository[T, ID]) Save(ctx context.Context, e T) error {
+
+	e = r.Kind.WithVersion(e, 1)
+	document, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.Db.ExecContext(ctx,
+		r.Dialect.Rebind("INSERT INTO "+SQL_TABLE+" (kind, id, version, document) VALUES (?, ?, ?, ?) ON CONFLICT (kind, id) DO NOTHING"),
+		r.Kind.Name, r.key(r.Kind.Id(e)), r.Kind.VersionOf(e), string(document))
+	if err != nil {
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return entity.ErrAlreadyExists
+	}
+
+	return nil
+}
+
+func (r *SqlRepository[T, ID]) Update(ctx context.Context, e T) (T, error) {
+
+	var zero T
+	id := r.Kind.Id(e)
+	if !r.Kind.Versioned() {
+		matched, err := r.replace(ctx, e, "")
+		if err != nil {
+			return zero, err
+		}
+		if !matched {
+			return zero, entity.ErrNotFound
+		}
+		return e, nil
+	}
+
+	expected := r.Kind.VersionOf(e)
+	for attempt := 0; attempt < updateAttempts; attempt++ {
+		version := expected
+		if version == 0 {
+			current, err := r.FindById(ctx, id)
+			if err != nil {
+				return zero, err
+			}
+			version = r.Kind.VersionOf(current)
+		}
+
+		replacement := r.Kind.WithVersion(e, version+1)
+		matched, err := r.replace(ctx, replacement, " AND version = ?", version)
+		if err != nil {
+			return zero, err
+		}
+		if matched {
+			return replacement, nil
+		}
+		if expected != 0 {
+			return zero, r.missOrConflict(ctx, id)
+		}
+	}
+
+	return zero, entity.ErrVersionConflict
+}
+
+func (r *SqlRepository[T, ID]) Delete(ctx context.Context, id ID, version int64) error {
+
+	statement := "DELETE FROM " + SQL_TABLE + " WHERE kind = ? AND id = ?"
+	args := []any{r.Kind.Name, r.key(id)}
+	if r.Kind.Versioned() && version != 0 {
+		statement += " AND version = ?"
+		args = append(args, version)
+	}
+
+	result, err := r.Db.ExecContext(ctx, r.Dialect.Rebind(statement), args...)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return r.missOrConflict(ctx, id)
+	}
+
+	return nil
+}
+
+// replace writes the document of e, further conditions restrict the row to replace
+func (r *SqlRepository[T, ID]) replace(ctx context.Context, e T, condition string, args ...any) (bool, error) {
+
+	document, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+
+	args = append([]any{r.Kind.VersionOf(e), string(document), r.Kind.Name, r.key(r.Kind.Id(e))}, args...)
+	result, err := r.Db.ExecContext(ctx,
+		r.Dialect.Rebind("UPDATE "+SQL_TABLE+" SET version = ?, document = ? WHERE kind = ? AND id = ?"+condition), args...)
+	if err != nil {
+		return false, err
+	}
+	matched, err := result.RowsAffected()
+	return matched > 0, err
+}
+
+// missOrConflict explains a conditional write which matched nothing
+func (r *SqlRepository[T, ID]) missOrConflict(ctx context.Context, id ID) error {
+	if _, err := r.FindById(ctx, id); err != nil {
+		return err
+	}
+	return entity.ErrVersionConflict
+}
+
+func (r *SqlRepository[T, ID]) key(id ID) string {
+	return fmt.Sprint(id)
+}
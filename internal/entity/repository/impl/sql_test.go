@@ -0,0 +1,34 @@
+package entity
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+func TestSqlRepositorySuite(t *testing.T) {
+	t.Log("Test SqlRepository Suite")
+
+	dbOptions := &options.DbOptions{
+		Type:         options.RepositoryTypeSQLite,
+		SqlDbOptions: options.SqlDbOptions{Dsn: filepath.Join(t.TempDir(), "entities.db")},
+	}
+	dbClient, err := db.NewClient(dbOptions)
+	if err != nil {
+		t.Fatalf("Failed to create the client: %s", err)
+	}
+	t.Cleanup(func() {
+		dbClient.(*sql.DB).Close()
+	})
+
+	t.Run("Test Versioned", func(t *testing.T) {
+		testRepository(t, &SqlRepository[widget, string]{Kind: widgetKind("versioned", true), Db: dbClient.(*sql.DB), Dialect: db.SQLite}, true)
+	})
+
+	t.Run("Test Unversioned", func(t *testing.T) {
+		testRepository(t, &SqlRepository[widget, string]{Kind: widgetKind("unversioned", false), Db: dbClient.(*sql.DB), Dialect: db.SQLite}, false)
+	})
+}
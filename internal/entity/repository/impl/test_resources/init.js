@@ -0,0 +1,6 @@
+db.createUser({
+    user: 'go',
+    pwd: 'go',
+    roles: [ { role: 'readWrite', db: 'go_db' } ],
+    mechanisms: ["SCRAM-SHA-256"]
+});
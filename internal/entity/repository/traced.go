@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+const tracerName = "github.com/morphy76/g-fe-server/internal/entity/repository"
+
+type tracedRepository[T any, ID comparable] struct {
+	delegate entity.Repository[T, ID]
+	kind     entity.Kind[T, ID]
+	system   string
+}
+
+func newTracedRepository[T any, ID comparable](delegate entity.Repository[T, ID], kind entity.Kind[T, ID], system string) entity.Repository[T, ID] {
+	return &tracedRepository[T, ID]{
+		delegate: delegate,
+		kind:     kind,
+		system:   system,
+	}
+}
+
+func (r *tracedRepository[T, ID]) FindAll(ctx context.Context) ([]T, error) {
+	ctx, span := r.start(ctx, "FindAll")
+	rv, err := r.delegate.FindAll(ctx)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository[T, ID]) FindById(ctx context.Context, id ID) (T, error) {
+	ctx, span := r.start(ctx, "FindById")
+	rv, err := r.delegate.FindById(ctx, id)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository[T, ID]) Save(ctx context.Context, e T) error {
+	ctx, span := r.start(ctx, "Save")
+	err := r.delegate.Save(ctx, e)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository[T, ID]) Update(ctx context.Context, e T) (T, error) {
+	ctx, span := r.start(ctx, "Update")
+	rv, err := r.delegate.Update(ctx, e)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository[T, ID]) Delete(ctx context.Context, id ID, version int64) error {
+	ctx, span := r.start(ctx, "Delete")
+	err := r.delegate.Delete(ctx, id, version)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository[T, ID]) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, r.kind.Name+"."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", r.system),
+			attribute.String("db.collection.name", r.kind.Collection),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !isOutcome(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// isOutcome tells the domain answers of the storage apart from its failures
+func isOutcome(err error) bool {
+	switch err {
+	case entity.ErrNotFound,
+		entity.ErrAlreadyExists,
+		entity.ErrVersionConflict:
+		return true
+	default:
+		return false
+	}
+}
@@ -21,7 +21,7 @@ func ContextualizedApi(apiHandler ContextualizedApiHandler) http.HandlerFunc {
 			useLog.Error().
 				Err(err).
 				Msg("Failed to create repository")
-			w.WriteHeader(http.StatusInternalServerError)
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to create repository")
 			return
 		}
 
@@ -0,0 +1,39 @@
+package example
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/morphy76/g-fe-server/internal/messaging"
+	"github.com/morphy76/g-fe-server/internal/options"
+	outbox_model "github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+// NewEventsConsumer consumes the change events of the examples of every tenant from the example topic
+func NewEventsConsumer(broker messaging.Broker, messagingOptions *options.MessagingOptions) *messaging.Consumer {
+	rv := messaging.NewConsumer(broker, messagingOptions.ConsumerGroup)
+	rv.Handle(messagingOptions.ExampleTopic, messaging.ForTenant(onExampleEvent))
+	return rv
+}
+
+// onExampleEvent logs the change events, malformed ones are skipped since retrying them is pointless
+func onExampleEvent(ctx context.Context, tenant string, record messaging.Record) error {
+
+	var message outbox_model.Message
+	if err := json.Unmarshal(record.Value, &message); err != nil {
+		log.Warn().Err(err).Str("topic", record.Topic).Int64("offset", record.Offset).Msg("Malformed example event")
+		return fmt.Errorf("%w: %s", messaging.ErrSkipRecord, err)
+	}
+
+	log.Info().
+		Str("tenant", tenant).
+		Str("id", message.Id).
+		Str("type", message.Type).
+		Str("entity_id", message.EntityId).
+		Int64("version", message.Version).
+		Msg("Example event consumed")
+	return nil
+}
@@ -11,13 +11,20 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 	"go.opentelemetry.io/otel"
 
+	apikey_http "github.com/morphy76/g-fe-server/internal/apikey/http"
+	audit_http "github.com/morphy76/g-fe-server/internal/audit/http"
+	"github.com/morphy76/g-fe-server/internal/cache"
 	"github.com/morphy76/g-fe-server/internal/db"
 	example_http "github.com/morphy76/g-fe-server/internal/example/http"
 	app_http "github.com/morphy76/g-fe-server/internal/http"
 	"github.com/morphy76/g-fe-server/internal/http/handlers/health"
 	"github.com/morphy76/g-fe-server/internal/http/handlers/metrics"
 	"github.com/morphy76/g-fe-server/internal/http/middleware"
+	"github.com/morphy76/g-fe-server/internal/messaging"
+	"github.com/morphy76/g-fe-server/internal/outbox"
 	"github.com/morphy76/g-fe-server/internal/serve"
+	"github.com/morphy76/g-fe-server/internal/webhook"
+	webhook_http "github.com/morphy76/g-fe-server/internal/webhook/http"
 )
 
 func Handler(
@@ -36,6 +43,9 @@ func Handler(
 
 	dbOptions := db.ExtractDbOptions(app_context)
 	dbClient := db.ExtractDb(app_context)
+	repositoryCache := cache.Extract(app_context)
+	outboxOptions := outbox.ExtractOptions(app_context)
+	broker := messaging.Extract(app_context)
 
 	// Parent router
 	parent.Use(otelmux.Middleware(serve.OTEL_EXAMPLE_NAME,
@@ -53,6 +63,12 @@ func Handler(
 			}
 			useRequest = useRequest.WithContext(db.InjectDbOptions(useRequest.Context(), dbOptions))
 			useRequest = useRequest.WithContext(db.InjectDb(useRequest.Context(), dbClient))
+			if repositoryCache != nil {
+				useRequest = useRequest.WithContext(cache.Inject(useRequest.Context(), repositoryCache))
+			}
+			if outboxOptions != nil {
+				useRequest = useRequest.WithContext(outbox.InjectOptions(useRequest.Context(), outboxOptions))
+			}
 
 			next.ServeHTTP(w, useRequest)
 		})
@@ -63,7 +79,14 @@ func Handler(
 	if log.Trace().Enabled() {
 		log.Trace().Msg("Non functional router registered")
 	}
-	health.HealthHandlers(nonFunctionalRouter, app_context, db.CreateHealthCheck(dbOptions))
+	healthChecks := []app_http.HealthCheckFn{db.CreateHealthCheck(dbOptions)}
+	if cacheHealthCheck := cache.CreateHealthCheck(repositoryCache); cacheHealthCheck != nil {
+		healthChecks = append(healthChecks, cacheHealthCheck)
+	}
+	if messagingHealthCheck := messaging.CreateHealthCheck(broker); messagingHealthCheck != nil {
+		healthChecks = append(healthChecks, messagingHealthCheck)
+	}
+	health.HealthHandlers(nonFunctionalRouter, app_context, healthChecks...)
 	if log.Trace().Enabled() {
 		log.Trace().Msg("Health handler registered")
 	}
@@ -85,12 +108,22 @@ func Handler(
 	apiRouter.Use(mux.CORSMethodMiddleware(apiRouter))
 	apiRouter.Use(middleware.JSONResponse)
 	apiRouter.Use(middleware.PrometheusMiddleware)
-	// TODO: bearer oriented auth, inspect and no renew
-	// apiRouter.Use(middleware.BearerAuthenticationRequired)
+	apiRouter.Use(apikey_http.ServiceAuthentication)
 	if log.Trace().Enabled() {
 		log.Trace().Msg("API router registered")
 	}
 
+	// Service accounts
+	apikey_http.ApiKeyHandlers(apiRouter, app_context)
+
+	// Audit trail
+	audit_http.AuditHandlers(apiRouter, app_context)
+
+	// Tenant webhooks
+	if webhook.Enabled(app_context) {
+		webhook_http.WebhookHandlers(apiRouter, app_context)
+	}
+
 	// Domain functions
 	example_http.ExampleHandlers(apiRouter, app_context)
 }
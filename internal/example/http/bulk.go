@@ -0,0 +1,251 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/validation"
+	"github.com/morphy76/g-fe-server/pkg/example"
+)
+
+const (
+	NDJSON_CONTENT_TYPE = "application/x-ndjson"
+
+	queryParamOrdered = "ordered"
+
+	maxBulkBodySize = 16 << 20
+)
+
+var errTooManyItems = fmt.Errorf("a bulk operation accepts at most %d items", example.MaxBulkSize)
+
+type bulkWrite func(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error)
+
+// bulkExample is a decoded item, decodeErr holds the field errors of items which do not fit an example
+type bulkExample struct {
+	example.Example
+	decodeErr error
+}
+
+type bulkItem struct {
+	example.BulkResult
+	Errors validation.Errors `json:"errors,omitempty"`
+}
+
+type bulkReport struct {
+	Ordered   bool       `json:"ordered"`
+	Succeeded int        `json:"succeeded"`
+	Failed    int        `json:"failed"`
+	Results   []bulkItem `json:"results"`
+}
+
+func onContextualizedBulkCreate(
+	useLog zerolog.Logger,
+	repository example.Repository,
+) http.HandlerFunc {
+	return bulkHandler(useLog, "creating", repository.SaveMany, validateExample)
+}
+
+func onContextualizedBulkUpdate(
+	useLog zerolog.Logger,
+	repository example.Repository,
+) http.HandlerFunc {
+	return bulkHandler(useLog, "updating", repository.UpdateMany, validateExample)
+}
+
+func onContextualizedBulkDelete(
+	useLog zerolog.Logger,
+	repository example.Repository,
+) http.HandlerFunc {
+	// deletions only need the name and, optionally, the expected version
+	return bulkHandler(useLog, "deleting", repository.DeleteMany, func(e example.Example, _ ...string) error {
+		return validation.Partial(e, "name")
+	})
+}
+
+func bulkHandler(
+	useLog zerolog.Logger,
+	action string,
+	write bulkWrite,
+	validate example.Validator,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msgf("Start bulk %s examples", action)
+		defer func() {
+			useLog.Info().Msgf("End bulk %s examples", action)
+		}()
+
+		ordered := true
+		if value := r.URL.Query().Get(queryParamOrdered); value != "" {
+			var err error
+			if ordered, err = strconv.ParseBool(value); err != nil {
+				app_http.RespondProblem(w, r, http.StatusBadRequest, "ordered must be a boolean")
+				return
+			}
+		}
+
+		items, err := decodeBulk(r, http.MaxBytesReader(w, r.Body, maxBulkBodySize))
+		if err != nil {
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Bulk decoding failed")
+			span.RecordError(err)
+
+			useLog.Debug().Msg(err.Error())
+			var maxBytesErr *http.MaxBytesError
+			if err == errTooManyItems || errors.As(err, &maxBytesErr) {
+				app_http.RespondProblem(w, r, http.StatusRequestEntityTooLarge, err.Error())
+				return
+			}
+			app_http.RespondProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		report := bulkReport{
+			Ordered: ordered,
+			Results: make([]bulkItem, len(items)),
+		}
+
+		// invalid items never reach the storage, ordered operations stop at the first one
+		var (
+			submitted = make([]example.Example, 0, len(items))
+			positions = make([]int, 0, len(items))
+			names     = make(map[string]struct{}, len(items))
+			stopped   = false
+		)
+		for i, e := range items {
+			report.Results[i].BulkResult = example.BulkResult{Index: i, Name: e.Name, Status: example.BulkSkipped}
+			if stopped {
+				continue
+			}
+
+			if fieldErrors := validateBulkItem(e, names, validate); fieldErrors != nil {
+				report.Results[i].Status = example.BulkInvalid
+				report.Results[i].Errors = fieldErrors
+				stopped = ordered
+				continue
+			}
+			submitted = append(submitted, e.Example)
+			positions = append(positions, i)
+		}
+
+		if len(submitted) > 0 {
+			results, err := write(r.Context(), submitted, ordered)
+			if err != nil {
+				span := trace.SpanFromContext(r.Context())
+				span.SetStatus(codes.Error, "Bulk "+action+" failed")
+				span.RecordError(err)
+
+				useLog.Error().Msg(err.Error())
+				app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+				return
+			}
+			for j, result := range results {
+				result.Index = positions[j]
+				report.Results[positions[j]].BulkResult = result
+			}
+		}
+
+		for _, result := range report.Results {
+			if result.Succeeded() {
+				report.Succeeded++
+			} else {
+				report.Failed++
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+func validateBulkItem(e bulkExample, names map[string]struct{}, validate example.Validator) validation.Errors {
+
+	err := e.decodeErr
+	if err == nil {
+		err = validate(e.Example)
+	}
+	if err != nil {
+		if fieldErrors, ok := validation.IsInvalid(err); ok {
+			return fieldErrors
+		}
+		return validation.Errors{{Rule: "invalid", Message: err.Error()}}
+	}
+
+	// the outcome of an item must not depend on another item of the same batch
+	if _, repeated := names[e.Name]; repeated {
+		return validation.Errors{{
+			Field:   "name",
+			Rule:    "unique",
+			Message: "is repeated in the batch",
+		}}
+	}
+	names[e.Name] = struct{}{}
+
+	return nil
+}
+
+// decodeBulk reads the items of a JSON array or of a NDJSON stream, items which are not objects fail the request
+func decodeBulk(r *http.Request, body io.Reader) ([]bulkExample, error) {
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var raws []json.RawMessage
+	if mediaType == NDJSON_CONTENT_TYPE {
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxPatchSize)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			if len(raws) == example.MaxBulkSize {
+				return nil, errTooManyItems
+			}
+			raws = append(raws, json.RawMessage(bytes.Clone(line)))
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else {
+		decoder := json.NewDecoder(body)
+		if err := decoder.Decode(&raws); err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, err
+			}
+			return nil, validation.ErrMalformedBody
+		}
+		if _, err := decoder.Token(); err != io.EOF {
+			return nil, validation.ErrMalformedBody
+		}
+		if len(raws) > example.MaxBulkSize {
+			return nil, errTooManyItems
+		}
+	}
+
+	rv := make([]bulkExample, len(raws))
+	for i, raw := range raws {
+		err := validation.DecodeJSON(bytes.NewReader(raw), &rv[i].Example)
+		if err != nil {
+			if validation.IsMalformedBody(err) {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			rv[i].decodeErr = err
+		}
+	}
+	return rv, nil
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/internal/example/repository"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+const (
+	queryParamId = "id"
+
+	eventsRetry     = 3 * time.Second
+	eventsHeartbeat = 15 * time.Second
+)
+
+// onContextualizedEvents streams the changes of the examples of the tenant, as recorded by the audit trail;
+// the event name is example.<operation> and the data is the audit entry
+func onContextualizedEvents(
+	useLog zerolog.Logger,
+	auditRepository audit.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start streaming example events")
+		defer func() {
+			useLog.Info().Msg("End streaming example events")
+		}()
+
+		ownership := app_http.ExtractOwnership(r.Context())
+		entries, err := auditRepository.Watch(r.Context(), audit.Query{
+			Tenant:   ownership.Tenant,
+			Entity:   repository.AUDIT_ENTITY,
+			EntityId: r.URL.Query().Get(queryParamId),
+		})
+		if err != nil {
+			span := trace.SpanFromContext(r.Context())
+			span.SetStatus(codes.Error, "Watch failed")
+			span.RecordError(err)
+
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+
+		stream, err := app_http.NewEventStream(w, eventsRetry)
+		if err != nil {
+			useLog.Debug().Msg(err.Error())
+			return
+		}
+
+		heartbeat := time.NewTicker(eventsHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				err = stream.Comment("heartbeat")
+			case entry, ok := <-entries:
+				if !ok {
+					// the client reconnects after the retry delay
+					return
+				}
+				err = stream.Send(entry.Id, entry.Entity+"."+string(entry.Operation), entry)
+			}
+			if err != nil {
+				useLog.Debug().Msg(err.Error())
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	audit_repository "github.com/morphy76/g-fe-server/internal/audit/repository/impl"
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/example/repository"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/http/middleware"
+	"github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+func TestEventsSuite(t *testing.T) {
+	t.Log("Test Events Suite")
+
+	auditRepository, _ := audit_repository.NewMemoryRepository(db.NewMemoryDbClient())
+	handler := middleware.TenantResolver(middleware.RequestLogger(onContextualizedEvents(zerolog.Nop(), auditRepository)))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("Test Stream", func(t *testing.T) {
+		t.Log("Testing Events Stream")
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?id=streamed", nil)
+		request.Header.Set("X-Tenant", "t1")
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("Failed to open the stream: %s", err)
+		}
+		defer response.Body.Close()
+
+		if contentType := response.Header.Get("Content-Type"); contentType != app_http.EVENT_STREAM_CONTENT_TYPE {
+			t.Fatalf("Expected content type %s, got %s", app_http.EVENT_STREAM_CONTENT_TYPE, contentType)
+		}
+
+		reader := bufio.NewReader(response.Body)
+		if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, "retry: ") {
+			t.Fatalf("Expected the retry hint first, got %q", line)
+		}
+		reader.ReadString('\n')
+
+		for _, entry := range []audit.Entry{
+			{Id: "other-tenant", Tenant: "t2", Entity: repository.AUDIT_ENTITY, EntityId: "streamed", Operation: audit.OpCreate},
+			{Id: "other-example", Tenant: "t1", Entity: repository.AUDIT_ENTITY, EntityId: "other", Operation: audit.OpCreate},
+			{Id: "expected", Tenant: "t1", Entity: repository.AUDIT_ENTITY, EntityId: "streamed", Operation: audit.OpUpdate},
+		} {
+			if err := auditRepository.Append(context.Background(), entry); err != nil {
+				t.Fatalf("Error on Append: %s", err)
+			}
+		}
+
+		frame := make([]string, 0, 3)
+		for len(frame) < 3 {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Failed to read the event: %s", err)
+			}
+			frame = append(frame, strings.TrimSpace(line))
+		}
+
+		if frame[0] != "id: expected" || frame[1] != "event: example.update" || !strings.HasPrefix(frame[2], "data: {") {
+			t.Errorf("Unexpected event %q", frame)
+		}
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 
 	"github.com/gorilla/mux"
@@ -11,14 +13,28 @@ import (
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 
+	apikey_http "github.com/morphy76/g-fe-server/internal/apikey/http"
+	audit_api "github.com/morphy76/g-fe-server/internal/audit/api"
+	entity_http "github.com/morphy76/g-fe-server/internal/entity/http"
 	"github.com/morphy76/g-fe-server/internal/example/api"
+	"github.com/morphy76/g-fe-server/internal/example/repository"
 	app_http "github.com/morphy76/g-fe-server/internal/http"
 	"github.com/morphy76/g-fe-server/internal/http/middleware"
+	"github.com/morphy76/g-fe-server/internal/validation"
+	"github.com/morphy76/g-fe-server/pkg/entity"
 	"github.com/morphy76/g-fe-server/pkg/example"
 )
 
 const (
 	pathParamExampleId = "exampleId"
+	pathBulk           = "/_bulk"
+	pathRestore        = "/restore"
+	pathEvents         = "/_events"
+
+	maxPatchSize = 1 << 20
+
+	SCOPE_READ  = "example:read"
+	SCOPE_WRITE = "example:write"
 )
 
 func ExampleHandlers(functionalRouter *mux.Router, app_context context.Context) {
@@ -30,23 +46,47 @@ func ExampleHandlers(functionalRouter *mux.Router, app_context context.Context)
 		apiRoot              = fmt.Sprintf("%s/api/example", ctxRoot)
 		apiParamExampleId    = fmt.Sprintf("{%s}", pathParamExampleId)
 		apiResourceExampleId = fmt.Sprintf("%s/%s", apiRoot, apiParamExampleId)
+		apiBulk              = fmt.Sprintf("%s%s", apiRoot, pathBulk)
+		apiEvents            = fmt.Sprintf("%s%s", apiRoot, pathEvents)
 
 		itemRouter = functionalRouter.PathPrefix("/example").Subrouter()
 	)
 	itemRouter.Use(middleware.DumpHeaders)
+	itemRouter.Use(apikey_http.RequireMethodScopes(SCOPE_READ, SCOPE_WRITE))
 
 	itemRouter.Methods(http.MethodGet).HandlerFunc(onList).Path("").Name("GET " + apiRoot)
-	itemRouter.Methods(http.MethodPost).HandlerFunc(onCreate).Name("POST " + apiRoot)
+	itemRouter.Methods(http.MethodPost).HandlerFunc(onCreate).Path("").Name("POST " + apiRoot)
+	// the bulk and events routes come first, example names can not start with an underscore
+	itemRouter.Methods(http.MethodGet).HandlerFunc(onEvents).Path(pathEvents).Name("GET " + apiEvents)
+	itemRouter.Methods(http.MethodPost).HandlerFunc(onBulkCreate).Path(pathBulk).Name("POST " + apiBulk)
+	itemRouter.Methods(http.MethodPut).HandlerFunc(onBulkUpdate).Path(pathBulk).Name("PUT " + apiBulk)
+	itemRouter.Methods(http.MethodDelete).HandlerFunc(onBulkDelete).Path(pathBulk).Name("DELETE " + apiBulk)
 	itemRouter.Methods(http.MethodGet).HandlerFunc(onGet).Path("/" + apiParamExampleId).Name("GET " + apiResourceExampleId)
 	itemRouter.Methods(http.MethodDelete).HandlerFunc(onDelete).Path("/" + apiParamExampleId).Name("DELETE " + apiResourceExampleId)
 	itemRouter.Methods(http.MethodPut).HandlerFunc(onPut).Path("/" + apiParamExampleId).Name("PUT " + apiResourceExampleId)
+	itemRouter.Methods(http.MethodPatch).HandlerFunc(onPatch).Path("/" + apiParamExampleId).Name("PATCH " + apiResourceExampleId)
+	itemRouter.Methods(http.MethodPost).HandlerFunc(onRestore).Path("/" + apiParamExampleId + pathRestore).Name("POST " + apiResourceExampleId + pathRestore)
+}
+
+// resource serves the plain CRUD operations, the example repository is a generic one as well
+var resource = entity_http.Resource[example.Example, string]{
+	Kind: example.Kind,
+	NewRepository: func(requestContext context.Context) (entity.Repository[example.Example, string], error) {
+		return repository.NewRepository(requestContext)
+	},
 }
 
 var onList = api.ContextualizedApi(onContextualizedList)
-var onCreate = api.ContextualizedApi(onContextualizedCreate)
-var onGet = api.ContextualizedApi(onContextualizedGet)
-var onDelete = api.ContextualizedApi(onContextualizedDelete)
-var onPut = api.ContextualizedApi(onContextualizedPut)
+var onCreate = resource.OnCreate()
+var onGet = resource.OnGet()
+var onDelete = resource.OnDelete()
+var onPut = resource.OnPut()
+var onPatch = api.ContextualizedApi(onContextualizedPatch)
+var onRestore = api.ContextualizedApi(onContextualizedRestore)
+var onEvents = audit_api.ContextualizedApi(onContextualizedEvents)
+var onBulkCreate = api.ContextualizedApi(onContextualizedBulkCreate)
+var onBulkUpdate = api.ContextualizedApi(onContextualizedBulkUpdate)
+var onBulkDelete = api.ContextualizedApi(onContextualizedBulkDelete)
 
 func onContextualizedList(
 	useLog zerolog.Logger,
@@ -59,185 +99,161 @@ func onContextualizedList(
 			useLog.Info().Msg("End listing examples")
 		}()
 
-		examples, err := repository.FindAll()
+		query, err := parseListQuery(r)
 		if err != nil {
-
-			span := trace.SpanFromContext(r.Context())
-			span.SetStatus(codes.Error, "FindAll failed")
-			span.RecordError(err)
-
-			useLog.Error().Msg(err.Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			useLog.Debug().Err(err).Str("query", r.URL.RawQuery).Msg("Invalid list query")
+			app_http.RespondProblem(w, r, http.StatusBadRequest, err.Error())
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		if len(examples) > 0 {
-			json.NewEncoder(w).Encode(examples)
-		} else {
-			w.Write([]byte("[]"))
-		}
-	}
-}
-
-func onContextualizedCreate(
-	useLog zerolog.Logger,
-	repository example.Repository,
-) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-
-		useLog.Trace().Msg("Start creating example")
-		defer func() {
-			useLog.Info().Msg("End creating example")
-		}()
-
-		var e example.Example
-		err := json.NewDecoder(r.Body).Decode(&e)
-		if err != nil {
-
-			span := trace.SpanFromContext(r.Context())
-			span.SetStatus(codes.Error, "Create failed")
-			span.RecordError(err)
-
-			useLog.Error().Msg(err.Error())
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		err = repository.Save(e)
+		page, err := repository.Find(r.Context(), query)
 		if err != nil {
-			if example.IsAlreadyExists(err) {
-				http.Error(w, err.Error(), http.StatusConflict)
+			if example.IsInvalidQuery(err) {
+				app_http.RespondProblem(w, r, http.StatusBadRequest, err.Error())
 				return
 			}
 
 			span := trace.SpanFromContext(r.Context())
-			span.SetStatus(codes.Error, "Create failed")
+			span.SetStatus(codes.Error, "Find failed")
 			span.RecordError(err)
 
 			useLog.Error().Msg(err.Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		w.WriteHeader(http.StatusCreated)
+		writePaginationHeaders(w, r, query, page)
+		w.WriteHeader(http.StatusOK)
+		if len(page.Items) > 0 {
+			json.NewEncoder(w).Encode(page.Items)
+		} else {
+			w.Write([]byte("[]"))
+		}
 	}
 }
 
-func onContextualizedGet(
+func onContextualizedRestore(
 	useLog zerolog.Logger,
 	repository example.Repository,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		useLog.Trace().Msg("Start fetching example")
+		useLog.Trace().Msg("Start restoring example")
 		defer func() {
-			useLog.Info().Msg("End fetching example")
+			useLog.Info().Msg("End restoring example")
 		}()
 
 		vars := mux.Vars(r)
 		exampleId := vars[pathParamExampleId]
 
-		ex, err := repository.FindById(exampleId)
+		version, err := entity_http.ExpectedVersion(r, repository, example.Kind, exampleId)
+		var ex example.Example
+		if err == nil {
+			ex, err = repository.Restore(r.Context(), exampleId, version)
+		}
 		if err != nil {
 			if example.IsNotFound(err) {
-				http.Error(w, err.Error(), http.StatusNotFound)
+				app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+			if example.IsVersionConflict(err) {
+				app_http.RespondProblem(w, r, http.StatusPreconditionFailed, err.Error())
 				return
 			}
 
 			span := trace.SpanFromContext(r.Context())
-			span.SetStatus(codes.Error, "Get failed")
+			span.SetStatus(codes.Error, "Restore failed")
 			span.RecordError(err)
 
 			useLog.Error().Msg(err.Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
 			return
 		}
 
+		w.Header().Set(entity_http.HEADER_ETAG, entity_http.FormatETag(ex.Version))
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(ex)
 	}
 }
 
-func onContextualizedDelete(
+func onContextualizedPatch(
 	useLog zerolog.Logger,
 	repository example.Repository,
 ) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		useLog.Trace().Msg("Start deleting example")
+		useLog.Trace().Msg("Start patching example")
 		defer func() {
-			useLog.Info().Msg("End deleting example")
+			useLog.Info().Msg("End patching example")
 		}()
 
 		vars := mux.Vars(r)
 		exampleId := vars[pathParamExampleId]
 
-		err := repository.Delete(exampleId)
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		document, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
 		if err != nil {
-			if example.IsNotFound(err) {
-				http.Error(w, err.Error(), http.StatusNotFound)
-				return
-			}
-
-			span := trace.SpanFromContext(r.Context())
-			span.SetStatus(codes.Error, "Delete failed")
-			span.RecordError(err)
-
-			useLog.Error().Msg(err.Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			app_http.RespondProblem(w, r, http.StatusRequestEntityTooLarge, err.Error())
 			return
 		}
 
-		w.WriteHeader(http.StatusNoContent)
-	}
-}
-
-func onContextualizedPut(
-	useLog zerolog.Logger,
-	repository example.Repository,
-) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-
-		useLog.Trace().Msg("Start updating example")
-		defer func() {
-			useLog.Info().Msg("End updating example")
-		}()
-
-		vars := mux.Vars(r)
-		exampleId := vars[pathParamExampleId]
-
-		var ex example.Example
-		err := json.NewDecoder(r.Body).Decode(&ex)
+		patch, err := example.NewPatch(mediaType, document)
 		if err != nil {
-
-			span := trace.SpanFromContext(r.Context())
-			span.SetStatus(codes.Error, "Put failed")
-			span.RecordError(err)
-
-			useLog.Error().Msg(err.Error())
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			if example.IsUnsupportedPatch(err) {
+				w.Header().Set("Accept-Patch", example.MergePatchMediaType+", "+example.JSONPatchMediaType)
+				app_http.RespondProblem(w, r, http.StatusUnsupportedMediaType, err.Error())
+				return
+			}
+			app_http.RespondProblem(w, r, http.StatusBadRequest, err.Error())
 			return
 		}
+		patch.Validator = validateExample
 
-		ex.Name = exampleId
-		err = repository.Update(ex)
+		version, err := entity_http.ExpectedVersion(r, repository, example.Kind, exampleId)
+		var ex example.Example
+		if err == nil {
+			ex, err = repository.Patch(r.Context(), exampleId, patch, version)
+		}
 		if err != nil {
-			if example.IsNotFound(err) {
-				http.Error(w, err.Error(), http.StatusNotFound)
+			switch {
+			case example.IsNotFound(err):
+				app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+				return
+			case example.IsVersionConflict(err):
+				app_http.RespondProblem(w, r, http.StatusPreconditionFailed, err.Error())
+				return
+			case example.IsPatchTestFailed(err):
+				app_http.RespondProblem(w, r, http.StatusConflict, err.Error())
+				return
+			case example.IsInvalidPatch(err):
+				app_http.RespondProblem(w, r, http.StatusUnprocessableEntity, err.Error())
+				return
+			}
+			if _, invalid := validation.IsInvalid(err); invalid {
+				entity_http.RespondInvalid(w, r, err)
 				return
 			}
 
 			span := trace.SpanFromContext(r.Context())
-			span.SetStatus(codes.Error, "Put failed")
+			span.SetStatus(codes.Error, "Patch failed")
 			span.RecordError(err)
 
 			useLog.Error().Msg(err.Error())
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		w.WriteHeader(http.StatusNoContent)
+		w.Header().Set(entity_http.HEADER_ETAG, entity_http.FormatETag(ex.Version))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(ex)
+	}
+}
+
+func validateExample(e example.Example, fields ...string) error {
+	if len(fields) == 0 {
+		return validation.Struct(e)
 	}
+	return validation.Partial(e, fields...)
 }
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/morphy76/g-fe-server/pkg/example"
+)
+
+const (
+	queryParamLimit   = "limit"
+	queryParamOffset  = "offset"
+	queryParamCursor  = "cursor"
+	queryParamSort    = "sort"
+	queryParamFilter  = "filter"
+	queryParamDeleted = "deleted"
+
+	HEADER_TOTAL_COUNT = "X-Total-Count"
+	HEADER_NEXT_CURSOR = "X-Next-Cursor"
+)
+
+func parseListQuery(r *http.Request) (example.Query, error) {
+
+	params := r.URL.Query()
+	rv := example.Query{}
+
+	if limit := params.Get(queryParamLimit); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return rv, example.ErrInvalidQuery
+		}
+		rv.Limit = value
+	}
+
+	if offset := params.Get(queryParamOffset); offset != "" {
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 {
+			return rv, example.ErrInvalidQuery
+		}
+		rv.Offset = value
+	}
+
+	rv.Cursor = params.Get(queryParamCursor)
+
+	if sort := params.Get(queryParamSort); sort != "" {
+		sortFields, err := example.ParseSort(sort)
+		if err != nil {
+			return rv, err
+		}
+		rv.Sort = sortFields
+	}
+
+	if deleted := params.Get(queryParamDeleted); deleted != "" {
+		value, err := strconv.ParseBool(deleted)
+		if err != nil {
+			return rv, example.ErrInvalidQuery
+		}
+		rv.Deleted = value
+	}
+
+	for _, expr := range params[queryParamFilter] {
+		filter, err := example.ParseFilter(expr)
+		if err != nil {
+			return rv, err
+		}
+		rv.Filters = append(rv.Filters, filter)
+	}
+
+	return rv, nil
+}
+
+func writePaginationHeaders(w http.ResponseWriter, r *http.Request, query example.Query, page example.Page) {
+
+	w.Header().Set(HEADER_TOTAL_COUNT, strconv.FormatInt(page.Total, 10))
+	if page.NextCursor != "" {
+		w.Header().Set(HEADER_NEXT_CURSOR, page.NextCursor)
+	}
+
+	limit := query.EffectiveLimit()
+	links := []string{pageLink(r, "first", map[string]string{queryParamOffset: "", queryParamCursor: ""})}
+
+	if query.Cursor == "" && r.URL.Query().Has(queryParamOffset) {
+		if int64(query.Offset+limit) < page.Total {
+			links = append(links, pageLink(r, "next", map[string]string{queryParamOffset: strconv.Itoa(query.Offset + limit)}))
+		}
+		if query.Offset > 0 {
+			links = append(links, pageLink(r, "prev", map[string]string{queryParamOffset: strconv.Itoa(max(query.Offset-limit, 0))}))
+		}
+	} else if page.NextCursor != "" {
+		links = append(links, pageLink(r, "next", map[string]string{queryParamOffset: "", queryParamCursor: page.NextCursor}))
+	}
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+// pageLink rewrites the request query with the given overrides, empty values remove the parameter;
+// the link is a query-only reference so that it resolves against the URL the client used, e.g. through the gateway proxy
+func pageLink(r *http.Request, rel string, overrides map[string]string) string {
+
+	params := r.URL.Query()
+	for key, value := range overrides {
+		if value == "" {
+			params.Del(key)
+		} else {
+			params.Set(key, value)
+		}
+	}
+
+	return fmt.Sprintf("<?%s>; rel=\"%s\"", params.Encode(), rel)
+}
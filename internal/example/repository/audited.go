@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/internal/audit"
+	audit_model "github.com/morphy76/g-fe-server/pkg/audit"
+	model "github.com/morphy76/g-fe-server/pkg/example"
+)
+
+const AUDIT_ENTITY = "example"
+
+// auditedRepository appends an audit entry for every successful write; a failure of the audit log
+// is reported but does not fail the write, which already happened
+type auditedRepository struct {
+	delegate model.Repository
+	audit    audit_model.Repository
+}
+
+func newAuditedRepository(delegate model.Repository, audit audit_model.Repository) model.Repository {
+	return &auditedRepository{
+		delegate: delegate,
+		audit:    audit,
+	}
+}
+
+func (r *auditedRepository) FindAll(ctx context.Context) ([]model.Example, error) {
+	return r.delegate.FindAll(ctx)
+}
+
+func (r *auditedRepository) Find(ctx context.Context, query model.Query) (model.Page, error) {
+	return r.delegate.Find(ctx, query)
+}
+
+func (r *auditedRepository) FindById(ctx context.Context, id string) (model.Example, error) {
+	return r.delegate.FindById(ctx, id)
+}
+
+func (r *auditedRepository) Save(ctx context.Context, e model.Example) error {
+	err := r.delegate.Save(ctx, e)
+	if err == nil {
+		e.Version = 1
+		r.record(ctx, e.Name, audit_model.OpCreate, nil, &e)
+	}
+	return err
+}
+
+func (r *auditedRepository) Update(ctx context.Context, e model.Example) (model.Example, error) {
+	before := r.live(ctx, e.Name)
+	rv, err := r.delegate.Update(ctx, e)
+	if err == nil {
+		r.record(ctx, e.Name, audit_model.OpUpdate, before, &rv)
+	}
+	return rv, err
+}
+
+func (r *auditedRepository) Delete(ctx context.Context, id string, version int64) error {
+	before := r.live(ctx, id)
+	err := r.delegate.Delete(ctx, id, version)
+	if err == nil {
+		r.record(ctx, id, audit_model.OpDelete, before, r.deleted(ctx, id))
+	}
+	return err
+}
+
+func (r *auditedRepository) Restore(ctx context.Context, id string, version int64) (model.Example, error) {
+	before := r.deleted(ctx, id)
+	rv, err := r.delegate.Restore(ctx, id, version)
+	if err == nil {
+		r.record(ctx, id, audit_model.OpRestore, before, &rv)
+	}
+	return rv, err
+}
+
+func (r *auditedRepository) Patch(ctx context.Context, id string, patch model.Patch, version int64) (model.Example, error) {
+	before := r.live(ctx, id)
+	rv, err := r.delegate.Patch(ctx, id, patch, version)
+	if err == nil {
+		r.record(ctx, id, audit_model.OpPatch, before, &rv)
+	}
+	return rv, err
+}
+
+func (r *auditedRepository) SaveMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	rv, err := r.delegate.SaveMany(ctx, items, ordered)
+	for _, result := range rv {
+		if result.Succeeded() {
+			after := items[result.Index]
+			after.Version = result.Version
+			r.record(ctx, after.Name, audit_model.OpCreate, nil, &after)
+		}
+	}
+	return rv, err
+}
+
+func (r *auditedRepository) UpdateMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	before := r.liveMany(ctx, items)
+	rv, err := r.delegate.UpdateMany(ctx, items, ordered)
+	for _, result := range rv {
+		if result.Succeeded() {
+			after := items[result.Index]
+			after.Version = result.Version
+			r.record(ctx, after.Name, audit_model.OpUpdate, before[result.Index], &after)
+		}
+	}
+	return rv, err
+}
+
+// DeleteMany records the deletion time of the audit entry, which can differ slightly from the stored one
+func (r *auditedRepository) DeleteMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	before := r.liveMany(ctx, items)
+	rv, err := r.delegate.DeleteMany(ctx, items, ordered)
+	for _, result := range rv {
+		if result.Succeeded() && before[result.Index] != nil {
+			after := *before[result.Index]
+			deletedAt := time.Now().UTC()
+			after.DeletedAt = &deletedAt
+			after.Version = result.Version
+			r.record(ctx, after.Name, audit_model.OpDelete, before[result.Index], &after)
+		}
+	}
+	return rv, err
+}
+
+// live reads the state before a write, nil when there is none
+func (r *auditedRepository) live(ctx context.Context, id string) *model.Example {
+	rv, err := r.delegate.FindById(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return &rv
+}
+
+func (r *auditedRepository) liveMany(ctx context.Context, items []model.Example) []*model.Example {
+	rv := make([]*model.Example, len(items))
+	for i, e := range items {
+		rv[i] = r.live(ctx, e.Name)
+	}
+	return rv
+}
+
+// deleted reads the soft deleted state of the example, nil when there is none
+func (r *auditedRepository) deleted(ctx context.Context, id string) *model.Example {
+	page, err := r.delegate.Find(ctx, model.Query{
+		Limit:   1,
+		Filters: []model.Filter{{Field: "name", Op: model.OpEq, Value: id}},
+		Deleted: true,
+	})
+	if err != nil || len(page.Items) == 0 {
+		return nil
+	}
+	return &page.Items[0]
+}
+
+func (r *auditedRepository) record(ctx context.Context, id string, operation audit_model.Operation, before *model.Example, after *model.Example) {
+
+	entry, err := audit.NewEntry(ctx, AUDIT_ENTITY, id, operation, before, after)
+	if err == nil {
+		err = r.audit.Append(ctx, entry)
+	}
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+		log.Error().
+			Err(err).
+			Str("entity", AUDIT_ENTITY).
+			Str("id", id).
+			Str("operation", string(operation)).
+			Msg("Failed to append the audit entry")
+	}
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	audit "github.com/morphy76/g-fe-server/internal/audit/repository/impl"
+	"github.com/morphy76/g-fe-server/internal/db"
+	example "github.com/morphy76/g-fe-server/internal/example/repository/impl"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	audit_model "github.com/morphy76/g-fe-server/pkg/audit"
+	model "github.com/morphy76/g-fe-server/pkg/example"
+)
+
+func TestAuditedSuite(t *testing.T) {
+	t.Log("Test Audited Suite")
+
+	ctx := app_http.InjectOwnership(context.Background(), serve.Ownership{Tenant: "audited"})
+	ctx = app_http.InjectPrincipal(ctx, app_http.Principal{Kind: app_http.PrincipalApiKey, Subject: "importer"})
+
+	memoryClient := db.NewMemoryDbClient()
+	auditRepository, _ := audit.NewMemoryRepository(memoryClient)
+	exampleRepository, _ := example.NewMemoryRepository(memoryClient)
+	repo := newAuditedRepository(exampleRepository, auditRepository)
+
+	t.Run("Test Audit Trail", func(t *testing.T) {
+		t.Log("Test Audited Audit Trail")
+
+		if err := repo.Save(ctx, model.Example{Name: "Audited", Age: 1}); err != nil {
+			t.Fatalf("Error on Save: %s", err)
+		}
+		if _, err := repo.Update(ctx, model.Example{Name: "Audited", Age: 2}); err != nil {
+			t.Fatalf("Error on Update: %s", err)
+		}
+		if err := repo.Delete(ctx, "Audited", 0); err != nil {
+			t.Fatalf("Error on Delete: %s", err)
+		}
+		if _, err := repo.Restore(ctx, "Audited", 0); err != nil {
+			t.Fatalf("Error on Restore: %s", err)
+		}
+		if err := repo.Save(ctx, model.Example{Name: "Audited", Age: 1}); !model.IsAlreadyExists(err) {
+			t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+		}
+
+		entries, err := auditRepository.Find(ctx, audit_model.Query{Tenant: "audited", EntityId: "Audited"})
+		if err != nil {
+			t.Fatalf("Error on Find: %s", err)
+		}
+
+		expected := []audit_model.Operation{audit_model.OpRestore, audit_model.OpDelete, audit_model.OpUpdate, audit_model.OpCreate}
+		if len(entries) != len(expected) {
+			t.Fatalf("Expected %d entries, got %#v", len(expected), entries)
+		}
+		for i, entry := range entries {
+			if entry.Operation != expected[i] || entry.Actor != "importer" || entry.Entity != AUDIT_ENTITY {
+				t.Errorf("Unexpected entry %d: %#v", i, entry)
+			}
+		}
+
+		changes := map[string]audit_model.Change{}
+		for _, change := range entries[2].Changes {
+			changes[change.Field] = change
+		}
+		if age := changes["age"]; age.Before != float64(1) || age.After != float64(2) {
+			t.Errorf("Expected age to change from 1 to 2, got %#v", entries[2].Changes)
+		}
+		if _, found := changes["name"]; found {
+			t.Errorf("Expected unchanged name not to be reported, got %#v", entries[2].Changes)
+		}
+
+		deleted := false
+		for _, change := range entries[1].Changes {
+			deleted = deleted || change.Field == "deleted_at" && change.Before == nil && change.After != nil
+		}
+		if !deleted {
+			t.Errorf("Expected the deletion mark in the changes, got %#v", entries[1].Changes)
+		}
+	})
+}
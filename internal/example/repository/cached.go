@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/morphy76/g-fe-server/internal/cache"
+	model "github.com/morphy76/g-fe-server/pkg/example"
+)
+
+// CACHE_NAME names the cache of the examples by name
+const CACHE_NAME = "example"
+
+// cachedRepository reads examples by name through the cache, every write invalidates the names it touches
+// whatever its outcome; the audited repository reads the states to compare past the cache
+type cachedRepository struct {
+	delegate model.Repository
+	cache    *cache.Cache
+}
+
+func newCachedRepository(delegate model.Repository, c *cache.Cache) model.Repository {
+	return &cachedRepository{
+		delegate: delegate,
+		cache:    c,
+	}
+}
+
+func (r *cachedRepository) FindAll(ctx context.Context) ([]model.Example, error) {
+	return r.delegate.FindAll(ctx)
+}
+
+func (r *cachedRepository) Find(ctx context.Context, query model.Query) (model.Page, error) {
+	return r.delegate.Find(ctx, query)
+}
+
+func (r *cachedRepository) FindById(ctx context.Context, id string) (model.Example, error) {
+	return cache.ReadThrough(ctx, r.cache, CACHE_NAME, id, func(ctx context.Context) (model.Example, error) {
+		return r.delegate.FindById(ctx, id)
+	})
+}
+
+func (r *cachedRepository) Save(ctx context.Context, e model.Example) error {
+	defer r.cache.Invalidate(ctx, CACHE_NAME, e.Name)
+	return r.delegate.Save(ctx, e)
+}
+
+func (r *cachedRepository) Update(ctx context.Context, e model.Example) (model.Example, error) {
+	defer r.cache.Invalidate(ctx, CACHE_NAME, e.Name)
+	return r.delegate.Update(ctx, e)
+}
+
+func (r *cachedRepository) Delete(ctx context.Context, id string, version int64) error {
+	defer r.cache.Invalidate(ctx, CACHE_NAME, id)
+	return r.delegate.Delete(ctx, id, version)
+}
+
+func (r *cachedRepository) Restore(ctx context.Context, id string, version int64) (model.Example, error) {
+	defer r.cache.Invalidate(ctx, CACHE_NAME, id)
+	return r.delegate.Restore(ctx, id, version)
+}
+
+func (r *cachedRepository) Patch(ctx context.Context, id string, patch model.Patch, version int64) (model.Example, error) {
+	defer r.cache.Invalidate(ctx, CACHE_NAME, id)
+	return r.delegate.Patch(ctx, id, patch, version)
+}
+
+func (r *cachedRepository) SaveMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	defer r.cache.Invalidate(ctx, CACHE_NAME, names(items)...)
+	return r.delegate.SaveMany(ctx, items, ordered)
+}
+
+func (r *cachedRepository) UpdateMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	defer r.cache.Invalidate(ctx, CACHE_NAME, names(items)...)
+	return r.delegate.UpdateMany(ctx, items, ordered)
+}
+
+func (r *cachedRepository) DeleteMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	defer r.cache.Invalidate(ctx, CACHE_NAME, names(items)...)
+	return r.delegate.DeleteMany(ctx, items, ordered)
+}
+
+func names(items []model.Example) []string {
+	rv := make([]string, len(items))
+	for i, e := range items {
+		rv[i] = e.Name
+	}
+	return rv
+}
@@ -2,23 +2,63 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 
+	audit_repository "github.com/morphy76/g-fe-server/internal/audit/repository"
+	"github.com/morphy76/g-fe-server/internal/cache"
 	"github.com/morphy76/g-fe-server/internal/db"
 	impl "github.com/morphy76/g-fe-server/internal/example/repository/impl"
 	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/outbox"
+	outbox_repository "github.com/morphy76/g-fe-server/internal/outbox/repository"
 	model "github.com/morphy76/g-fe-server/pkg/example"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// NewRepository builds the repository of the storage configured in the request context, reading through the
+// cache when there is one
 func NewRepository(requestContext context.Context) (model.Repository, error) {
 
+	rv, err := newStorageRepository(requestContext)
+	if err != nil {
+		return nil, err
+	}
+
+	if c := cache.Extract(requestContext); c != nil {
+		return newCachedRepository(rv, c), nil
+	}
+	return rv, nil
+}
+
+func newStorageRepository(requestContext context.Context) (model.Repository, error) {
+
+	auditRepository, err := audit_repository.NewRepository(requestContext)
+	if err != nil {
+		return nil, err
+	}
+
 	dbOptions := db.ExtractDbOptions(requestContext)
 	dbClient := db.ExtractDb(requestContext)
 
 	switch dbOptions.Type {
 	case options.RepositoryTypeMemoryDB:
-		return impl.NewMemoryRepository(), nil
+		memoryClient, ok := dbClient.(*db.MemoryDbClient)
+		if !ok {
+			return nil, errors.New("memory client not found in request context")
+		}
+
+		rv, err := impl.NewMemoryRepository(memoryClient)
+		if err != nil {
+			return nil, err
+		}
+
+		outboxed, err := withOutbox(requestContext, newTracedRepository(rv, "memory", impl.MEMORY_STORE))
+		if err != nil {
+			return nil, err
+		}
+
+		return newAuditedRepository(outboxed, auditRepository), nil
 	case options.RepositoryTypeMongoDB:
 		if dbClient == nil {
 			return nil, errors.New("MongoDB client not found in request context")
@@ -27,13 +67,53 @@ func NewRepository(requestContext context.Context) (model.Repository, error) {
 		mongoClient := dbClient.(*mongo.Client)
 
 		var rv model.Repository = &impl.MongoRepository{
-			DbOptions:  dbOptions,
-			Client:     mongoClient,
-			UseContext: requestContext,
+			DbOptions: dbOptions,
+			Client:    mongoClient,
 		}
 
-		return rv, nil
+		outboxed, err := withOutbox(requestContext, newTracedRepository(rv, "mongodb", impl.MONGO_COLLECTION))
+		if err != nil {
+			return nil, err
+		}
+
+		return newAuditedRepository(outboxed, auditRepository), nil
+	case options.RepositoryTypePostgreSQL, options.RepositoryTypeSQLite:
+		sqlDb, ok := dbClient.(*sql.DB)
+		if !ok {
+			return nil, errors.New("SQL client not found in request context")
+		}
+
+		dialect, err := db.DialectOf(dbOptions.Type)
+		if err != nil {
+			return nil, err
+		}
+
+		var rv model.Repository = &impl.SqlRepository{
+			Db:      sqlDb,
+			Dialect: dialect,
+		}
+
+		outboxed, err := withOutbox(requestContext, newTracedRepository(rv, dialect.Name, impl.SQL_TABLE))
+		if err != nil {
+			return nil, err
+		}
+
+		return newAuditedRepository(outboxed, auditRepository), nil
 	default:
 		return nil, model.ErrUnknownRepositoryType
 	}
 }
+
+// withOutbox appends the changes of the repository to the outbox when the context enables it
+func withOutbox(requestContext context.Context, rv model.Repository) (model.Repository, error) {
+
+	if !outbox.Enabled(requestContext) {
+		return rv, nil
+	}
+
+	outboxRepository, transactor, err := outbox_repository.NewRepository(requestContext)
+	if err != nil {
+		return nil, err
+	}
+	return newOutboxRepository(rv, outboxRepository, transactor), nil
+}
@@ -3,10 +3,13 @@ package repository
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/morphy76/g-fe-server/internal/cache"
 	"github.com/morphy76/g-fe-server/internal/db"
 	example "github.com/morphy76/g-fe-server/internal/example/repository/impl"
 	"github.com/morphy76/g-fe-server/internal/options"
+	model "github.com/morphy76/g-fe-server/pkg/example"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -17,13 +20,19 @@ func TestFactorySuite(t *testing.T) {
 
 	t.Run("Test RepositoryTypeMemoryDB", func(t *testing.T) {
 		t.Log("Test Factory RepositoryTypeMemoryDB")
-		useContext := db.InjectDbOptions(testContext, &options.DbOptions{
+		useContext := db.InjectDbOptions(db.InjectDb(testContext, db.NewMemoryDbClient()), &options.DbOptions{
 			Type: options.RepositoryTypeMemoryDB,
 		})
 		if repo, err := NewRepository(useContext); err != nil {
 			t.Fatalf("Failed to create the repository: %s", err)
-		} else if _, ok := repo.(*example.MemoryRepository); !ok {
-			t.Fatalf("Expected Repository got %T", repo)
+		} else if audited, ok := repo.(*auditedRepository); !ok {
+			t.Fatalf("Expected audited Repository got %T", repo)
+		} else if traced, ok := audited.delegate.(*tracedRepository); !ok {
+			t.Fatalf("Expected traced Repository got %T", audited.delegate)
+		} else if _, ok := traced.delegate.(*example.MemoryRepository); !ok {
+			t.Fatalf("Expected Repository got %T", traced.delegate)
+		} else if traced.system != "memory" || traced.collection != example.MEMORY_STORE {
+			t.Fatalf("Expected the spans of the memory examples, got %s %s", traced.system, traced.collection)
 		}
 	})
 
@@ -34,8 +43,42 @@ func TestFactorySuite(t *testing.T) {
 		})
 		if repo, err := NewRepository(useContext); err != nil {
 			t.Fatalf("Failed to create the repository: %s", err)
-		} else if _, ok := repo.(*example.MongoRepository); !ok {
-			t.Fatalf("Expected Repository got %T", repo)
+		} else if audited, ok := repo.(*auditedRepository); !ok {
+			t.Fatalf("Expected audited Repository got %T", repo)
+		} else if traced, ok := audited.delegate.(*tracedRepository); !ok {
+			t.Fatalf("Expected traced Repository got %T", audited.delegate)
+		} else if _, ok := traced.delegate.(*example.MongoRepository); !ok {
+			t.Fatalf("Expected Repository got %T", traced.delegate)
+		} else if traced.system != "mongodb" || traced.collection != example.MONGO_COLLECTION {
+			t.Fatalf("Expected the spans of the mongodb examples, got %s %s", traced.system, traced.collection)
+		}
+	})
+
+	t.Run("Test Cached", func(t *testing.T) {
+		t.Log("Test Factory Cached")
+		repositoryCache, _ := cache.New(options.CacheOptions{Size: 8, TTL: time.Minute})
+		useContext := cache.Inject(db.InjectDbOptions(db.InjectDb(testContext, db.NewMemoryDbClient()), &options.DbOptions{
+			Type: options.RepositoryTypeMemoryDB,
+		}), repositoryCache)
+		repo, err := NewRepository(useContext)
+		if err != nil {
+			t.Fatalf("Failed to create the repository: %s", err)
+		} else if cached, ok := repo.(*cachedRepository); !ok {
+			t.Fatalf("Expected cached Repository got %T", repo)
+		} else if _, ok := cached.delegate.(*auditedRepository); !ok {
+			t.Fatalf("Expected audited Repository got %T", cached.delegate)
+		}
+
+		if err := repo.Save(useContext, model.Example{Name: "cached", Age: 1}); err != nil {
+			t.Fatalf("Error on Save: %s", err)
+		}
+		current, _ := repo.FindById(useContext, "cached")
+		current.Age = 2
+		if _, err := repo.Update(useContext, current); err != nil {
+			t.Fatalf("Error on Update: %s", err)
+		}
+		if found, _ := repo.FindById(useContext, "cached"); found.Age != 2 {
+			t.Errorf("Expected the updated example, got %v", found)
 		}
 	})
 }
@@ -1,70 +1,300 @@
 package example
 
 import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
 	"github.com/morphy76/g-fe-server/pkg/example"
 )
 
+// MEMORY_STORE names the examples in the stores of the memory client
+const MEMORY_STORE = "examples"
+
 type MemoryRepository struct {
-	db map[string]example.Example
+	lock *sync.RWMutex
+	db   map[string]example.Example
 }
 
-var exampleDb map[string]example.Example = map[string]example.Example{
-	"1": {
-		Name: "1",
-		Age:  1,
-	},
-	"2": {
-		Name: "2",
-		Age:  2,
-	},
-}
+func NewMemoryRepository(client *db.MemoryDbClient) (example.Repository, error) {
 
-func NewMemoryRepository() example.Repository {
-	return &MemoryRepository{
-		db: exampleDb,
+	table, err := db.MemoryTableOf(client, MEMORY_STORE, func(e example.Example) string { return e.Name })
+	if err != nil {
+		return nil, err
 	}
+
+	return &MemoryRepository{
+		lock: &table.Lock,
+		db:   table.Rows,
+	}, nil
 }
 
-func (r *MemoryRepository) FindAll() ([]example.Example, error) {
+func (r *MemoryRepository) FindAll(ctx context.Context) ([]example.Example, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
 	values := make([]example.Example, 0, len(r.db))
 	for _, v := range r.db {
-		values = append(values, v)
+		if !v.IsDeleted() {
+			values = append(values, v)
+		}
 	}
 	return values, nil
 }
 
-func (r *MemoryRepository) FindById(id string) (example.Example, error) {
+func (r *MemoryRepository) Find(ctx context.Context, query example.Query) (example.Page, error) {
+	if err := ctx.Err(); err != nil {
+		return example.Page{}, err
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	sortFields := query.EffectiveSort()
+	var cursor []any
+	if query.Cursor != "" {
+		var err error
+		cursor, err = example.DecodeCursor(query.Cursor, sortFields)
+		if err != nil {
+			return example.Page{}, err
+		}
+	}
+
+	matching := make([]example.Example, 0, len(r.db))
+	for _, v := range r.db {
+		if v.IsDeleted() == query.Deleted && matchesAll(v, query.Filters) {
+			matching = append(matching, v)
+		}
+	}
+	sort.Slice(matching, func(a, b int) bool {
+		return example.AfterCursor(matching[b], sortFields, cursorOf(matching[a], sortFields))
+	})
+
+	rv := example.Page{
+		Items: make([]example.Example, 0),
+		Total: int64(len(matching)),
+	}
+
+	start := 0
+	if cursor != nil {
+		start = sort.Search(len(matching), func(i int) bool {
+			return example.AfterCursor(matching[i], sortFields, cursor)
+		})
+	} else if query.Offset > 0 {
+		start = min(query.Offset, len(matching))
+	}
+
+	end := min(start+query.EffectiveLimit(), len(matching))
+	rv.Items = append(rv.Items, matching[start:end]...)
+	if end < len(matching) && len(rv.Items) > 0 {
+		rv.NextCursor = example.EncodeCursor(rv.Items[len(rv.Items)-1], sortFields)
+	}
+
+	return rv, nil
+}
+
+func (r *MemoryRepository) FindById(ctx context.Context, id string) (example.Example, error) {
+	if err := ctx.Err(); err != nil {
+		return example.Example{}, err
+	}
+
+	r.lock.RLock()
+	defer r.lock.RUnlock()
 
 	rv, ok := r.db[id]
-	if !ok {
-		return rv, example.ErrNotFound
+	if !ok || rv.IsDeleted() {
+		return example.Example{}, example.ErrNotFound
 	}
 	return rv, nil
 }
 
-func (r *MemoryRepository) Save(e example.Example) error {
+func (r *MemoryRepository) Save(ctx context.Context, e example.Example) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_, err := r.save(e)
+	return err
+}
+
+func (r *MemoryRepository) Update(ctx context.Context, e example.Example) (example.Example, error) {
+	if err := ctx.Err(); err != nil {
+		return example.Example{}, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.update(e)
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id string, version int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_, err := r.remove(example.Example{Name: id, Version: version})
+	return err
+}
+
+func (r *MemoryRepository) Restore(ctx context.Context, id string, version int64) (example.Example, error) {
+	if err := ctx.Err(); err != nil {
+		return example.Example{}, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	appo, ok := r.db[id]
+	if !ok || !appo.IsDeleted() {
+		return example.Example{}, example.ErrNotFound
+	}
+	if version != 0 && version != appo.Version {
+		return example.Example{}, example.ErrVersionConflict
+	}
+	appo.DeletedAt = nil
+	appo.Version++
+	r.db[id] = appo
+	return appo, nil
+}
+
+func (r *MemoryRepository) Patch(ctx context.Context, id string, patch example.Patch, version int64) (example.Example, error) {
+	if err := ctx.Err(); err != nil {
+		return example.Example{}, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	appo, ok := r.db[id]
+	if !ok || appo.IsDeleted() {
+		return example.Example{}, example.ErrNotFound
+	}
+	if version != 0 && version != appo.Version {
+		return example.Example{}, example.ErrVersionConflict
+	}
+
+	patched, err := patch.Apply(appo)
+	if err != nil {
+		return example.Example{}, err
+	}
+	patched.Version = appo.Version + 1
+	r.db[id] = patched
+	return patched, nil
+}
+
+func (r *MemoryRepository) SaveMany(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error) {
+	return r.writeMany(ctx, items, ordered, example.BulkCreated, r.save)
+}
+
+func (r *MemoryRepository) UpdateMany(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error) {
+	return r.writeMany(ctx, items, ordered, example.BulkUpdated, r.update)
+}
+
+func (r *MemoryRepository) DeleteMany(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error) {
+	return r.writeMany(ctx, items, ordered, example.BulkDeleted, r.remove)
+}
+
+// writeMany applies the writes under a single lock, so that the batch is isolated from concurrent requests
+func (r *MemoryRepository) writeMany(
+	ctx context.Context,
+	items []example.Example,
+	ordered bool,
+	succeeded example.BulkStatus,
+	write func(e example.Example) (example.Example, error),
+) ([]example.BulkResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	rv := example.NewBulkResults(items)
+	for i, e := range items {
+		stored, err := write(e)
+		if err != nil {
+			status, ok := example.BulkStatusOf(err)
+			if !ok {
+				return nil, err
+			}
+			rv[i].Status = status
+			if ordered {
+				break
+			}
+			continue
+		}
+		rv[i].Status = succeeded
+		rv[i].Version = stored.Version
+	}
+	return rv, nil
+}
+
+func (r *MemoryRepository) save(e example.Example) (example.Example, error) {
 	if _, ok := r.db[e.Name]; ok {
-		return example.ErrAlreadyExists
+		return example.Example{}, example.ErrAlreadyExists
 	}
+	e.Version = 1
+	e.DeletedAt = nil
 	r.db[e.Name] = e
-	return nil
+	return e, nil
 }
 
-func (r *MemoryRepository) Update(e example.Example) error {
+func (r *MemoryRepository) update(e example.Example) (example.Example, error) {
 	appo, ok := r.db[e.Name]
-	if !ok {
-		return example.ErrNotFound
+	if !ok || appo.IsDeleted() {
+		return example.Example{}, example.ErrNotFound
 	}
-	appo.Age = e.Age
+	if e.Version != 0 && e.Version != appo.Version {
+		return example.Example{}, example.ErrVersionConflict
+	}
+	e.Version = appo.Version + 1
+	e.DeletedAt = nil
+	r.db[e.Name] = e
+	return e, nil
+}
+
+// remove soft deletes the example named after the given one, which only carries the expected version
+func (r *MemoryRepository) remove(e example.Example) (example.Example, error) {
+	appo, ok := r.db[e.Name]
+	if !ok || appo.IsDeleted() {
+		return example.Example{}, example.ErrNotFound
+	}
+	if e.Version != 0 && e.Version != appo.Version {
+		return example.Example{}, example.ErrVersionConflict
+	}
+	deletedAt := time.Now().UTC()
+	appo.DeletedAt = &deletedAt
+	appo.Version++
 	r.db[e.Name] = appo
-	return nil
+	return appo, nil
+}
+
+func matchesAll(e example.Example, filters []example.Filter) bool {
+	for _, f := range filters {
+		if !f.Matches(e) {
+			return false
+		}
+	}
+	return true
 }
 
-func (r *MemoryRepository) Delete(id string) error {
-	if _, ok := r.db[id]; !ok {
-		return example.ErrNotFound
+func cursorOf(e example.Example, sortFields []example.SortField) []any {
+	rv := make([]any, len(sortFields))
+	for i, s := range sortFields {
+		rv[i] = e.FieldValue(s.Field)
 	}
-	delete(r.db, id)
-	return nil
+	return rv
 }
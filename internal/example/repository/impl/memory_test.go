@@ -1,30 +1,35 @@
 package example
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/morphy76/g-fe-server/internal/db"
 	"github.com/morphy76/g-fe-server/pkg/example"
 )
 
 func TestMemoryRepositorySuite(t *testing.T) {
 	t.Log("Test MemoryRepository Suite")
 
-	repo := NewMemoryRepository()
+	ctx := context.Background()
+	repo, _ := NewMemoryRepository(db.NewMemoryDbClient())
 	t.Logf("Repository URL: memory")
 
 	t.Run("Test List", func(t *testing.T) {
 		t.Log("Testing Memory List")
 
-		repo.Save(example.Example{
+		repo.Save(ctx, example.Example{
 			Name: "Test",
 			Age:  10,
 		})
-		repo.Save(example.Example{
+		repo.Save(ctx, example.Example{
 			Name: "Test2",
 			Age:  20,
 		})
 
-		items, err := repo.FindAll()
+		items, err := repo.FindAll(ctx)
 		if err != nil {
 			t.Errorf("Error on List: %s", err)
 		}
@@ -36,4 +41,244 @@ func TestMemoryRepositorySuite(t *testing.T) {
 			t.Logf("%#v", item)
 		}
 	})
+
+	t.Run("Test Find", func(t *testing.T) {
+		t.Log("Testing Memory Find")
+
+		for i := 0; i < 5; i++ {
+			repo.Save(ctx, example.Example{
+				Name: fmt.Sprintf("Page%d", i),
+				Age:  30 + i%2,
+			})
+		}
+
+		ageFilter, _ := example.ParseFilter("age>=30")
+		nameFilter, _ := example.ParseFilter("name^=Page")
+		query := example.Query{
+			Limit:   2,
+			Sort:    []example.SortField{{Field: "age", Desc: true}},
+			Filters: []example.Filter{ageFilter, nameFilter},
+		}
+
+		seen := make([]string, 0)
+		for {
+			page, err := repo.Find(ctx, query)
+			if err != nil {
+				t.Fatalf("Error on Find: %s", err)
+			}
+			if page.Total != 5 {
+				t.Errorf("Expected total 5, got %d", page.Total)
+			}
+			for _, item := range page.Items {
+				seen = append(seen, item.Name)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+
+		expected := []string{"Page1", "Page3", "Page0", "Page2", "Page4"}
+		if strings.Join(seen, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected %v, got %v", expected, seen)
+		}
+
+		page, err := repo.Find(ctx, example.Query{Offset: 4, Limit: 10, Filters: []example.Filter{nameFilter}})
+		if err != nil {
+			t.Fatalf("Error on Find: %s", err)
+		}
+		if len(page.Items) != 1 || page.Items[0].Name != "Page4" {
+			t.Errorf("Expected Page4 at offset 4, got %v", page.Items)
+		}
+
+		_, err = repo.Find(ctx, example.Query{Cursor: "not-a-cursor"})
+		if !example.IsInvalidQuery(err) {
+			t.Errorf("Expected invalid query, got %v", err)
+		}
+	})
+
+	t.Run("Test Versioning", func(t *testing.T) {
+		t.Log("Testing Memory Versioning")
+
+		if err := repo.Save(ctx, example.Example{Name: "Versioned", Age: 1, Version: 42}); err != nil {
+			t.Fatalf("Error on Save: %s", err)
+		}
+		saved, _ := repo.FindById(ctx, "Versioned")
+		if saved.Version != 1 {
+			t.Errorf("Expected version 1 on save, got %d", saved.Version)
+		}
+
+		updated, err := repo.Update(ctx, example.Example{Name: "Versioned", Age: 2, Version: 1})
+		if err != nil {
+			t.Fatalf("Error on Update: %s", err)
+		}
+		if updated.Version != 2 || updated.Age != 2 {
+			t.Errorf("Expected version 2 and age 2, got %#v", updated)
+		}
+
+		if _, err := repo.Update(ctx, example.Example{Name: "Versioned", Age: 3, Version: 1}); !example.IsVersionConflict(err) {
+			t.Errorf("Expected version conflict on stale update, got %v", err)
+		}
+		if err := repo.Delete(ctx, "Versioned", 1); !example.IsVersionConflict(err) {
+			t.Errorf("Expected version conflict on stale delete, got %v", err)
+		}
+
+		updated, err = repo.Update(ctx, example.Example{Name: "Versioned", Age: 4})
+		if err != nil || updated.Version != 3 {
+			t.Errorf("Expected unconditional update to version 3, got %#v, %v", updated, err)
+		}
+		if err := repo.Delete(ctx, "Versioned", 3); err != nil {
+			t.Errorf("Error on Delete: %s", err)
+		}
+		if err := repo.Delete(ctx, "Versioned", 3); !example.IsNotFound(err) {
+			t.Errorf("Expected not found, got %v", err)
+		}
+	})
+
+	t.Run("Test Patch", func(t *testing.T) {
+		t.Log("Testing Memory Patch")
+
+		if err := repo.Save(ctx, example.Example{Name: "Patched", Age: 1}); err != nil {
+			t.Fatalf("Error on Save: %s", err)
+		}
+
+		merge, _ := example.NewPatch(example.MergePatchMediaType, []byte(`{"age": 5}`))
+		patched, err := repo.Patch(ctx, "Patched", merge, 1)
+		if err != nil {
+			t.Fatalf("Error on merge Patch: %s", err)
+		}
+		if patched.Age != 5 || patched.Version != 2 {
+			t.Errorf("Expected age 5 at version 2, got %#v", patched)
+		}
+
+		ops, _ := example.NewPatch(example.JSONPatchMediaType, []byte(`[{"op": "test", "path": "/age", "value": 5}, {"op": "replace", "path": "/age", "value": 6}]`))
+		patched, err = repo.Patch(ctx, "Patched", ops, 0)
+		if err != nil {
+			t.Fatalf("Error on JSON Patch: %s", err)
+		}
+		if patched.Age != 6 || patched.Version != 3 {
+			t.Errorf("Expected age 6 at version 3, got %#v", patched)
+		}
+
+		if _, err := repo.Patch(ctx, "Patched", ops, 0); !example.IsPatchTestFailed(err) {
+			t.Errorf("Expected failed test, got %v", err)
+		}
+		if _, err := repo.Patch(ctx, "Patched", merge, 1); !example.IsVersionConflict(err) {
+			t.Errorf("Expected version conflict, got %v", err)
+		}
+
+		copied, _ := example.NewPatch(example.JSONPatchMediaType, []byte(`[{"op": "copy", "from": "/version", "path": "/age"}]`))
+		patched, err = repo.Patch(ctx, "Patched", copied, 3)
+		if err != nil {
+			t.Fatalf("Error on copy Patch: %s", err)
+		}
+		if patched.Age != 3 || patched.Version != 4 {
+			t.Errorf("Expected age 3 at version 4, got %#v", patched)
+		}
+
+		renamed, _ := example.NewPatch(example.MergePatchMediaType, []byte(`{"name": "Other"}`))
+		if _, err := repo.Patch(ctx, "Patched", renamed, 0); !example.IsInvalidPatch(err) {
+			t.Errorf("Expected invalid patch, got %v", err)
+		}
+		if _, err := repo.Patch(ctx, "Missing", merge, 0); !example.IsNotFound(err) {
+			t.Errorf("Expected not found, got %v", err)
+		}
+	})
+
+	t.Run("Test Bulk", func(t *testing.T) {
+		t.Log("Testing Memory Bulk")
+
+		statuses := func(results []example.BulkResult) []example.BulkStatus {
+			rv := make([]example.BulkStatus, len(results))
+			for i, result := range results {
+				rv[i] = result.Status
+			}
+			return rv
+		}
+		expect := func(label string, results []example.BulkResult, err error, expected ...example.BulkStatus) {
+			if err != nil {
+				t.Fatalf("Error on %s: %s", label, err)
+			}
+			if got := statuses(results); fmt.Sprint(got) != fmt.Sprint(expected) {
+				t.Errorf("Expected %s to report %v, got %v", label, expected, got)
+			}
+		}
+
+		created := []example.Example{{Name: "BulkA", Age: 1}, {Name: "BulkB", Age: 2}}
+		results, err := repo.SaveMany(ctx, created, true)
+		expect("SaveMany", results, err, example.BulkCreated, example.BulkCreated)
+
+		again := []example.Example{{Name: "BulkC", Age: 3}, {Name: "BulkA", Age: 1}, {Name: "BulkD", Age: 4}}
+		results, err = repo.SaveMany(ctx, again, true)
+		expect("ordered SaveMany", results, err, example.BulkCreated, example.BulkConflict, example.BulkSkipped)
+		results, err = repo.SaveMany(ctx, again[1:], false)
+		expect("unordered SaveMany", results, err, example.BulkConflict, example.BulkCreated)
+
+		updated := []example.Example{{Name: "BulkA", Age: 10, Version: 1}, {Name: "BulkB", Age: 20, Version: 7}, {Name: "BulkX", Age: 1}}
+		results, err = repo.UpdateMany(ctx, updated, false)
+		expect("UpdateMany", results, err, example.BulkUpdated, example.BulkConflict, example.BulkNotFound)
+		if results[0].Version != 2 {
+			t.Errorf("Expected version 2, got %d", results[0].Version)
+		}
+
+		deleted := []example.Example{{Name: "BulkA", Version: 1}, {Name: "BulkB"}, {Name: "BulkC", Version: 1}}
+		results, err = repo.DeleteMany(ctx, deleted, true)
+		expect("DeleteMany", results, err, example.BulkConflict, example.BulkSkipped, example.BulkSkipped)
+		deleted[0].Version = 2
+		results, err = repo.DeleteMany(ctx, deleted, true)
+		expect("DeleteMany", results, err, example.BulkDeleted, example.BulkDeleted, example.BulkDeleted)
+
+		if _, err := repo.FindById(ctx, "BulkB"); !example.IsNotFound(err) {
+			t.Errorf("Expected not found, got %v", err)
+		}
+	})
+
+	t.Run("Test Soft Delete", func(t *testing.T) {
+		t.Log("Testing Memory Soft Delete")
+
+		if err := repo.Save(ctx, example.Example{Name: "Trashed", Age: 1}); err != nil {
+			t.Fatalf("Error on Save: %s", err)
+		}
+		if err := repo.Delete(ctx, "Trashed", 1); err != nil {
+			t.Fatalf("Error on Delete: %s", err)
+		}
+
+		if _, err := repo.FindById(ctx, "Trashed"); !example.IsNotFound(err) {
+			t.Errorf("Expected deleted example not to be found, got %v", err)
+		}
+		if _, err := repo.Update(ctx, example.Example{Name: "Trashed", Age: 2}); !example.IsNotFound(err) {
+			t.Errorf("Expected deleted example not to be updated, got %v", err)
+		}
+		if err := repo.Delete(ctx, "Trashed", 0); !example.IsNotFound(err) {
+			t.Errorf("Expected deleted example not to be deleted again, got %v", err)
+		}
+		if err := repo.Save(ctx, example.Example{Name: "Trashed", Age: 3}); !example.IsAlreadyExists(err) {
+			t.Errorf("Expected the name of a deleted example to stay taken, got %v", err)
+		}
+
+		page, err := repo.Find(ctx, example.Query{Deleted: true, Filters: []example.Filter{{Field: "name", Op: example.OpEq, Value: "Trashed"}}})
+		if err != nil {
+			t.Fatalf("Error on Find: %s", err)
+		}
+		if len(page.Items) != 1 || !page.Items[0].IsDeleted() || page.Items[0].Version != 2 {
+			t.Fatalf("Expected the deleted example at version 2, got %#v", page.Items)
+		}
+
+		if _, err := repo.Restore(ctx, "Trashed", 1); !example.IsVersionConflict(err) {
+			t.Errorf("Expected version conflict, got %v", err)
+		}
+		restored, err := repo.Restore(ctx, "Trashed", 2)
+		if err != nil {
+			t.Fatalf("Error on Restore: %s", err)
+		}
+		if restored.IsDeleted() || restored.Version != 3 || restored.Age != 1 {
+			t.Errorf("Expected the restored example at version 3, got %#v", restored)
+		}
+		if _, err := repo.Restore(ctx, "Trashed", 0); !example.IsNotFound(err) {
+			t.Errorf("Expected live example not to be restored, got %v", err)
+		}
+		if _, err := repo.FindById(ctx, "Trashed"); err != nil {
+			t.Errorf("Expected restored example to be found, got %v", err)
+		}
+	})
 }
@@ -1,39 +1,45 @@
 package example
 
 import (
+	"errors"
 	"net/url"
 	"path"
+	"regexp"
+	"time"
 
 	"github.com/morphy76/g-fe-server/internal/options"
 	"github.com/morphy76/g-fe-server/pkg/example"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	mongo_options "go.mongodb.org/mongo-driver/mongo/options"
 
 	"context"
 )
 
 const MONGO_COLLECTION = "examples"
 
+// patchAttempts bounds the read-apply-swap cycles of patches which can not be translated to update operators
+const patchAttempts = 3
+
 type MongoRepository struct {
 	DbOptions  *options.DbOptions
 	Client     *mongo.Client
-	UseContext context.Context
 	collection *mongo.Collection
 }
 
-func (r *MongoRepository) FindAll() ([]example.Example, error) {
+func (r *MongoRepository) FindAll(ctx context.Context) ([]example.Example, error) {
 
 	r.lazyBindCollection()
 
-	cur, err := r.collection.Find(r.UseContext, bson.D{})
+	cur, err := r.collection.Find(ctx, bson.D{liveCondition})
 	if err != nil {
 		return nil, err
 	}
-	defer cur.Close(r.UseContext)
+	defer cur.Close(ctx)
 
 	var rv []example.Example
-	err = cur.All(r.UseContext, &rv)
+	err = cur.All(ctx, &rv)
 	if err != nil {
 		return nil, err
 	}
@@ -41,13 +47,64 @@ func (r *MongoRepository) FindAll() ([]example.Example, error) {
 	return rv, nil
 }
 
-func (r *MongoRepository) FindById(id string) (example.Example, error) {
+func (r *MongoRepository) Find(ctx context.Context, query example.Query) (example.Page, error) {
+
+	r.lazyBindCollection()
+
+	sortFields := query.EffectiveSort()
+	filter := filterDocument(query.Filters, query.Deleted)
+
+	total, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return example.Page{}, err
+	}
+
+	limit := query.EffectiveLimit()
+	findOptions := mongo_options.Find().
+		SetSort(sortDocument(sortFields)).
+		SetLimit(int64(limit + 1))
+
+	if query.Cursor != "" {
+		cursor, err := example.DecodeCursor(query.Cursor, sortFields)
+		if err != nil {
+			return example.Page{}, err
+		}
+		filter = bson.D{{Key: "$and", Value: bson.A{filter, keysetDocument(sortFields, cursor)}}}
+	} else if query.Offset > 0 {
+		findOptions.SetSkip(int64(query.Offset))
+	}
+
+	cur, err := r.collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return example.Page{}, err
+	}
+	defer cur.Close(ctx)
+
+	items := make([]example.Example, 0, limit)
+	err = cur.All(ctx, &items)
+	if err != nil {
+		return example.Page{}, err
+	}
+
+	rv := example.Page{
+		Items: items,
+		Total: total,
+	}
+	if len(items) > limit {
+		rv.Items = items[:limit]
+		rv.NextCursor = example.EncodeCursor(rv.Items[limit-1], sortFields)
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) FindById(ctx context.Context, id string) (example.Example, error) {
 
 	r.lazyBindCollection()
 
 	rv := example.Example{}
 
-	singleResult := r.collection.FindOne(r.UseContext, bson.D{{Key: "name", Value: id}})
+	singleResult := r.collection.FindOne(ctx, bson.D{{Key: "name", Value: id}, liveCondition})
 
 	err := singleResult.Decode(&rv)
 	if err != nil {
@@ -60,11 +117,13 @@ func (r *MongoRepository) FindById(id string) (example.Example, error) {
 	return rv, nil
 }
 
-func (r *MongoRepository) Save(e example.Example) error {
+func (r *MongoRepository) Save(ctx context.Context, e example.Example) error {
 
 	r.lazyBindCollection()
 
-	_, err := r.collection.InsertOne(r.UseContext, e)
+	e.Version = 1
+	e.DeletedAt = nil
+	_, err := r.collection.InsertOne(ctx, e)
 	if err != nil {
 		if mongo.IsDuplicateKeyError(err) {
 			return example.ErrAlreadyExists
@@ -75,34 +134,360 @@ func (r *MongoRepository) Save(e example.Example) error {
 	return nil
 }
 
-func (r *MongoRepository) Update(e example.Example) error {
+func (r *MongoRepository) Update(ctx context.Context, e example.Example) (example.Example, error) {
 
 	r.lazyBindCollection()
 
-	updateResult, err := r.collection.ReplaceOne(r.UseContext, bson.D{{Key: "name", Value: e.Name}}, e)
+	fields, err := replacementFields(e)
 	if err != nil {
-		return err
+		return example.Example{}, err
 	}
-	if updateResult.MatchedCount == 0 {
-		return example.ErrNotFound
+
+	rv, err := r.updateOne(ctx, versionedFilter(e.Name, e.Version), fields)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return rv, r.missOrConflict(ctx, e.Name, e.Version)
+		}
+		return rv, err
 	}
 
-	return nil
+	return rv, nil
 }
 
-func (r *MongoRepository) Delete(id string) error {
+func (r *MongoRepository) Delete(ctx context.Context, id string, version int64) error {
 
 	r.lazyBindCollection()
 
-	deleteResult, err := r.collection.DeleteOne(r.UseContext, bson.D{{Key: "name", Value: id}})
+	_, err := r.updateOne(ctx, versionedFilter(id, version), bson.M{"deleted_at": time.Now().UTC()})
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return r.missOrConflict(ctx, id, version)
+		}
 		return err
 	}
-	if deleteResult.DeletedCount == 0 {
+
+	return nil
+}
+
+func (r *MongoRepository) Restore(ctx context.Context, id string, version int64) (example.Example, error) {
+
+	r.lazyBindCollection()
+
+	filter := bson.D{{Key: "name", Value: id}, deletedCondition}
+	if version != 0 {
+		filter = append(filter, bson.E{Key: "version", Value: version})
+	}
+	update := bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+		{Key: "$unset", Value: bson.D{{Key: "deleted_at", Value: ""}}},
+	}
+	findOptions := mongo_options.FindOneAndUpdate().SetReturnDocument(mongo_options.After)
+
+	rv := example.Example{}
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, findOptions).Decode(&rv)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return rv, err
+		}
+		if version == 0 {
+			return rv, example.ErrNotFound
+		}
+		count, err := r.collection.CountDocuments(ctx, bson.D{{Key: "name", Value: id}, deletedCondition}, mongo_options.Count().SetLimit(1))
+		if err != nil {
+			return rv, err
+		}
+		if count == 0 {
+			return rv, example.ErrNotFound
+		}
+		return rv, example.ErrVersionConflict
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) Patch(ctx context.Context, id string, patch example.Patch, version int64) (example.Example, error) {
+
+	r.lazyBindCollection()
+
+	set, tests, ok, err := patch.Assignments(id)
+	if err != nil {
+		return example.Example{}, err
+	}
+	if ok {
+		return r.patchInPlace(ctx, id, set, tests, version)
+	}
+
+	for attempt := 0; attempt < patchAttempts; attempt++ {
+		current, err := r.FindById(ctx, id)
+		if err != nil {
+			return example.Example{}, err
+		}
+		if version != 0 && version != current.Version {
+			return example.Example{}, example.ErrVersionConflict
+		}
+
+		patched, err := patch.Apply(current)
+		if err != nil {
+			return example.Example{}, err
+		}
+		fields, err := replacementFields(patched)
+		if err != nil {
+			return example.Example{}, err
+		}
+
+		rv, err := r.updateOne(ctx, swapFilter(id, current.Version), fields)
+		if err != mongo.ErrNoDocuments {
+			return rv, err
+		}
+	}
+
+	return example.Example{}, example.ErrVersionConflict
+}
+
+func (r *MongoRepository) SaveMany(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error) {
+
+	r.lazyBindCollection()
+
+	rv := example.NewBulkResults(items)
+	if len(items) == 0 {
+		return rv, nil
+	}
+
+	documents := make([]any, len(items))
+	for i, e := range items {
+		e.Version = 1
+		e.DeletedAt = nil
+		documents[i] = e
+	}
+
+	_, err := r.collection.InsertMany(ctx, documents, mongo_options.InsertMany().SetOrdered(ordered))
+
+	failed := make(map[int]example.BulkStatus)
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+			return nil, err
+		}
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr) {
+				return nil, err
+			}
+			failed[writeErr.Index] = example.BulkConflict
+		}
+	}
+
+	for i := range rv {
+		if status, ok := failed[i]; ok {
+			rv[i].Status = status
+			if ordered {
+				break
+			}
+			continue
+		}
+		rv[i].Status = example.BulkCreated
+		rv[i].Version = 1
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) UpdateMany(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error) {
+
+	r.lazyBindCollection()
+
+	return r.writeMany(ctx, items, ordered, example.BulkUpdated, func(e example.Example) (mongo.WriteModel, error) {
+		fields, err := replacementFields(e)
+		if err != nil {
+			return nil, err
+		}
+		update := bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+			{Key: "$set", Value: fields},
+		}
+		return mongo.NewUpdateOneModel().SetFilter(versionedFilter(e.Name, e.Version)).SetUpdate(update), nil
+	})
+}
+
+func (r *MongoRepository) DeleteMany(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error) {
+
+	r.lazyBindCollection()
+
+	deletedAt := time.Now().UTC()
+	return r.writeMany(ctx, items, ordered, example.BulkDeleted, func(e example.Example) (mongo.WriteModel, error) {
+		update := bson.D{
+			{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+			{Key: "$set", Value: bson.D{{Key: "deleted_at", Value: deletedAt}}},
+		}
+		return mongo.NewUpdateOneModel().SetFilter(versionedFilter(e.Name, e.Version)).SetUpdate(update), nil
+	})
+}
+
+// writeMany runs versioned writes of existing documents as a single bulk write; since a bulk write does not tell
+// which operations matched nothing, misses and conflicts are found reading the versions before and after it
+func (r *MongoRepository) writeMany(
+	ctx context.Context,
+	items []example.Example,
+	ordered bool,
+	succeeded example.BulkStatus,
+	model func(e example.Example) (mongo.WriteModel, error),
+) ([]example.BulkResult, error) {
+
+	rv := example.NewBulkResults(items)
+	if len(items) == 0 {
+		return rv, nil
+	}
+
+	before, err := r.versions(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		models  = make([]mongo.WriteModel, 0, len(items))
+		written = make([]int, 0, len(items))
+	)
+	for i, e := range items {
+		current, found := before[e.Name]
+		if !found || (e.Version != 0 && e.Version != current) {
+			rv[i].Status = example.BulkNotFound
+			if found {
+				rv[i].Status = example.BulkConflict
+			}
+			if ordered {
+				break
+			}
+			continue
+		}
+
+		writeModel, err := model(e)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, writeModel)
+		written = append(written, i)
+	}
+
+	if len(models) == 0 {
+		return rv, nil
+	}
+
+	bulkResult, err := r.collection.BulkWrite(ctx, models, mongo_options.BulkWrite().SetOrdered(ordered))
+	if err != nil {
+		return nil, err
+	}
+
+	var after map[string]int64
+	if bulkResult.MatchedCount < int64(len(models)) {
+		after, err = r.versions(ctx, items)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// deleted documents are no longer live, so the read after tells them apart from conflicting ones
+	for _, i := range written {
+		name := items[i].Name
+		expected := before[name] + 1
+
+		if after != nil {
+			current, found := after[name]
+			switch {
+			case succeeded == example.BulkDeleted && found:
+				rv[i].Status = example.BulkConflict
+				continue
+			case succeeded != example.BulkDeleted && !found:
+				rv[i].Status = example.BulkNotFound
+				continue
+			case found && current != expected:
+				rv[i].Status = example.BulkConflict
+				continue
+			}
+		}
+
+		rv[i].Status = succeeded
+		rv[i].Version = expected
+	}
+
+	return rv, nil
+}
+
+// versions reads the current version of the live stored items by name
+func (r *MongoRepository) versions(ctx context.Context, items []example.Example) (map[string]int64, error) {
+
+	names := make(bson.A, len(items))
+	for i, e := range items {
+		names[i] = e.Name
+	}
+
+	findOptions := mongo_options.Find().SetProjection(bson.D{{Key: "name", Value: 1}, {Key: "version", Value: 1}})
+	cur, err := r.collection.Find(ctx, bson.D{{Key: "name", Value: bson.D{{Key: "$in", Value: names}}}, liveCondition}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var stored []example.Example
+	if err := cur.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+
+	rv := make(map[string]int64, len(stored))
+	for _, e := range stored {
+		rv[e.Name] = e.Version
+	}
+	return rv, nil
+}
+
+func (r *MongoRepository) patchInPlace(ctx context.Context, id string, set map[string]any, tests map[string]any, version int64) (example.Example, error) {
+
+	filter := versionedFilter(id, version)
+	for field, value := range tests {
+		filter = append(filter, bson.E{Key: field, Value: value})
+	}
+
+	rv, err := r.updateOne(ctx, filter, set)
+	if err != mongo.ErrNoDocuments {
+		return rv, err
+	}
+
+	current, err := r.FindById(ctx, id)
+	if err != nil {
+		return rv, err
+	}
+	if version != 0 && version != current.Version {
+		return rv, example.ErrVersionConflict
+	}
+	return rv, example.ErrPatchTestFailed
+}
+
+// updateOne sets the fields and bumps the version of the matching document, returning the updated document
+func (r *MongoRepository) updateOne(ctx context.Context, filter bson.D, fields map[string]any) (example.Example, error) {
+
+	update := bson.D{{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}}}
+	if len(fields) > 0 {
+		update = append(update, bson.E{Key: "$set", Value: fields})
+	}
+	findOptions := mongo_options.FindOneAndUpdate().SetReturnDocument(mongo_options.After)
+
+	rv := example.Example{}
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, findOptions).Decode(&rv)
+	return rv, err
+}
+
+// missOrConflict tells why a versioned write matched nothing
+func (r *MongoRepository) missOrConflict(ctx context.Context, id string, version int64) error {
+	if version == 0 {
 		return example.ErrNotFound
 	}
 
-	return nil
+	count, err := r.collection.CountDocuments(ctx, bson.D{{Key: "name", Value: id}, liveCondition}, mongo_options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return example.ErrNotFound
+	}
+	return example.ErrVersionConflict
 }
 
 func (r *MongoRepository) lazyBindCollection() {
@@ -118,3 +503,100 @@ func (r *MongoRepository) lazyBindCollection() {
 		r.collection = r.Client.Database(path.Base(useUrl.Path)).Collection(MONGO_COLLECTION)
 	}
 }
+
+var mongoOperators = map[example.Operator]string{
+	example.OpEq:  "$eq",
+	example.OpNe:  "$ne",
+	example.OpGt:  "$gt",
+	example.OpGte: "$gte",
+	example.OpLt:  "$lt",
+	example.OpLte: "$lte",
+}
+
+// liveCondition matches the documents which are not soft deleted, null also matches the missing field
+var liveCondition = bson.E{Key: "deleted_at", Value: nil}
+
+var deletedCondition = bson.E{Key: "deleted_at", Value: bson.D{{Key: "$ne", Value: nil}}}
+
+func filterDocument(filters []example.Filter, deleted bool) bson.D {
+
+	conditions := make(bson.A, 0, len(filters)+1)
+	if deleted {
+		conditions = append(conditions, bson.D{deletedCondition})
+	} else {
+		conditions = append(conditions, bson.D{liveCondition})
+	}
+	for _, f := range filters {
+		// anchored, case sensitive regexes can use the index on the field
+		if f.Op == example.OpPrefix {
+			conditions = append(conditions, bson.D{{Key: f.Field, Value: bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(f.Value.(string))}}}})
+			continue
+		}
+		conditions = append(conditions, bson.D{{Key: f.Field, Value: bson.D{{Key: mongoOperators[f.Op], Value: f.Value}}}})
+	}
+	return bson.D{{Key: "$and", Value: conditions}}
+}
+
+func sortDocument(sortFields []example.SortField) bson.D {
+	rv := bson.D{}
+	for _, s := range sortFields {
+		direction := 1
+		if s.Desc {
+			direction = -1
+		}
+		rv = append(rv, bson.E{Key: s.Field, Value: direction})
+	}
+	return rv
+}
+
+// keysetDocument matches the documents strictly after the cursor position:
+// (f1 > v1) or (f1 = v1 and f2 > v2) or ...
+func keysetDocument(sortFields []example.SortField, cursor []any) bson.D {
+	alternatives := make(bson.A, 0, len(sortFields))
+	for i, s := range sortFields {
+		branch := bson.D{}
+		for j := 0; j < i; j++ {
+			branch = append(branch, bson.E{Key: sortFields[j].Field, Value: cursor[j]})
+		}
+		op := "$gt"
+		if s.Desc {
+			op = "$lt"
+		}
+		branch = append(branch, bson.E{Key: s.Field, Value: bson.D{{Key: op, Value: cursor[i]}}})
+		alternatives = append(alternatives, branch)
+	}
+	return bson.D{{Key: "$or", Value: alternatives}}
+}
+
+func versionedFilter(id string, version int64) bson.D {
+	rv := bson.D{{Key: "name", Value: id}, liveCondition}
+	if version != 0 {
+		rv = append(rv, bson.E{Key: "version", Value: version})
+	}
+	return rv
+}
+
+// swapFilter matches the document only if unchanged since it was read, documents stored before versioning have no version
+func swapFilter(id string, version int64) bson.D {
+	if version == 0 {
+		return bson.D{{Key: "name", Value: id}, liveCondition, {Key: "version", Value: bson.D{{Key: "$in", Value: bson.A{0, nil}}}}}
+	}
+	return versionedFilter(id, version)
+}
+
+// replacementFields are the fields a full update overwrites, the identity, the version and the deletion mark are excluded
+func replacementFields(e example.Example) (map[string]any, error) {
+	raw, err := bson.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := bson.M{}
+	if err := bson.Unmarshal(raw, &rv); err != nil {
+		return nil, err
+	}
+	delete(rv, "name")
+	delete(rv, "version")
+	delete(rv, "deleted_at")
+	return rv, nil
+}
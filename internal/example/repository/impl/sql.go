@@ -0,0 +1,401 @@
+package example
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/example"
+)
+
+const SQL_TABLE = "examples"
+
+// exampleColumns are the columns mapped by the db tags of example.Example
+var exampleColumns = db.Columns(example.Example{})
+
+type SqlRepository struct {
+	Db      *sql.DB
+	Dialect db.Dialect
+}
+
+func (r *SqlRepository) FindAll(ctx context.Context) ([]example.Example, error) {
+	return r.query(ctx, r.Db, "SELECT "+r.columns()+" FROM "+SQL_TABLE+" WHERE deleted_at IS NULL")
+}
+
+func (r *SqlRepository) Find(ctx context.Context, query example.Query) (example.Page, error) {
+
+	sortFields := query.EffectiveSort()
+	where, args := whereClause(query.Filters, query.Deleted)
+
+	var total int64
+	err := r.Db.QueryRowContext(ctx, r.Dialect.Rebind("SELECT COUNT(*) FROM "+SQL_TABLE+" WHERE "+where), args...).Scan(&total)
+	if err != nil {
+		return example.Page{}, err
+	}
+
+	limit := query.EffectiveLimit()
+	offset := 0
+	if query.Cursor != "" {
+		cursor, err := example.DecodeCursor(query.Cursor, sortFields)
+		if err != nil {
+			return example.Page{}, err
+		}
+		keyset, keysetArgs := keysetClause(sortFields, cursor)
+		where += " AND " + keyset
+		args = append(args, keysetArgs...)
+	} else if query.Offset > 0 {
+		offset = query.Offset
+	}
+
+	statement := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT %d OFFSET %d",
+		r.columns(), SQL_TABLE, where, orderClause(sortFields), limit+1, offset)
+	items, err := r.query(ctx, r.Db, statement, args...)
+	if err != nil {
+		return example.Page{}, err
+	}
+
+	rv := example.Page{
+		Items: items,
+		Total: total,
+	}
+	if len(items) > limit {
+		rv.Items = items[:limit]
+		rv.NextCursor = example.EncodeCursor(rv.Items[limit-1], sortFields)
+	}
+
+	return rv, nil
+}
+
+func (r *SqlRepository) FindById(ctx context.Context, id string) (example.Example, error) {
+	return r.findOne(ctx, r.Db, id, false)
+}
+
+func (r *SqlRepository) Save(ctx context.Context, e example.Example) error {
+	_, err := r.save(ctx, r.Db, e)
+	return err
+}
+
+func (r *SqlRepository) Update(ctx context.Context, e example.Example) (example.Example, error) {
+	return r.update(ctx, r.Db, e)
+}
+
+func (r *SqlRepository) Delete(ctx context.Context, id string, version int64) error {
+	_, err := r.remove(ctx, r.Db, example.Example{Name: id, Version: version}, time.Now().UTC())
+	return err
+}
+
+func (r *SqlRepository) Restore(ctx context.Context, id string, version int64) (example.Example, error) {
+
+	statement := "UPDATE " + SQL_TABLE + " SET deleted_at = NULL, version = version + 1 WHERE name = ? AND deleted_at IS NOT NULL"
+	args := []any{id}
+	if version != 0 {
+		statement += " AND version = ?"
+		args = append(args, version)
+	}
+
+	rv, err := r.returning(ctx, r.Db, statement, args...)
+	if err == example.ErrNotFound && version != 0 {
+		if _, err := r.findOne(ctx, r.Db, id, true); err != nil {
+			return rv, err
+		}
+		return rv, example.ErrVersionConflict
+	}
+	return rv, err
+}
+
+func (r *SqlRepository) Patch(ctx context.Context, id string, patch example.Patch, version int64) (example.Example, error) {
+
+	for attempt := 0; attempt < patchAttempts; attempt++ {
+		current, err := r.findOne(ctx, r.Db, id, false)
+		if err != nil {
+			return example.Example{}, err
+		}
+		if version != 0 && version != current.Version {
+			return example.Example{}, example.ErrVersionConflict
+		}
+
+		patched, err := patch.Apply(current)
+		if err != nil {
+			return example.Example{}, err
+		}
+
+		rv, err := r.update(ctx, r.Db, patched)
+		if err != example.ErrVersionConflict {
+			return rv, err
+		}
+	}
+
+	return example.Example{}, example.ErrVersionConflict
+}
+
+func (r *SqlRepository) SaveMany(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error) {
+	return r.writeMany(ctx, items, ordered, example.BulkCreated, r.save)
+}
+
+func (r *SqlRepository) UpdateMany(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error) {
+	return r.writeMany(ctx, items, ordered, example.BulkUpdated, r.update)
+}
+
+func (r *SqlRepository) DeleteMany(ctx context.Context, items []example.Example, ordered bool) ([]example.BulkResult, error) {
+	deletedAt := time.Now().UTC()
+	return r.writeMany(ctx, items, ordered, example.BulkDeleted, func(ctx context.Context, q db.Querier, e example.Example) (example.Example, error) {
+		return r.remove(ctx, q, e, deletedAt)
+	})
+}
+
+// writeMany applies the writes in a single transaction, so that the batch is isolated from concurrent requests;
+// the writes never raise constraint errors, which would abort a postgres transaction
+func (r *SqlRepository) writeMany(
+	ctx context.Context,
+	items []example.Example,
+	ordered bool,
+	succeeded example.BulkStatus,
+	write func(ctx context.Context, q db.Querier, e example.Example) (example.Example, error),
+) ([]example.BulkResult, error) {
+
+	rv := example.NewBulkResults(items)
+	if len(items) == 0 {
+		return rv, nil
+	}
+
+	tx, err := r.Db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for i, e := range items {
+		stored, err := write(ctx, tx, e)
+		if err != nil {
+			status, ok := example.BulkStatusOf(err)
+			if !ok {
+				return nil, err
+			}
+			rv[i].Status = status
+			if ordered {
+				break
+			}
+			continue
+		}
+		rv[i].Status = succeeded
+		rv[i].Version = stored.Version
+	}
+
+	return rv, tx.Commit()
+}
+
+// save inserts the example unless its name is taken, soft deleted examples included
+func (r *SqlRepository) save(ctx context.Context, q db.Querier, e example.Example) (example.Example, error) {
+
+	e.Version = 1
+	e.DeletedAt = nil
+	values, err := db.Values(e, exampleColumns...)
+	if err != nil {
+		return example.Example{}, err
+	}
+
+	statement := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (name) DO NOTHING",
+		SQL_TABLE, r.columns(), placeholders(len(exampleColumns)))
+	result, err := q.ExecContext(ctx, r.Dialect.Rebind(statement), values...)
+	if err != nil {
+		return example.Example{}, err
+	}
+	if inserted, err := result.RowsAffected(); err != nil || inserted == 0 {
+		if err != nil {
+			return example.Example{}, err
+		}
+		return example.Example{}, example.ErrAlreadyExists
+	}
+
+	return e, nil
+}
+
+// update replaces the mapped columns of a live example, but its identity, version and deletion mark
+func (r *SqlRepository) update(ctx context.Context, q db.Querier, e example.Example) (example.Example, error) {
+
+	assigned := make([]string, 0, len(exampleColumns))
+	for _, column := range exampleColumns {
+		if column != "name" && column != "version" && column != "deleted_at" {
+			assigned = append(assigned, column)
+		}
+	}
+	args, err := db.Values(e, assigned...)
+	if err != nil {
+		return example.Example{}, err
+	}
+
+	statement := "UPDATE " + SQL_TABLE + " SET version = version + 1"
+	for _, column := range assigned {
+		statement += ", " + column + " = ?"
+	}
+	statement += " WHERE name = ? AND deleted_at IS NULL"
+	args = append(args, e.Name)
+	if e.Version != 0 {
+		statement += " AND version = ?"
+		args = append(args, e.Version)
+	}
+
+	rv, err := r.returning(ctx, q, statement, args...)
+	if err == example.ErrNotFound && e.Version != 0 {
+		return rv, r.missOrConflict(ctx, q, e.Name)
+	}
+	return rv, err
+}
+
+// remove soft deletes the example named after the given one, which only carries the expected version
+func (r *SqlRepository) remove(ctx context.Context, q db.Querier, e example.Example, deletedAt time.Time) (example.Example, error) {
+
+	statement := "UPDATE " + SQL_TABLE + " SET deleted_at = ?, version = version + 1 WHERE name = ? AND deleted_at IS NULL"
+	args := []any{deletedAt, e.Name}
+	if e.Version != 0 {
+		statement += " AND version = ?"
+		args = append(args, e.Version)
+	}
+
+	rv, err := r.returning(ctx, q, statement, args...)
+	if err == example.ErrNotFound && e.Version != 0 {
+		return rv, r.missOrConflict(ctx, q, e.Name)
+	}
+	return rv, err
+}
+
+// missOrConflict explains a versioned write of a live example which matched nothing
+func (r *SqlRepository) missOrConflict(ctx context.Context, q db.Querier, name string) error {
+	if _, err := r.findOne(ctx, q, name, false); err != nil {
+		return err
+	}
+	return example.ErrVersionConflict
+}
+
+func (r *SqlRepository) findOne(ctx context.Context, q db.Querier, name string, deleted bool) (example.Example, error) {
+
+	condition := "deleted_at IS NULL"
+	if deleted {
+		condition = "deleted_at IS NOT NULL"
+	}
+
+	items, err := r.query(ctx, q, "SELECT "+r.columns()+" FROM "+SQL_TABLE+" WHERE name = ? AND "+condition, name)
+	if err != nil {
+		return example.Example{}, err
+	}
+	if len(items) == 0 {
+		return example.Example{}, example.ErrNotFound
+	}
+	return items[0], nil
+}
+
+// returning runs an update of a single example and reads it back, ErrNotFound when nothing matched
+func (r *SqlRepository) returning(ctx context.Context, q db.Querier, statement string, args ...any) (example.Example, error) {
+
+	items, err := r.query(ctx, q, statement+" RETURNING "+r.columns(), args...)
+	if err != nil {
+		return example.Example{}, err
+	}
+	if len(items) == 0 {
+		return example.Example{}, example.ErrNotFound
+	}
+	return items[0], nil
+}
+
+func (r *SqlRepository) query(ctx context.Context, q db.Querier, statement string, args ...any) ([]example.Example, error) {
+
+	rows, err := q.QueryContext(ctx, r.Dialect.Rebind(statement), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]example.Example, 0)
+	for rows.Next() {
+		e := example.Example{}
+		targets, err := db.Targets(&e, exampleColumns...)
+		if err != nil {
+			return nil, err
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return nil, err
+		}
+		rv = append(rv, e)
+	}
+
+	return rv, rows.Err()
+}
+
+func (r *SqlRepository) columns() string {
+	return strings.Join(exampleColumns, ", ")
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+var sqlOperators = map[example.Operator]string{
+	example.OpEq:  "=",
+	example.OpNe:  "<>",
+	example.OpGt:  ">",
+	example.OpGte: ">=",
+	example.OpLt:  "<",
+	example.OpLte: "<=",
+}
+
+// whereClause translates the filters, whose fields are the db tags listed by example.Fields
+func whereClause(filters []example.Filter, deleted bool) (string, []any) {
+
+	conditions := []string{"deleted_at IS NULL"}
+	if deleted {
+		conditions[0] = "deleted_at IS NOT NULL"
+	}
+
+	args := make([]any, 0, len(filters))
+	for _, f := range filters {
+		if f.Op == example.OpPrefix {
+			// LIKE is case insensitive in sqlite, prefixes are case sensitive
+			prefix := f.Value.(string)
+			conditions = append(conditions, fmt.Sprintf("substr(%s, 1, ?) = ?", f.Field))
+			args = append(args, utf8.RuneCountInString(prefix), prefix)
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s ?", f.Field, sqlOperators[f.Op]))
+		args = append(args, f.Value)
+	}
+
+	return strings.Join(conditions, " AND "), args
+}
+
+func orderClause(sortFields []example.SortField) string {
+	rv := make([]string, len(sortFields))
+	for i, s := range sortFields {
+		rv[i] = s.Field + " ASC"
+		if s.Desc {
+			rv[i] = s.Field + " DESC"
+		}
+	}
+	return strings.Join(rv, ", ")
+}
+
+// keysetClause selects the rows after the cursor: (f1 > c1) OR (f1 = c1 AND f2 > c2) OR ...
+func keysetClause(sortFields []example.SortField, cursor []any) (string, []any) {
+
+	alternatives := make([]string, 0, len(sortFields))
+	args := make([]any, 0)
+	for i, s := range sortFields {
+		conditions := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			conditions = append(conditions, sortFields[j].Field+" = ?")
+			args = append(args, cursor[j])
+		}
+		op := ">"
+		if s.Desc {
+			op = "<"
+		}
+		conditions = append(conditions, s.Field+" "+op+" ?")
+		args = append(args, cursor[i])
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
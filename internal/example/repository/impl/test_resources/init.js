@@ -7,8 +7,11 @@ db.createUser({
 
 db.createCollection('examples', {});
 db.createCollection('sessions', {});
+db.createCollection('apikeys', {});
 
 db.examples.createIndex({ name: 1 }, { unique: true });
+db.examples.createIndex({ age: 1, name: 1 });
+db.apikeys.createIndex({ id: 1 }, { unique: true });
 
 db.examples.insertOne({
     name: 'test',
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/morphy76/g-fe-server/internal/outbox"
+	audit_model "github.com/morphy76/g-fe-server/pkg/audit"
+	model "github.com/morphy76/g-fe-server/pkg/example"
+	outbox_model "github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+// outboxRepository appends an event to the outbox for every successful write, in the transaction of the write;
+// a failure to append fails the write, so that no change goes unpublished
+type outboxRepository struct {
+	delegate   model.Repository
+	outbox     outbox_model.Repository
+	transactor outbox_model.Transactor
+}
+
+func newOutboxRepository(delegate model.Repository, outbox outbox_model.Repository, transactor outbox_model.Transactor) model.Repository {
+	return &outboxRepository{
+		delegate:   delegate,
+		outbox:     outbox,
+		transactor: transactor,
+	}
+}
+
+func (r *outboxRepository) FindAll(ctx context.Context) ([]model.Example, error) {
+	return r.delegate.FindAll(ctx)
+}
+
+func (r *outboxRepository) Find(ctx context.Context, query model.Query) (model.Page, error) {
+	return r.delegate.Find(ctx, query)
+}
+
+func (r *outboxRepository) FindById(ctx context.Context, id string) (model.Example, error) {
+	return r.delegate.FindById(ctx, id)
+}
+
+func (r *outboxRepository) Save(ctx context.Context, e model.Example) error {
+	return r.transactor(ctx, func(ctx context.Context) error {
+		if err := r.delegate.Save(ctx, e); err != nil {
+			return err
+		}
+		e.Version = 1
+		return r.enqueue(ctx, e.Name, audit_model.OpCreate, &e)
+	})
+}
+
+func (r *outboxRepository) Update(ctx context.Context, e model.Example) (model.Example, error) {
+	var rv model.Example
+	err := r.transactor(ctx, func(ctx context.Context) error {
+		var err error
+		rv, err = r.delegate.Update(ctx, e)
+		if err != nil {
+			return err
+		}
+		return r.enqueue(ctx, rv.Name, audit_model.OpUpdate, &rv)
+	})
+	return rv, err
+}
+
+func (r *outboxRepository) Delete(ctx context.Context, id string, version int64) error {
+	return r.transactor(ctx, func(ctx context.Context) error {
+		if err := r.delegate.Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return r.enqueue(ctx, id, audit_model.OpDelete, nil)
+	})
+}
+
+func (r *outboxRepository) Restore(ctx context.Context, id string, version int64) (model.Example, error) {
+	var rv model.Example
+	err := r.transactor(ctx, func(ctx context.Context) error {
+		var err error
+		rv, err = r.delegate.Restore(ctx, id, version)
+		if err != nil {
+			return err
+		}
+		return r.enqueue(ctx, id, audit_model.OpRestore, &rv)
+	})
+	return rv, err
+}
+
+func (r *outboxRepository) Patch(ctx context.Context, id string, patch model.Patch, version int64) (model.Example, error) {
+	var rv model.Example
+	err := r.transactor(ctx, func(ctx context.Context) error {
+		var err error
+		rv, err = r.delegate.Patch(ctx, id, patch, version)
+		if err != nil {
+			return err
+		}
+		return r.enqueue(ctx, id, audit_model.OpPatch, &rv)
+	})
+	return rv, err
+}
+
+// SaveMany creates the examples one transaction at a time: a duplicated name aborts a MongoDB transaction, so a
+// single one would lose the examples created before the conflict
+func (r *outboxRepository) SaveMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+
+	rv := model.NewBulkResults(items)
+	for i, e := range items {
+		if err := r.Save(ctx, e); err != nil {
+			status, ok := model.BulkStatusOf(err)
+			if !ok {
+				return nil, err
+			}
+			rv[i].Status = status
+			if ordered {
+				break
+			}
+			continue
+		}
+		rv[i].Status = model.BulkCreated
+		rv[i].Version = 1
+	}
+	return rv, nil
+}
+
+func (r *outboxRepository) UpdateMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	var rv []model.BulkResult
+	err := r.transactor(ctx, func(ctx context.Context) error {
+		var err error
+		rv, err = r.delegate.UpdateMany(ctx, items, ordered)
+		if err != nil {
+			return err
+		}
+		return r.enqueueMany(ctx, items, rv, audit_model.OpUpdate)
+	})
+	return rv, err
+}
+
+func (r *outboxRepository) DeleteMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	var rv []model.BulkResult
+	err := r.transactor(ctx, func(ctx context.Context) error {
+		var err error
+		rv, err = r.delegate.DeleteMany(ctx, items, ordered)
+		if err != nil {
+			return err
+		}
+		return r.enqueueMany(ctx, items, rv, audit_model.OpDelete)
+	})
+	return rv, err
+}
+
+func (r *outboxRepository) enqueue(ctx context.Context, id string, operation audit_model.Operation, after *model.Example) error {
+
+	var payload any
+	var version int64
+	if after != nil {
+		payload = after
+		version = after.Version
+	}
+
+	event, err := outbox.NewEvent(ctx, AUDIT_ENTITY, id, string(operation), version, payload)
+	if err != nil {
+		return err
+	}
+	return r.outbox.Append(ctx, event)
+}
+
+// enqueueMany appends the events of the succeeded items of a bulk write, the payload of updates is the item as
+// it was written
+func (r *outboxRepository) enqueueMany(ctx context.Context, items []model.Example, results []model.BulkResult, operation audit_model.Operation) error {
+
+	events := make([]outbox_model.Event, 0, len(results))
+	for _, result := range results {
+		if !result.Succeeded() {
+			continue
+		}
+
+		var payload any
+		if operation != audit_model.OpDelete {
+			after := items[result.Index]
+			after.Version = result.Version
+			payload = after
+		}
+
+		event, err := outbox.NewEvent(ctx, AUDIT_ENTITY, result.Name, string(operation), result.Version, payload)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+	}
+	return r.outbox.Append(ctx, events...)
+}
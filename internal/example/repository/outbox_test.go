@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	example "github.com/morphy76/g-fe-server/internal/example/repository/impl"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	outbox "github.com/morphy76/g-fe-server/internal/outbox/repository/impl"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	model "github.com/morphy76/g-fe-server/pkg/example"
+	outbox_model "github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+// failingOutbox fails every append, as a MongoDB transaction would abort
+type failingOutbox struct {
+	outbox_model.Repository
+}
+
+func (o failingOutbox) Append(ctx context.Context, events ...outbox_model.Event) error {
+	return errors.New("outbox unavailable")
+}
+
+func TestOutboxSuite(t *testing.T) {
+	t.Log("Test Outbox Suite")
+
+	ctx := app_http.InjectOwnership(context.Background(), serve.Ownership{Tenant: "outboxed"})
+
+	t.Run("Test Events", func(t *testing.T) {
+		t.Log("Test Outbox Events")
+
+		memoryClient := db.NewMemoryDbClient()
+		outboxRepository, _ := outbox.NewMemoryRepository(memoryClient)
+		exampleRepository, _ := example.NewMemoryRepository(memoryClient)
+		repo := newOutboxRepository(exampleRepository, outboxRepository, outbox.MemoryTransactor)
+
+		if err := repo.Save(ctx, model.Example{Name: "Outboxed", Age: 1}); err != nil {
+			t.Fatalf("Error on Save: %s", err)
+		}
+		if _, err := repo.Update(ctx, model.Example{Name: "Outboxed", Age: 2, Version: 1}); err != nil {
+			t.Fatalf("Error on Update: %s", err)
+		}
+		if _, err := repo.Update(ctx, model.Example{Name: "Outboxed", Age: 3, Version: 1}); !model.IsVersionConflict(err) {
+			t.Fatalf("Expected ErrVersionConflict, got %v", err)
+		}
+		if err := repo.Delete(ctx, "Outboxed", 0); err != nil {
+			t.Fatalf("Error on Delete: %s", err)
+		}
+		results, err := repo.SaveMany(ctx, []model.Example{{Name: "First", Age: 1}, {Name: "First", Age: 2}, {Name: "Second", Age: 3}}, false)
+		if err != nil {
+			t.Fatalf("Error on SaveMany: %s", err)
+		}
+		if results[0].Status != model.BulkCreated || results[1].Status != model.BulkConflict || results[2].Status != model.BulkCreated {
+			t.Fatalf("Expected the duplicate only to conflict, got %v", results)
+		}
+
+		events, err := outboxRepository.Claim(ctx, time.Now().Add(time.Second), time.Minute, 100)
+		if err != nil {
+			t.Fatalf("Error on Claim: %s", err)
+		}
+
+		expected := []string{"example.create", "example.update", "example.delete", "example.create", "example.create"}
+		if len(events) != len(expected) {
+			t.Fatalf("Expected %d events, got %#v", len(expected), events)
+		}
+		for i, e := range events {
+			if e.Type != expected[i] || e.Tenant != "outboxed" {
+				t.Errorf("Unexpected event %d: %#v", i, e)
+			}
+		}
+		if events[1].Version != 2 || string(events[1].Payload) != `{"name":"Outboxed","age":2,"version":2}` {
+			t.Errorf("Expected the updated example as payload, got %d %s", events[1].Version, events[1].Payload)
+		}
+		if len(events[2].Payload) != 0 {
+			t.Errorf("Expected no payload on delete, got %s", events[2].Payload)
+		}
+	})
+
+	t.Run("Test Failing Outbox", func(t *testing.T) {
+		t.Log("Test Outbox Failing Outbox")
+
+		memoryClient := db.NewMemoryDbClient()
+		exampleRepository, _ := example.NewMemoryRepository(memoryClient)
+		repo := newOutboxRepository(exampleRepository, failingOutbox{}, outbox.MemoryTransactor)
+
+		if err := repo.Save(ctx, model.Example{Name: "Unpublished", Age: 1}); err == nil {
+			t.Fatal("Expected the write to fail with the outbox")
+		}
+	})
+}
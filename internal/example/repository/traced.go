@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/internal/validation"
+	model "github.com/morphy76/g-fe-server/pkg/example"
+)
+
+const tracerName = "github.com/morphy76/g-fe-server/internal/example/repository"
+
+type tracedRepository struct {
+	delegate   model.Repository
+	system     string
+	collection string
+}
+
+// newTracedRepository traces the calls to the collection, or table, of the examples in the given system
+func newTracedRepository(delegate model.Repository, system string, collection string) model.Repository {
+	return &tracedRepository{
+		delegate:   delegate,
+		system:     system,
+		collection: collection,
+	}
+}
+
+func (r *tracedRepository) FindAll(ctx context.Context) ([]model.Example, error) {
+	ctx, span := r.start(ctx, "FindAll")
+	rv, err := r.delegate.FindAll(ctx)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) Find(ctx context.Context, query model.Query) (model.Page, error) {
+	ctx, span := r.start(ctx, "Find")
+	span.SetAttributes(attribute.Int("db.query.limit", query.EffectiveLimit()))
+	rv, err := r.delegate.Find(ctx, query)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) FindById(ctx context.Context, id string) (model.Example, error) {
+	ctx, span := r.start(ctx, "FindById")
+	rv, err := r.delegate.FindById(ctx, id)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) Save(ctx context.Context, e model.Example) error {
+	ctx, span := r.start(ctx, "Save")
+	err := r.delegate.Save(ctx, e)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) Update(ctx context.Context, e model.Example) (model.Example, error) {
+	ctx, span := r.start(ctx, "Update")
+	rv, err := r.delegate.Update(ctx, e)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) Delete(ctx context.Context, id string, version int64) error {
+	ctx, span := r.start(ctx, "Delete")
+	err := r.delegate.Delete(ctx, id, version)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) Restore(ctx context.Context, id string, version int64) (model.Example, error) {
+	ctx, span := r.start(ctx, "Restore")
+	rv, err := r.delegate.Restore(ctx, id, version)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) Patch(ctx context.Context, id string, patch model.Patch, version int64) (model.Example, error) {
+	ctx, span := r.start(ctx, "Patch")
+	rv, err := r.delegate.Patch(ctx, id, patch, version)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) SaveMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	ctx, span := r.startBulk(ctx, "SaveMany", len(items), ordered)
+	rv, err := r.delegate.SaveMany(ctx, items, ordered)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) UpdateMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	ctx, span := r.startBulk(ctx, "UpdateMany", len(items), ordered)
+	rv, err := r.delegate.UpdateMany(ctx, items, ordered)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) DeleteMany(ctx context.Context, items []model.Example, ordered bool) ([]model.BulkResult, error) {
+	ctx, span := r.startBulk(ctx, "DeleteMany", len(items), ordered)
+	rv, err := r.delegate.DeleteMany(ctx, items, ordered)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) startBulk(ctx context.Context, operation string, size int, ordered bool) (context.Context, trace.Span) {
+	ctx, span := r.start(ctx, operation)
+	span.SetAttributes(
+		attribute.Int("db.operation.batch.size", size),
+		attribute.Bool("db.operation.batch.ordered", ordered),
+	)
+	return ctx, span
+}
+
+func (r *tracedRepository) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "example."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", r.system),
+			attribute.String("db.collection.name", r.collection),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !isOutcome(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// isOutcome tells the domain answers of the storage apart from its failures
+func isOutcome(err error) bool {
+	if _, invalid := validation.IsInvalid(err); invalid {
+		return true
+	}
+
+	switch err {
+	case model.ErrNotFound,
+		model.ErrAlreadyExists,
+		model.ErrInvalidQuery,
+		model.ErrVersionConflict,
+		model.ErrInvalidPatch,
+		model.ErrPatchTestFailed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,34 @@
+package http
+
+type SessionStatus struct {
+	Authenticated bool   `json:"authenticated"`
+	ExpiresAt     string `json:"expires_at,omitempty"`
+	ExpiresIn     int64  `json:"expires_in,omitempty"`
+	RefreshURL    string `json:"refresh_url,omitempty"`
+	LoginURL      string `json:"login_url,omitempty"`
+	LogoutURL     string `json:"logout_url,omitempty"`
+}
+
+type PrincipalKind string
+
+const (
+	PrincipalClient PrincipalKind = "client"
+	PrincipalApiKey PrincipalKind = "api_key"
+	PrincipalUser   PrincipalKind = "user"
+)
+
+type Principal struct {
+	Kind    PrincipalKind
+	Subject string
+	Tenant  string
+	Scopes  []string
+}
+
+func (p Principal) HasScope(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -14,22 +14,28 @@ import (
 type ContextModelKey string
 type ContextSessionKey string
 type ContextSessionStoreKey string
+type ContextSessionIndexKey string
 type ContextLoggerKey string
 type ContextOwnershipKey string
 type ContextOIDCOptions string
 type ContextOIDCKey string
 type ContextOIDCResourceKey string
+type ContextOIDCNonceKey string
+type ContextPrincipalKey string
 type RouteChannelKey string
 
 const (
 	ctx_CONTEXT_SERVE_KEY ContextModelKey        = "contextModel"
 	ctx_SESSION_STORE_KEY ContextSessionStoreKey = "sessionStore"
+	ctx_SESSION_INDEX_KEY ContextSessionIndexKey = "sessionIndex"
 	ctx_SESSION_KEY       ContextSessionKey      = "session"
 	ctx_LOGGER_KEY        ContextLoggerKey       = "logger"
 	ctx_OWNERSHIP_KEY     ContextOwnershipKey    = "ownership"
 	ctx_OIDC_OPTIONS_KEY  ContextOIDCOptions     = "oidcOptions"
 	ctx_OIDC_KEY          ContextOIDCKey         = "oidc"
 	ctx_OIDC_RESOURCE_KEY ContextOIDCResourceKey = "oidcResource"
+	ctx_OIDC_NONCE_KEY    ContextOIDCNonceKey    = "oidcNonce"
+	ctx_PRINCIPAL_KEY     ContextPrincipalKey    = "principal"
 	ctx_ROUTE_CHANNEL_KEY RouteChannelKey        = "routeChannel"
 )
 
@@ -49,6 +55,15 @@ func ExtractOidcResource(ctx context.Context) rs.ResourceServer {
 	return ctx.Value(ctx_OIDC_RESOURCE_KEY).(rs.ResourceServer)
 }
 
+func InjectOidcNonce(ctx context.Context, nonce string) context.Context {
+	return context.WithValue(ctx, ctx_OIDC_NONCE_KEY, nonce)
+}
+
+func ExtractOidcNonce(ctx context.Context) string {
+	nonce, _ := ctx.Value(ctx_OIDC_NONCE_KEY).(string)
+	return nonce
+}
+
 func ExtractServeOptions(ctx context.Context) *options.ServeOptions {
 	return ctx.Value(ctx_CONTEXT_SERVE_KEY).(*options.ServeOptions)
 }
@@ -69,6 +84,11 @@ func ExtractOwnership(ctx context.Context) serve.Ownership {
 	return ctx.Value(ctx_OWNERSHIP_KEY).(serve.Ownership)
 }
 
+func LookupOwnership(ctx context.Context) (serve.Ownership, bool) {
+	ownership, ok := ctx.Value(ctx_OWNERSHIP_KEY).(serve.Ownership)
+	return ownership, ok
+}
+
 func InjectOwnership(ctx context.Context, ownership serve.Ownership) context.Context {
 	return context.WithValue(ctx, ctx_OWNERSHIP_KEY, ownership)
 }
@@ -81,10 +101,23 @@ func InjectSessionStore(ctx context.Context, store sessions.Store) context.Conte
 	return context.WithValue(ctx, ctx_SESSION_STORE_KEY, store)
 }
 
+func ExtractSessionIndex(ctx context.Context) *serve.SessionIndex {
+	return ctx.Value(ctx_SESSION_INDEX_KEY).(*serve.SessionIndex)
+}
+
+func InjectSessionIndex(ctx context.Context, index *serve.SessionIndex) context.Context {
+	return context.WithValue(ctx, ctx_SESSION_INDEX_KEY, index)
+}
+
 func ExtractSession(ctx context.Context) *sessions.Session {
 	return ctx.Value(ctx_SESSION_KEY).(*sessions.Session)
 }
 
+func LookupSession(ctx context.Context) (*sessions.Session, bool) {
+	session, ok := ctx.Value(ctx_SESSION_KEY).(*sessions.Session)
+	return session, ok
+}
+
 func InjectSession(ctx context.Context, session *sessions.Session) context.Context {
 	return context.WithValue(ctx, ctx_SESSION_KEY, session)
 }
@@ -96,3 +129,12 @@ func ExtractRelyingParty(ctx context.Context) rp.RelyingParty {
 func InjectRelyingParty(ctx context.Context, relyingParty rp.RelyingParty) context.Context {
 	return context.WithValue(ctx, ctx_OIDC_KEY, relyingParty)
 }
+
+func ExtractPrincipal(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(ctx_PRINCIPAL_KEY).(Principal)
+	return principal, ok
+}
+
+func InjectPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, ctx_PRINCIPAL_KEY, principal)
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func CollectClaims(idTokenClaims *oidc.IDTokenClaims, info *oidc.UserInfo) map[string]any {
+
+	rv := make(map[string]any)
+	mergeClaims(rv, idTokenClaims)
+	mergeClaims(rv, info)
+
+	return rv
+}
+
+func ResolveProfile(claims map[string]any, mapping map[string]string) map[string]any {
+
+	rv := make(map[string]any, len(mapping))
+	for target, claimPath := range mapping {
+		if value, found := lookupClaim(claims, claimPath); found {
+			rv[target] = value
+		}
+	}
+
+	return rv
+}
+
+func mergeClaims(into map[string]any, source any) {
+
+	if source == nil {
+		return
+	}
+
+	raw, err := json.Marshal(source)
+	if err != nil {
+		return
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		return
+	}
+
+	for k, v := range claims {
+		into[k] = v
+	}
+}
+
+func lookupClaim(claims map[string]any, claimPath string) (any, bool) {
+
+	var current any = claims
+	for _, segment := range strings.Split(claimPath, ".") {
+		node, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = node[segment]
+		if !ok || current == nil {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
@@ -2,38 +2,85 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
 	"github.com/zitadel/oidc/v3/pkg/client/rp"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 
 	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/http/middleware"
 )
 
 func IAMHandlers(authRouter *mux.Router, ctxRoot string, relyingParty rp.RelyingParty) {
 	authRouter.HandleFunc("/login", onLogin(ctxRoot, relyingParty)).Methods("GET").Name("GET " + ctxRoot + "/auth/login")
-	authRouter.HandleFunc("/callback", rp.CodeExchangeHandler(rp.UserinfoCallback(marshalUserinfo), relyingParty)).Name("GET " + ctxRoot + "/auth/callback")
+	authRouter.HandleFunc("/callback", onCallback(relyingParty)).Name("GET " + ctxRoot + "/auth/callback")
 	authRouter.HandleFunc("/logout", onLogout()).Name("GET " + ctxRoot + "/auth/logout")
 	authRouter.HandleFunc("/info", onInfo(ctxRoot)).Name("GET " + ctxRoot + "/auth/info")
+	authRouter.HandleFunc("/session", onSession(ctxRoot)).Methods("GET").Name("GET " + ctxRoot + "/auth/session")
+	authRouter.HandleFunc("/refresh", onRefresh(ctxRoot, relyingParty)).Methods("POST").Name("POST " + ctxRoot + "/auth/refresh")
+	SessionHandlers(authRouter, ctxRoot)
 }
 
 func onLogin(ctxRoot string, relyingParty rp.RelyingParty) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		requested_url, err := url.QueryUnescape(r.URL.Query().Get("requested_url"))
+		session := app_http.ExtractSession(r.Context())
+		logger := app_http.ExtractLogger(r.Context(), "auth")
+
+		requested_url := SafeRedirect(ctxRoot, r.URL.Query().Get("requested_url"))
+
+		state, err := randomToken()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to generate state")
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to generate state")
+			return
+		}
+		nonce, err := randomToken()
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to generate nonce")
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to generate nonce")
+			return
+		}
+
+		session.Values["oidc_state"] = state
+		session.Values["oidc_nonce"] = nonce
+		session.Values["oidc_requested_url"] = requested_url
+		err = session.Save(r, w)
 		if err != nil {
-			requested_url = ctxRoot + "/ui"
+			logger.Error().Err(err).Msg("Failed to save session")
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to save session")
+			return
 		}
 
 		stateFn := func() string {
-			return requested_url
+			return state
 		}
 
-		rp.AuthURLHandler(stateFn, relyingParty)(w, r)
+		rp.AuthURLHandler(stateFn, relyingParty, rp.WithURLParam("nonce", nonce))(w, r)
+	}
+}
+
+func onCallback(relyingParty rp.RelyingParty) http.HandlerFunc {
+
+	codeExchange := rp.CodeExchangeHandler(rp.UserinfoCallback(marshalUserinfo), relyingParty)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		session := app_http.ExtractSession(r.Context())
+
+		nonce, _ := session.Values["oidc_nonce"].(string)
+		codeExchange(w, r.WithContext(app_http.InjectOidcNonce(r.Context(), nonce)))
 	}
 }
 
@@ -72,15 +119,16 @@ func onLogout() http.HandlerFunc {
 			return
 		}
 
-		sessionState := session.Values["session_state"].(string)
+		sessionState, _ := session.Values["session_state"].(string)
 
+		app_http.ExtractSessionIndex(r.Context()).Remove(session.ID)
 		session.Options.MaxAge = -1
 		delete(session.Values, "id_token")
 		session.Save(r, w)
 		url, err := rp.EndSession(context.Background(), useRelyingParty, idToken.(string), backTo, sessionState)
 		if err != nil {
 			logger.Error().Err(err).Msg("End session failed")
-			http.Error(w, "End session failed", http.StatusInternalServerError)
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "End session failed")
 			return
 		}
 		logger.Trace().
@@ -102,22 +150,24 @@ func onInfo(ctxRoot string) http.HandlerFunc {
 
 		idToken := session.Values["id_token"]
 		if idToken == nil {
-			http.Error(w, "Auth session not found", http.StatusUnauthorized)
+			app_http.RespondProblem(w, r, http.StatusUnauthorized, "Auth session not found")
 			return
 		}
 
-		rv := &map[string]string{
-			"email":              session.Values["email"].(string),
-			"family_name":        session.Values["family_name"].(string),
-			"given_name":         session.Values["given_name"].(string),
-			"name":               session.Values["name"].(string),
-			"preferred_username": session.Values["preferred_username"].(string),
-			"logout_url":         ctxRoot + "/auth/logout",
+		rv := make(map[string]any)
+		if profile, ok := session.Values["profile"].(string); ok {
+			if err := json.Unmarshal([]byte(profile), &rv); err != nil {
+				logger.Warn().Err(err).Msg("Failed to read profile from session")
+			}
 		}
+		csrfToken, _ := session.Values["csrf_token"].(string)
+		rv["logout_url"] = ctxRoot + "/auth/logout"
+		rv["csrf_token"] = csrfToken
+
 		responseBody, err := json.Marshal(rv)
 		if err != nil {
 			logger.Error().Err(err).Msg("Failed to marshal response")
-			http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to marshal response")
 			return
 		}
 
@@ -127,23 +177,155 @@ func onInfo(ctxRoot string) http.HandlerFunc {
 	}
 }
 
+func onSession(ctxRoot string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		session := app_http.ExtractSession(r.Context())
+		logger := app_http.ExtractLogger(r.Context(), "auth")
+
+		logger.Trace().Msg("Session status requested")
+
+		idToken, _ := session.Values["id_token"].(string)
+		if idToken == "" {
+			middleware.RespondUnauthorized(w, r, ctxRoot)
+			return
+		}
+
+		writeSessionStatus(w, session, ctxRoot)
+	}
+}
+
+func onRefresh(ctxRoot string, relyingParty rp.RelyingParty) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		session := app_http.ExtractSession(r.Context())
+		logger := app_http.ExtractLogger(r.Context(), "auth")
+
+		logger.Trace().Msg("Session refresh requested")
+
+		err := middleware.RenewSessionTokens(r.Context(), session, relyingParty)
+		if err != nil {
+			if middleware.IsMissingTokens(err) {
+				logger.Trace().Msg("No tokens to refresh")
+			} else {
+				logger.Warn().Err(err).Msg("Failed to refresh tokens")
+			}
+			middleware.RespondUnauthorized(w, r, ctxRoot)
+			return
+		}
+
+		err = session.Save(r, w)
+		if err != nil {
+			logger.Error().Err(err).Msg("Failed to save session")
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to save session")
+			return
+		}
+		logger.Trace().Msg("Auth session refreshed")
+
+		writeSessionStatus(w, session, ctxRoot)
+	}
+}
+
+func writeSessionStatus(w http.ResponseWriter, session *sessions.Session, ctxRoot string) {
+
+	rv := &app_http.SessionStatus{
+		Authenticated: true,
+		RefreshURL:    ctxRoot + "/auth/refresh",
+		LoginURL:      ctxRoot + "/auth/login",
+		LogoutURL:     ctxRoot + "/auth/logout",
+	}
+	if expiry, ok := session.Values["token_expiry"].(int64); ok {
+		expiresAt := time.Unix(expiry, 0)
+		rv.ExpiresAt = expiresAt.UTC().Format(time.RFC3339)
+		rv.ExpiresIn = int64(time.Until(expiresAt).Seconds())
+		if rv.ExpiresIn < 0 {
+			rv.ExpiresIn = 0
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rv)
+}
+
 func marshalUserinfo(w http.ResponseWriter, r *http.Request, tokens *oidc.Tokens[*oidc.IDTokenClaims], state string, rp rp.RelyingParty, info *oidc.UserInfo) {
 
 	session := app_http.ExtractSession(r.Context())
 	logger := app_http.ExtractLogger(r.Context(), "auth")
+	serveOptions := app_http.ExtractServeOptions(r.Context())
 
-	session.Values["access_token"] = tokens.AccessToken
-	session.Values["refresh_token"] = tokens.RefreshToken
-	session.Values["id_token"] = tokens.IDToken
-	session.Values["session_state"] = tokens.IDTokenClaims.Claims["session_state"]
-	session.Values["email"] = info.Email
-	session.Values["family_name"] = info.FamilyName
-	session.Values["given_name"] = info.GivenName
-	session.Values["name"] = info.Name
-	session.Values["preferred_username"] = info.PreferredUsername
+	expectedState, _ := session.Values["oidc_state"].(string)
+	if expectedState == "" || subtle.ConstantTimeCompare([]byte(expectedState), []byte(state)) != 1 {
+		logger.Warn().Msg("Invalid login state")
+		app_http.RespondProblem(w, r, http.StatusUnauthorized, "Invalid login state")
+		return
+	}
+	requested_url, _ := session.Values["oidc_requested_url"].(string)
+	requested_url = SafeRedirect(serveOptions.ContextRoot, requested_url)
+	delete(session.Values, "oidc_state")
+	delete(session.Values, "oidc_requested_url")
+
+	oidcOptions := app_http.ExtractOidcOptions(r.Context())
+	claims := CollectClaims(tokens.IDTokenClaims, info)
+	profile, err := json.Marshal(ResolveProfile(claims, oidcOptions.ProfileClaims))
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to marshal profile")
+		app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to marshal profile")
+		return
+	}
+
+	middleware.StoreSessionTokens(session, tokens.Token, tokens.IDToken)
+	if sessionState, ok := claims["session_state"].(string); ok {
+		session.Values["session_state"] = sessionState
+	}
+	session.Values["profile"] = string(profile)
+	session.Values["subject"] = tokens.IDTokenClaims.Subject
+	session.Values["login_at"] = time.Now().Unix()
 
 	session.Save(r, w)
+	middleware.TrackSession(r, session.ID, tokens.IDTokenClaims.Subject, session.Values["login_at"])
 	logger.Trace().Msg("Auth session saved")
 
-	http.Redirect(w, r, state, http.StatusFound)
+	http.Redirect(w, r, requested_url, http.StatusFound)
+}
+
+func SafeRedirect(ctxRoot string, requested string) string {
+
+	fallback := ctxRoot + "/ui/"
+
+	if requested == "" || strings.ContainsAny(requested, "\\\r\n") {
+		return fallback
+	}
+
+	target, err := url.Parse(requested)
+	if err != nil || target.Scheme != "" || target.Host != "" || target.User != nil || target.Opaque != "" {
+		return fallback
+	}
+	if !strings.HasPrefix(target.Path, "/") {
+		return fallback
+	}
+
+	cleaned := path.Clean(target.Path)
+	if cleaned != ctxRoot && !strings.HasPrefix(cleaned, ctxRoot+"/") {
+		return fallback
+	}
+	if strings.HasSuffix(target.Path, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	rv := url.URL{
+		Path:     cleaned,
+		RawQuery: target.RawQuery,
+		Fragment: target.Fragment,
+	}
+	return rv.String()
+}
+
+func randomToken() (string, error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
 }
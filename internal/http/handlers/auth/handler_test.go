@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+)
+
+func TestAuthHandlerSuite(t *testing.T) {
+	t.Log("Test Auth Handler Suite")
+
+	t.Run("Test SafeRedirect", func(t *testing.T) {
+		t.Log("Test Auth SafeRedirect")
+
+		cases := map[string]string{
+			"":                          "/fe/ui/",
+			"/fe/ui/":                   "/fe/ui/",
+			"/fe/ui/examples?id=1":      "/fe/ui/examples?id=1",
+			"/fe":                       "/fe",
+			"/fe/ui/../../evil":         "/fe/ui/",
+			"/feevil":                   "/fe/ui/",
+			"https://evil.com/fe/ui/":   "/fe/ui/",
+			"//evil.com/fe/ui/":         "/fe/ui/",
+			"/\\evil.com":               "/fe/ui/",
+			"fe/ui/":                    "/fe/ui/",
+			"javascript:alert(1)":       "/fe/ui/",
+			"/fe/ui/%0d%0aSet-Cookie:x": "/fe/ui/%0D%0ASet-Cookie:x",
+		}
+
+		for requested, expected := range cases {
+			if actual := SafeRedirect("/fe", requested); actual != expected {
+				t.Errorf("SafeRedirect(%q): expected %q, got %q", requested, expected, actual)
+			}
+		}
+	})
+
+	t.Run("Test ResolveProfile", func(t *testing.T) {
+		t.Log("Test Auth ResolveProfile")
+
+		idTokenClaims := &oidc.IDTokenClaims{
+			Claims: map[string]any{
+				"realm_access": map[string]any{
+					"roles": []any{"admin", "user"},
+				},
+				"tenant": "id-token-tenant",
+			},
+		}
+		idTokenClaims.Subject = "sub"
+		info := &oidc.UserInfo{
+			Subject: "sub",
+			Claims: map[string]any{
+				"tenant": "userinfo-tenant",
+			},
+		}
+		info.Email = "user@example.com"
+
+		profile := ResolveProfile(CollectClaims(idTokenClaims, info), map[string]string{
+			"email":  "email",
+			"roles":  "realm_access.roles",
+			"tenant": "tenant",
+			"groups": "groups",
+			"deep":   "realm_access.roles.missing",
+		})
+
+		if profile["email"] != "user@example.com" {
+			t.Errorf("Expected email, got %v", profile["email"])
+		}
+		if !reflect.DeepEqual(profile["roles"], []any{"admin", "user"}) {
+			t.Errorf("Expected nested roles, got %v", profile["roles"])
+		}
+		if profile["tenant"] != "userinfo-tenant" {
+			t.Errorf("Expected userinfo to override ID token claims, got %v", profile["tenant"])
+		}
+		if _, found := profile["groups"]; found {
+			t.Errorf("Expected absent claim to be skipped")
+		}
+		if _, found := profile["deep"]; found {
+			t.Errorf("Expected path through a non-object to be skipped")
+		}
+	})
+
+	t.Run("Test hasRole", func(t *testing.T) {
+		t.Log("Test Auth hasRole")
+
+		profile := ResolveProfile(map[string]any{
+			"realm_access": map[string]any{"roles": []any{"admin", "user"}},
+			"role":         "auditor",
+		}, map[string]string{
+			"authorities": "realm_access.roles",
+			"role":        "role",
+		})
+
+		if !hasRole(profile, "authorities", "admin") {
+			t.Errorf("Expected the admin role in the renamed field")
+		}
+		if hasRole(profile, "roles", "admin") {
+			t.Errorf("Expected no roles in the unmapped field")
+		}
+		if !hasRole(profile, "role", "auditor") || hasRole(profile, "role", "admin") {
+			t.Errorf("Expected a single role to be matched as is")
+		}
+	})
+}
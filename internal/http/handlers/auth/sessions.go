@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/serve"
+)
+
+const (
+	pathParamSessionId = "sessionId"
+	pathParamSubject   = "subject"
+)
+
+func SessionHandlers(authRouter *mux.Router, ctxRoot string) {
+	authRouter.HandleFunc("/sessions", onListSessions()).Methods("GET").Name("GET " + ctxRoot + "/auth/sessions")
+	authRouter.HandleFunc("/sessions/{"+pathParamSessionId+"}", onRevokeSession()).Methods("DELETE").Name("DELETE " + ctxRoot + "/auth/sessions/{" + pathParamSessionId + "}")
+	authRouter.HandleFunc("/users/{"+pathParamSubject+"}/sessions", onListUserSessions()).Methods("GET").Name("GET " + ctxRoot + "/auth/users/{" + pathParamSubject + "}/sessions")
+	authRouter.HandleFunc("/users/{"+pathParamSubject+"}/sessions", onRevokeUserSessions()).Methods("DELETE").Name("DELETE " + ctxRoot + "/auth/users/{" + pathParamSubject + "}/sessions")
+}
+
+func onListSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		session := app_http.ExtractSession(r.Context())
+		index := app_http.ExtractSessionIndex(r.Context())
+
+		subject, ok := sessionSubject(w, r, session)
+		if !ok {
+			return
+		}
+
+		writeSessions(w, index.List(subject), session.ID)
+	}
+}
+
+func onRevokeSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		session := app_http.ExtractSession(r.Context())
+		index := app_http.ExtractSessionIndex(r.Context())
+		logger := app_http.ExtractLogger(r.Context(), "auth")
+
+		subject, ok := sessionSubject(w, r, session)
+		if !ok {
+			return
+		}
+
+		handle := mux.Vars(r)[pathParamSessionId]
+		if !index.Revoke(subject, handle) {
+			app_http.RespondProblem(w, r, http.StatusNotFound, "Session not found")
+			return
+		}
+		logger.Info().
+			Str("subject", subject).
+			Str("session", handle).
+			Msg("Session revoked")
+
+		if handle == serve.SessionHandle(session.ID) {
+			index.Remove(session.ID)
+			session.Options.MaxAge = -1
+			session.Save(r, w)
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func onListUserSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		session := app_http.ExtractSession(r.Context())
+		index := app_http.ExtractSessionIndex(r.Context())
+
+		if !requireAdmin(w, r, session) {
+			return
+		}
+
+		writeSessions(w, index.List(mux.Vars(r)[pathParamSubject]), session.ID)
+	}
+}
+
+func onRevokeUserSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		session := app_http.ExtractSession(r.Context())
+		index := app_http.ExtractSessionIndex(r.Context())
+		logger := app_http.ExtractLogger(r.Context(), "auth")
+
+		if !requireAdmin(w, r, session) {
+			return
+		}
+
+		subject := mux.Vars(r)[pathParamSubject]
+		revoked := index.RevokeAll(subject)
+		logger.Info().
+			Str("subject", subject).
+			Int("sessions", revoked).
+			Msg("User sessions revoked")
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func sessionSubject(w http.ResponseWriter, r *http.Request, session *sessions.Session) (string, bool) {
+	subject, _ := session.Values["subject"].(string)
+	if subject == "" {
+		app_http.RespondProblem(w, r, http.StatusUnauthorized, "Auth session not found")
+		return "", false
+	}
+	return subject, true
+}
+
+func requireAdmin(w http.ResponseWriter, r *http.Request, session *sessions.Session) bool {
+
+	if _, ok := sessionSubject(w, r, session); !ok {
+		return false
+	}
+
+	oidcOptions := app_http.ExtractOidcOptions(r.Context())
+	profile := make(map[string]any)
+	if raw, ok := session.Values["profile"].(string); ok {
+		json.Unmarshal([]byte(raw), &profile)
+	}
+
+	if hasRole(profile, oidcOptions.RolesField, oidcOptions.AdminRole) {
+		return true
+	}
+
+	app_http.RespondProblem(w, r, http.StatusForbidden, "Forbidden")
+	return false
+}
+
+// hasRole looks the role up in the profile field the roles claim is mapped to, a list or a single role
+func hasRole(profile map[string]any, field string, role string) bool {
+	switch roles := profile[field].(type) {
+	case []any:
+		for _, candidate := range roles {
+			if candidate == role {
+				return true
+			}
+		}
+	case string:
+		return roles == role
+	}
+	return false
+}
+
+func writeSessions(w http.ResponseWriter, infos []serve.SessionInfo, currentSessionID string) {
+
+	current := serve.SessionHandle(currentSessionID)
+	for i := range infos {
+		infos[i].Current = infos[i].Handle == current
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(infos)
+}
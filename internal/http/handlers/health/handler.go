@@ -45,12 +45,17 @@ func onHealth(additionalChecks []app_http.HealthCheckFn) http.HandlerFunc {
 			}
 		}
 
-		if overallStatus == app_http.Active {
-			w.WriteHeader(http.StatusOK)
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
+		if overallStatus == app_http.Inactive {
+			problem := app_http.NewProblem(r, http.StatusServiceUnavailable, "One or more subsystems are inactive")
+			problem.Extensions = map[string]any{
+				"health_status": overallStatus,
+				"subsystems":    subsystems,
+			}
+			app_http.WriteProblem(w, problem)
+			return
 		}
 
+		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(&app_http.HealthResponse{
 			Status:     overallStatus,
 			SubSystems: subsystems,
@@ -0,0 +1,68 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+func TestHealthSuite(t *testing.T) {
+	t.Log("Test Health Suite")
+
+	appContext := app_http.InjectServeOptions(context.Background(), &options.ServeOptions{ContextRoot: "/be"})
+	status := app_http.Active
+
+	router := mux.NewRouter()
+	HealthHandlers(router, appContext, func(requestContext context.Context) (string, app_http.Status) {
+		return "Database", status
+	})
+
+	call := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		return w
+	}
+
+	t.Run("Test active", func(t *testing.T) {
+		t.Log("Testing active")
+
+		w := call()
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var body app_http.HealthResponse
+		json.NewDecoder(w.Body).Decode(&body)
+		if body.Status != app_http.Active || body.SubSystems["Database"].Status != app_http.Active {
+			t.Fatalf("Expected an active health, got %#v", body)
+		}
+	})
+
+	t.Run("Test inactive", func(t *testing.T) {
+		t.Log("Testing inactive")
+
+		status = app_http.Inactive
+		w := call()
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+		}
+		var body struct {
+			Status       int                                `json:"status"`
+			HealthStatus app_http.Status                    `json:"health_status"`
+			SubSystems   map[string]app_http.HealthResponse `json:"subsystems"`
+		}
+		json.NewDecoder(w.Body).Decode(&body)
+		if body.Status != http.StatusServiceUnavailable || body.HealthStatus != app_http.Inactive {
+			t.Fatalf("Expected the problem status along with the health status, got %#v", body)
+		}
+		if body.SubSystems["Database"].Status != app_http.Inactive {
+			t.Fatalf("Expected the inactive subsystem, got %#v", body.SubSystems)
+		}
+	})
+}
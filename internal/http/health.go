@@ -15,9 +15,4 @@ type HealthResponse struct {
 	SubSystems map[string]HealthResponse `json:"subsystems,omitempty"`
 }
 
-type Error struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
-
 type HealthCheckFn func(requestContext context.Context) (string, Status)
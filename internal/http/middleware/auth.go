@@ -2,18 +2,28 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"path/filepath"
 	"strings"
 
-	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/gorilla/sessions"
 	"github.com/zitadel/oidc/v3/pkg/client/rp"
 	"github.com/zitadel/oidc/v3/pkg/client/rs"
 	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"golang.org/x/oauth2"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
 )
 
+var ErrMissingTokens = errors.New("missing tokens in session")
+
+func IsMissingTokens(err error) bool {
+	return err == ErrMissingTokens
+}
+
 func HttpSessionInspectAndRenew(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -39,20 +49,20 @@ func HttpSessionInspectAndRenew(next http.Handler) http.Handler {
 		access_token := session.Values["access_token"]
 		if access_token == nil {
 			logger.Warn().Msg("No access token found")
-			http.Redirect(w, r, ctxRoot+"/auth/logout", http.StatusTemporaryRedirect)
+			sessionExpired(w, r, ctxRoot)
 			return
 		}
 		refresh_token := session.Values["refresh_token"]
 		if refresh_token == nil {
 			logger.Warn().Msg("No refresh token found")
-			http.Redirect(w, r, ctxRoot+"/auth/logout", http.StatusTemporaryRedirect)
+			sessionExpired(w, r, ctxRoot)
 			return
 		}
 
 		resp, err := rs.Introspect[*oidc.IntrospectionResponse](context.Background(), resourceServer, access_token.(string))
 		if err != nil {
 			logger.Warn().Err(err).Msg("Failed to refresh tokens")
-			http.Redirect(w, r, ctxRoot+"/auth/logout", http.StatusTemporaryRedirect)
+			sessionExpired(w, r, ctxRoot)
 			return
 		}
 		if resp.Active {
@@ -63,23 +73,13 @@ func HttpSessionInspectAndRenew(next http.Handler) http.Handler {
 			logger.Trace().Msg("Token is not active")
 		}
 
-		tokens, err := rp.RefreshTokens[*oidc.IDTokenClaims](
-			context.Background(),
-			relyingParty,
-			refresh_token.(string),
-			access_token.(string),
-			"urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
-		)
+		err = RenewSessionTokens(context.Background(), session, relyingParty)
 		if err != nil {
 			logger.Error().Err(err).Msg("Failed to refresh tokens")
-			http.Redirect(w, r, ctxRoot+"/auth/logout", http.StatusTemporaryRedirect)
+			sessionExpired(w, r, ctxRoot)
 			return
 		}
 
-		session.Values["access_token"] = tokens.AccessToken
-		session.Values["id_token"] = tokens.IDToken
-		session.Values["refresh_token"] = tokens.RefreshToken
-
 		session.Save(r, w)
 
 		next.ServeHTTP(w, r)
@@ -110,6 +110,13 @@ func HttpSessionAuthenticationRequired(next http.Handler) http.Handler {
 
 		idToken := session.Values["id_token"]
 		if idToken == nil || len(idToken.(string)) == 0 {
+			if IsXHR(r) {
+				logger.Trace().
+					Str("requested_url", r.URL.String()).
+					Msg("Unauthorized XHR request")
+				RespondUnauthorized(w, r, serveOptions.ContextRoot)
+				return
+			}
 			logger.Trace().
 				Str("requested_url", r.URL.String()).
 				Msg("Redirecting to login")
@@ -121,3 +128,65 @@ func HttpSessionAuthenticationRequired(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+func StoreSessionTokens(session *sessions.Session, tokens *oauth2.Token, idToken string) {
+	session.Values["access_token"] = tokens.AccessToken
+	session.Values["refresh_token"] = tokens.RefreshToken
+	session.Values["id_token"] = idToken
+	if !tokens.Expiry.IsZero() {
+		session.Values["token_expiry"] = tokens.Expiry.Unix()
+	} else {
+		delete(session.Values, "token_expiry")
+	}
+}
+
+func RenewSessionTokens(ctx context.Context, session *sessions.Session, relyingParty rp.RelyingParty) error {
+
+	access_token, _ := session.Values["access_token"].(string)
+	refresh_token, _ := session.Values["refresh_token"].(string)
+	if access_token == "" || refresh_token == "" {
+		return ErrMissingTokens
+	}
+
+	nonce, _ := session.Values["oidc_nonce"].(string)
+	tokens, err := rp.RefreshTokens[*oidc.IDTokenClaims](
+		app_http.InjectOidcNonce(ctx, nonce),
+		relyingParty,
+		refresh_token,
+		access_token,
+		"urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+	)
+	if err != nil {
+		return err
+	}
+
+	StoreSessionTokens(session, tokens.Token, tokens.IDToken)
+	return nil
+}
+
+func IsXHR(r *http.Request) bool {
+	if r.Header.Get("X-Requested-With") == "XMLHttpRequest" {
+		return true
+	}
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, "text/html")
+}
+
+// RespondUnauthorized answers XHR requests with a problem carrying the session status members
+func RespondUnauthorized(w http.ResponseWriter, r *http.Request, ctxRoot string) {
+	problem := app_http.NewProblem(r, http.StatusUnauthorized, "Authentication required")
+	problem.Extensions = map[string]any{
+		"authenticated": false,
+		"login_url":     ctxRoot + "/auth/login",
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	app_http.WriteProblem(w, problem)
+}
+
+func sessionExpired(w http.ResponseWriter, r *http.Request, ctxRoot string) {
+	if IsXHR(r) {
+		RespondUnauthorized(w, r, ctxRoot)
+		return
+	}
+	http.Redirect(w, r, ctxRoot+"/auth/logout", http.StatusTemporaryRedirect)
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+)
+
+func TestAuthSuite(t *testing.T) {
+	t.Log("Test Auth Suite")
+
+	t.Run("Test IsXHR", func(t *testing.T) {
+		t.Log("Test Auth IsXHR")
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if IsXHR(r) {
+			t.Fatalf("Expected plain request not to be XHR")
+		}
+
+		r.Header.Set("X-Requested-With", "XMLHttpRequest")
+		if !IsXHR(r) {
+			t.Fatalf("Expected X-Requested-With request to be XHR")
+		}
+
+		r = httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept", "application/json")
+		if !IsXHR(r) {
+			t.Fatalf("Expected JSON request to be XHR")
+		}
+
+		r.Header.Set("Accept", "text/html,application/json")
+		if IsXHR(r) {
+			t.Fatalf("Expected browser navigation not to be XHR")
+		}
+	})
+
+	t.Run("Test RespondUnauthorized", func(t *testing.T) {
+		t.Log("Test Auth RespondUnauthorized")
+
+		w := httptest.NewRecorder()
+		RespondUnauthorized(w, httptest.NewRequest(http.MethodGet, "/fe/api/session", nil), "/fe")
+
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != app_http.PROBLEM_CONTENT_TYPE {
+			t.Fatalf("Expected content type %s, got %s", app_http.PROBLEM_CONTENT_TYPE, contentType)
+		}
+
+		var status app_http.SessionStatus
+		if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+			t.Fatalf("Failed to decode response: %s", err)
+		}
+		if status.Authenticated {
+			t.Fatalf("Expected unauthenticated status")
+		}
+		if status.LoginURL != "/fe/auth/login" {
+			t.Fatalf("Expected login URL /fe/auth/login, got %s", status.LoginURL)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+)
+
+const (
+	CSRF_COOKIE_NAME     = "XSRF-TOKEN"
+	CSRF_HEADER_NAME     = "X-XSRF-TOKEN"
+	CSRF_ALT_HEADER_NAME = "X-CSRF-Token"
+	API_KEY_HEADER_NAME  = "X-API-Key"
+)
+
+func CSRFProtection(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		// browsers never attach the Authorization nor the API key headers on their own; such calls neither get
+		// a CSRF token, which would save a session they never use
+		if isServiceAuthenticated(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		serveOptions := app_http.ExtractServeOptions(r.Context())
+		session := app_http.ExtractSession(r.Context())
+		logger := app_http.ExtractLogger(r.Context(), "csrf")
+
+		token, ok := session.Values["csrf_token"].(string)
+		if !ok || token == "" {
+			token = base64.RawURLEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+			session.Values["csrf_token"] = token
+			if err := session.Save(r, w); err != nil {
+				logger.Error().Err(err).Msg("Failed to save CSRF token")
+				app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to save CSRF token")
+				return
+			}
+			logger.Trace().Msg("CSRF token generated")
+		}
+
+		if cookie, err := r.Cookie(CSRF_COOKIE_NAME); err != nil || cookie.Value != token {
+			http.SetCookie(w, &http.Cookie{
+				Name:     CSRF_COOKIE_NAME,
+				Value:    token,
+				Path:     serveOptions.ContextRoot,
+				Domain:   serveOptions.SessionDomain,
+				MaxAge:   serveOptions.SessionMaxAge,
+				Secure:   serveOptions.SessionSecureCookies,
+				SameSite: serveOptions.SessionSameSite,
+				HttpOnly: false,
+			})
+		}
+
+		if isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		submitted := r.Header.Get(CSRF_HEADER_NAME)
+		if submitted == "" {
+			submitted = r.Header.Get(CSRF_ALT_HEADER_NAME)
+		}
+		if submitted == "" || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+			logger.Warn().
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Msg("CSRF token mismatch")
+			app_http.RespondProblem(w, r, http.StatusForbidden, "Invalid CSRF token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func isServiceAuthenticated(r *http.Request) bool {
+	if r.Header.Get(API_KEY_HEADER_NAME) != "" {
+		return true
+	}
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) < 8 {
+		return false
+	}
+	scheme := authorization[:7]
+	return strings.EqualFold(scheme, "bearer ") || strings.EqualFold(scheme, "apikey ")
+}
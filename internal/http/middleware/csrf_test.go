@@ -0,0 +1,120 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/sessions"
+	"github.com/rs/zerolog"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+func csrfRequest(method string, session *sessions.Session) *http.Request {
+	r := httptest.NewRequest(method, "/fe/api/example", nil)
+	ctx := app_http.InjectServeOptions(r.Context(), &options.ServeOptions{ContextRoot: "/fe"})
+	ctx = app_http.InjectSession(ctx, session)
+	ctx = app_http.InjectLogger(ctx, zerolog.Nop())
+	return r.WithContext(ctx)
+}
+
+func TestCSRFSuite(t *testing.T) {
+	t.Log("Test CSRF Suite")
+
+	store := sessions.NewCookieStore([]byte("test-session-key"))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mid := CSRFProtection(next)
+
+	t.Run("Test safe method issues token", func(t *testing.T) {
+		t.Log("Test CSRF safe method")
+
+		session := sessions.NewSession(store, "test")
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, csrfRequest(http.MethodGet, session))
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+		token, _ := session.Values["csrf_token"].(string)
+		if token == "" {
+			t.Fatalf("Expected CSRF token in session")
+		}
+		found := false
+		for _, cookie := range w.Result().Cookies() {
+			if cookie.Name == CSRF_COOKIE_NAME && cookie.Value == token {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("Expected %s cookie", CSRF_COOKIE_NAME)
+		}
+	})
+
+	t.Run("Test unsafe method without token", func(t *testing.T) {
+		t.Log("Test CSRF missing token")
+
+		session := sessions.NewSession(store, "test")
+		session.Values["csrf_token"] = "expected"
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, csrfRequest(http.MethodPost, session))
+
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
+	t.Run("Test unsafe method with token", func(t *testing.T) {
+		t.Log("Test CSRF valid token")
+
+		session := sessions.NewSession(store, "test")
+		session.Values["csrf_token"] = "expected"
+		r := csrfRequest(http.MethodDelete, session)
+		r.Header.Set(CSRF_HEADER_NAME, "expected")
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+	})
+
+	t.Run("Test bearer exemption", func(t *testing.T) {
+		t.Log("Test CSRF bearer exemption")
+
+		r := httptest.NewRequest(http.MethodPut, "/fe/api/example", nil).WithContext(context.Background())
+		r.Header.Set("Authorization", "Bearer abc")
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+	})
+
+	t.Run("Test API key exemption", func(t *testing.T) {
+		t.Log("Test CSRF API key exemption")
+
+		for name, value := range map[string]string{
+			API_KEY_HEADER_NAME: "id.secret",
+			"Authorization":     "ApiKey id.secret",
+		} {
+			session := sessions.NewSession(store, "test")
+			r := csrfRequest(http.MethodPost, session)
+			r.Header.Set(name, value)
+			w := httptest.NewRecorder()
+			mid.ServeHTTP(w, r)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("Expected status %d with %s, got %d", http.StatusNoContent, name, w.Code)
+			}
+			if _, found := session.Values["csrf_token"]; found || len(w.Result().Cookies()) > 0 {
+				t.Fatalf("Expected no session nor CSRF cookie with %s", name)
+			}
+		}
+	})
+}
@@ -1,7 +1,10 @@
 package middleware
 
 import (
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	app_http "github.com/morphy76/g-fe-server/internal/http"
 )
@@ -11,12 +14,51 @@ func InjectSession(next http.Handler) http.Handler {
 
 		store := app_http.ExtractSessionStore(r.Context())
 		serveOptions := app_http.ExtractServeOptions(r.Context())
+		index := app_http.ExtractSessionIndex(r.Context())
 
 		session, _ := store.Get(r, serveOptions.SessionName)
 
+		if session.ID != "" && index.IsRevoked(session.ID) {
+			index.Remove(session.ID)
+			session.Options.MaxAge = -1
+			session.Save(r, w)
+			for k := range session.Values {
+				delete(session.Values, k)
+			}
+			session.ID = ""
+			session.IsNew = true
+			session.Options.MaxAge = serveOptions.SessionMaxAge
+		} else if subject, ok := session.Values["subject"].(string); ok && session.ID != "" {
+			TrackSession(r, session.ID, subject, session.Values["login_at"])
+		}
+
 		sessionContext := app_http.InjectSession(r.Context(), session)
 		useRequest := r.WithContext(sessionContext)
 
 		next.ServeHTTP(w, useRequest)
 	})
 }
+
+func TrackSession(r *http.Request, sessionID string, subject string, loginAt any) {
+
+	index := app_http.ExtractSessionIndex(r.Context())
+
+	var createdAt time.Time
+	if unix, ok := loginAt.(int64); ok {
+		createdAt = time.Unix(unix, 0)
+	}
+
+	index.Touch(sessionID, subject, r.UserAgent(), clientIP(r), createdAt)
+}
+
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		first, _, _ := strings.Cut(forwarded, ",")
+		return strings.TrimSpace(first)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
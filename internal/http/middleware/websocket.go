@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	app_serve "github.com/morphy76/g-fe-server/internal/serve"
+)
+
+const (
+	WS_CLOSED_IDLE   = "idle_timeout"
+	WS_CLOSED_NORMAL = "closed"
+
+	WS_REJECTED_PROTOCOL        = "protocol"
+	WS_REJECTED_ORIGIN          = "origin"
+	WS_REJECTED_UNAUTHENTICATED = "unauthenticated"
+)
+
+// IsUpgrade tells requests asking to switch protocol apart
+func IsUpgrade(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func IsWebSocketUpgrade(r *http.Request) bool {
+	return IsUpgrade(r) && strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// WebSocketHandshake authenticates WebSocket handshakes against the HTTP session and
+// closes the upgraded connections once they are idle, other requests pass through untouched
+func WebSocketHandshake(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if !IsUpgrade(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		serveOptions := app_http.ExtractServeOptions(r.Context())
+		oidcOptions := app_http.ExtractOidcOptions(r.Context())
+		logger := app_http.ExtractLogger(r.Context(), "websocket")
+
+		reject := func(reason string) {
+			app_serve.WebSocketRejectedTotal.With(prometheus.Labels{"path": r.URL.Path, "reason": reason}).Inc()
+			logger.Warn().
+				Str("path", r.URL.Path).
+				Str("reason", reason).
+				Msg("WebSocket handshake rejected")
+		}
+
+		if !IsWebSocketUpgrade(r) {
+			reject(WS_REJECTED_PROTOCOL)
+			app_http.RespondProblem(w, r, http.StatusBadRequest, "Unsupported protocol upgrade")
+			return
+		}
+
+		// browsers attach cookies to cross-site handshakes and do not let pages set the CSRF header
+		if !isSameOrigin(r) {
+			reject(WS_REJECTED_ORIGIN)
+			app_http.RespondProblem(w, r, http.StatusForbidden, "Cross-origin WebSocket handshake")
+			return
+		}
+
+		if !oidcOptions.Disabled {
+			session := app_http.ExtractSession(r.Context())
+			idToken, _ := session.Values["id_token"].(string)
+			if idToken == "" {
+				reject(WS_REJECTED_UNAUTHENTICATED)
+				RespondUnauthorized(w, r, serveOptions.ContextRoot)
+				return
+			}
+		}
+
+		// the switching protocols response carries the upstream headers only
+		w.Header().Del("Content-Type")
+
+		logger.Trace().
+			Str("path", r.URL.Path).
+			Dur("idle_timeout", serveOptions.WebSocketIdleTimeout).
+			Msg("WebSocket handshake accepted")
+
+		next.ServeHTTP(&upgradeWriter{
+			ResponseWriter: w,
+			path:           r.URL.Path,
+			idleTimeout:    serveOptions.WebSocketIdleTimeout,
+		}, r)
+	})
+}
+
+func isSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		// not a browser
+		return true
+	}
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(originURL.Host, r.Host)
+}
+
+// upgradeWriter hands out idle bounded connections to the handler hijacking the upgraded request
+type upgradeWriter struct {
+	http.ResponseWriter
+	path        string
+	idleTimeout time.Duration
+}
+
+func (w *upgradeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the server deadlines still apply to hijacked connections
+	conn.SetDeadline(time.Time{})
+
+	labels := prometheus.Labels{"path": w.path}
+	app_serve.WebSocketConnectionsTotal.With(labels).Inc()
+	app_serve.WebSocketActiveConnections.With(labels).Inc()
+
+	start := time.Now()
+	return newIdleConn(conn, w.idleTimeout, func(idle bool) {
+		reason := WS_CLOSED_NORMAL
+		if idle {
+			reason = WS_CLOSED_IDLE
+		}
+		app_serve.WebSocketActiveConnections.With(labels).Dec()
+		app_serve.WebSocketConnectionDuration.With(labels).Observe(time.Since(start).Seconds())
+		app_serve.WebSocketClosedTotal.With(prometheus.Labels{"path": w.path, "reason": reason}).Inc()
+	}), brw, nil
+}
+
+func (w *upgradeWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// idleConn pushes its deadline forward on traffic in either direction, reads and writes
+// fail once the connection has been idle for longer than the timeout
+type idleConn struct {
+	net.Conn
+	timeout  time.Duration
+	timedOut atomic.Bool
+	once     sync.Once
+	onClose  func(idle bool)
+}
+
+func newIdleConn(conn net.Conn, timeout time.Duration, onClose func(idle bool)) *idleConn {
+	rv := &idleConn{
+		Conn:    conn,
+		timeout: timeout,
+		onClose: onClose,
+	}
+	rv.extend()
+	return rv
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	c.extend()
+	n, err := c.Conn.Read(b)
+	c.observe(err)
+	return n, err
+}
+
+func (c *idleConn) Write(b []byte) (int, error) {
+	c.extend()
+	n, err := c.Conn.Write(b)
+	c.observe(err)
+	return n, err
+}
+
+func (c *idleConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		c.onClose(c.timedOut.Load())
+	})
+	return err
+}
+
+func (c *idleConn) extend() {
+	if c.timeout > 0 {
+		c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+}
+
+func (c *idleConn) observe(err error) {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		c.timedOut.Store(true)
+	}
+}
@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/rs/zerolog"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+func handshakeRequest(oidcDisabled bool, session *sessions.Session) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://gw.local/fe/api/chat", nil)
+	r.Header.Set("Connection", "keep-alive, Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	ctx := app_http.InjectServeOptions(r.Context(), &options.ServeOptions{ContextRoot: "/fe"})
+	ctx = app_http.InjectOidcOptions(ctx, &options.OidcOptions{Disabled: oidcDisabled})
+	ctx = app_http.InjectSession(ctx, session)
+	ctx = app_http.InjectLogger(ctx, zerolog.Nop())
+	return r.WithContext(ctx)
+}
+
+func TestWebSocketSuite(t *testing.T) {
+	t.Log("Test WebSocket Suite")
+
+	store := sessions.NewCookieStore([]byte("test-session-key"))
+	var upgraded bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, upgraded = w.(*upgradeWriter)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mid := WebSocketHandshake(next)
+
+	t.Run("Test plain request passes through", func(t *testing.T) {
+		t.Log("Test WebSocket plain request")
+
+		r := httptest.NewRequest(http.MethodGet, "/fe/api/chat", nil)
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent || upgraded {
+			t.Fatalf("Expected untouched request, got status %d", w.Code)
+		}
+	})
+
+	t.Run("Test authenticated handshake", func(t *testing.T) {
+		t.Log("Test WebSocket authenticated handshake")
+
+		session := sessions.NewSession(store, "test")
+		session.Values["id_token"] = "token"
+		r := handshakeRequest(false, session)
+		r.Header.Set("Origin", "http://gw.local")
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent || !upgraded {
+			t.Fatalf("Expected upgradable request, got status %d", w.Code)
+		}
+	})
+
+	t.Run("Test unauthenticated handshake", func(t *testing.T) {
+		t.Log("Test WebSocket unauthenticated handshake")
+
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, handshakeRequest(false, sessions.NewSession(store, "test")))
+
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+
+	t.Run("Test handshake without OIDC", func(t *testing.T) {
+		t.Log("Test WebSocket handshake without OIDC")
+
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, handshakeRequest(true, sessions.NewSession(store, "test")))
+
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+	})
+
+	t.Run("Test cross-origin handshake", func(t *testing.T) {
+		t.Log("Test WebSocket cross-origin handshake")
+
+		r := handshakeRequest(true, sessions.NewSession(store, "test"))
+		r.Header.Set("Origin", "http://evil.local")
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, r)
+
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("Expected status %d, got %d", http.StatusForbidden, w.Code)
+		}
+	})
+
+	t.Run("Test unsupported upgrade", func(t *testing.T) {
+		t.Log("Test WebSocket unsupported upgrade")
+
+		r := handshakeRequest(true, sessions.NewSession(store, "test"))
+		r.Header.Set("Upgrade", "h2c")
+		w := httptest.NewRecorder()
+		mid.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("Test idle connection", func(t *testing.T) {
+		t.Log("Test WebSocket idle connection")
+
+		client, server := net.Pipe()
+		defer client.Close()
+
+		closed := make(chan bool, 1)
+		conn := newIdleConn(server, 50*time.Millisecond, func(idle bool) {
+			closed <- idle
+		})
+
+		go client.Write([]byte("ping"))
+		buf := make([]byte, 4)
+		if _, err := conn.Read(buf); err != nil {
+			t.Fatalf("Expected traffic before the timeout, got %v", err)
+		}
+
+		if _, err := conn.Read(buf); err == nil {
+			t.Fatalf("Expected the idle connection to time out")
+		}
+		conn.Close()
+		conn.Close()
+
+		if idle := <-closed; !idle {
+			t.Fatalf("Expected the close to be reported as idle")
+		}
+		if len(closed) != 0 {
+			t.Fatalf("Expected a single close report")
+		}
+	})
+}
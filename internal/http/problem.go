@@ -0,0 +1,79 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/internal/validation"
+)
+
+const PROBLEM_CONTENT_TYPE = "application/problem+json"
+
+const (
+	ProblemTypeDefault    = "about:blank"
+	ProblemTypeValidation = "urn:g-fe-server:problem:validation"
+)
+
+// Problem is the RFC 7807 error model, members not known to the RFC are carried by Extensions
+type Problem struct {
+	Type       string            `json:"type"`
+	Title      string            `json:"title"`
+	Status     int               `json:"status"`
+	Detail     string            `json:"detail,omitempty"`
+	Instance   string            `json:"instance,omitempty"`
+	TraceId    string            `json:"trace_id,omitempty"`
+	Errors     validation.Errors `json:"errors,omitempty"`
+	Extensions map[string]any    `json:"-"`
+}
+
+func NewProblem(r *http.Request, status int, detail string) Problem {
+
+	rv := Problem{
+		Type:   ProblemTypeDefault,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+
+	if r != nil {
+		rv.Instance = r.URL.Path
+		if spanContext := trace.SpanContextFromContext(r.Context()); spanContext.HasTraceID() {
+			rv.TraceId = spanContext.TraceID().String()
+		}
+	}
+
+	return rv
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+
+	type problem Problem
+	raw, err := json.Marshal(problem(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return raw, err
+	}
+
+	members := make(map[string]any, len(p.Extensions)+7)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	// the standard members win over extensions with the same name
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	w.Header().Set("Content-Type", PROBLEM_CONTENT_TYPE)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// RespondProblem is the problem+json counterpart of http.Error
+func RespondProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, NewProblem(r, status, detail))
+}
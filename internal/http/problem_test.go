@@ -0,0 +1,77 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/internal/validation"
+)
+
+func TestProblemSuite(t *testing.T) {
+	t.Log("Test Problem Suite")
+
+	t.Run("Test RespondProblem", func(t *testing.T) {
+		t.Log("Testing RespondProblem")
+
+		traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+		spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+		spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceId, SpanID: spanId})
+
+		r := httptest.NewRequest(http.MethodGet, "/fe/api/example/missing", nil)
+		r = r.WithContext(trace.ContextWithSpanContext(r.Context(), spanContext))
+		w := httptest.NewRecorder()
+
+		RespondProblem(w, r, http.StatusNotFound, "example not found")
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != PROBLEM_CONTENT_TYPE {
+			t.Errorf("Expected content type %s, got %s", PROBLEM_CONTENT_TYPE, contentType)
+		}
+
+		var problem Problem
+		if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+			t.Fatalf("Failed to decode problem: %s", err)
+		}
+		if problem.Type != ProblemTypeDefault || problem.Title != "Not Found" || problem.Status != http.StatusNotFound {
+			t.Errorf("Unexpected problem: %#v", problem)
+		}
+		if problem.Instance != "/fe/api/example/missing" || problem.TraceId != traceId.String() {
+			t.Errorf("Unexpected instance or trace id: %#v", problem)
+		}
+	})
+
+	t.Run("Test Problem Extensions", func(t *testing.T) {
+		t.Log("Testing Problem Extensions")
+
+		problem := NewProblem(nil, http.StatusUnprocessableEntity, "validation failed")
+		problem.Type = ProblemTypeValidation
+		problem.Errors = validation.Errors{{Field: "age", Rule: "min", Param: "1", Message: "must be at least 1"}}
+		problem.Extensions = map[string]any{
+			"login_url": "/fe/auth/login",
+			"status":    "overridden",
+		}
+
+		raw, err := json.Marshal(problem)
+		if err != nil {
+			t.Fatalf("Failed to marshal problem: %s", err)
+		}
+
+		members := map[string]any{}
+		json.Unmarshal(raw, &members)
+		if members["login_url"] != "/fe/auth/login" {
+			t.Errorf("Expected the login_url extension, got %s", raw)
+		}
+		if members["status"] != float64(http.StatusUnprocessableEntity) {
+			t.Errorf("Expected the standard status to win, got %s", raw)
+		}
+		if errs, ok := members["errors"].([]any); !ok || len(errs) != 1 {
+			t.Errorf("Expected one field error, got %s", raw)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const EVENT_STREAM_CONTENT_TYPE = "text/event-stream"
+
+// EventStream writes server-sent events, every write is flushed to the client
+type EventStream struct {
+	w          http.ResponseWriter
+	controller *http.ResponseController
+}
+
+// NewEventStream starts the stream, proxies are asked not to buffer it and clients to reconnect after retry
+func NewEventStream(w http.ResponseWriter, retry time.Duration) (*EventStream, error) {
+
+	w.Header().Set("Content-Type", EVENT_STREAM_CONTENT_TYPE)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusOK)
+
+	rv := &EventStream{
+		w:          w,
+		controller: http.NewResponseController(w),
+	}
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds()); err != nil {
+		return nil, err
+	}
+	return rv, rv.controller.Flush()
+}
+
+// Send writes an event whose data is the JSON encoding of the payload
+func (s *EventStream) Send(id string, event string, payload any) error {
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var frame strings.Builder
+	if id != "" {
+		fmt.Fprintf(&frame, "id: %s\n", id)
+	}
+	if event != "" {
+		fmt.Fprintf(&frame, "event: %s\n", event)
+	}
+	fmt.Fprintf(&frame, "data: %s\n\n", data)
+
+	if _, err := s.w.Write([]byte(frame.String())); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// Comment writes a comment line, which clients ignore; it keeps idle connections open
+func (s *EventStream) Comment(text string) error {
+	if _, err := fmt.Fprintf(s.w, ": %s\n\n", text); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
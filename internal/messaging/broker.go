@@ -0,0 +1,49 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// the headers of the records, besides the trace context
+const (
+	HEADER_TENANT     = "tenant"
+	HEADER_EVENT_ID   = "event_id"
+	HEADER_EVENT_TYPE = "event_type"
+)
+
+var ErrBrokerClosed = errors.New("broker closed")
+
+func IsBrokerClosed(err error) bool {
+	return errors.Is(err, ErrBrokerClosed)
+}
+
+// Record is a message of a topic; Partition and Offset are assigned by the broker
+type Record struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+	Timestamp time.Time
+}
+
+// Broker is the transport of the messaging subsystem, kafka in production and the memory broker in tests
+type Broker interface {
+	Produce(ctx context.Context, records ...Record) error
+	// Subscribe joins the consumer group on the topics, the replicas of a group share the partitions
+	Subscribe(group string, topics []string) (Subscription, error)
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Subscription reads the records of a consumer group
+type Subscription interface {
+	// Fetch waits for the next records and tells the lag of each topic after them, i.e. the records left behind
+	Fetch(ctx context.Context) ([]Record, map[string]int64, error)
+	// Commit records that the fetched records are processed, a new member of the group resumes after them
+	Commit(ctx context.Context) error
+	Close() error
+}
@@ -0,0 +1,180 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/serve"
+)
+
+// consumerAttempts is how many times a record is handed to its handler before it is skipped
+const consumerAttempts = 3
+
+// ErrSkipRecord tells the consumer to skip a record without retrying it
+var ErrSkipRecord = errors.New("record skipped")
+
+func IsSkipRecord(err error) bool {
+	return errors.Is(err, ErrSkipRecord)
+}
+
+// Handler processes a record within the trace of its producer and, when the record has one, on behalf of its
+// tenant, see app_http.LookupOwnership
+type Handler func(ctx context.Context, record Record) error
+
+// TenantHandler processes a record on behalf of the tenant of the record
+type TenantHandler func(ctx context.Context, tenant string, record Record) error
+
+// ForTenant adapts a tenant handler, the records without a tenant are skipped
+func ForTenant(handler TenantHandler) Handler {
+	return func(ctx context.Context, record Record) error {
+		ownership, ok := app_http.LookupOwnership(ctx)
+		if !ok || ownership.Tenant == "" {
+			return ErrSkipRecord
+		}
+		return handler(ctx, ownership.Tenant, record)
+	}
+}
+
+// Consumer dispatches the records of a consumer group to the handlers of their topics; offsets are committed
+// after each fetched batch is handled, so that a crash processes the batch again
+type Consumer struct {
+	broker       Broker
+	group        string
+	handlers     map[string]Handler
+	retryBackoff time.Duration
+}
+
+func NewConsumer(broker Broker, group string) *Consumer {
+	return &Consumer{
+		broker:       broker,
+		group:        group,
+		handlers:     make(map[string]Handler),
+		retryBackoff: 100 * time.Millisecond,
+	}
+}
+
+// Handle registers the handler of a topic, it must be called before Run
+func (c *Consumer) Handle(topic string, handler Handler) {
+	c.handlers[topic] = handler
+}
+
+// Run consumes the topics of the handlers until the context is done or the broker is closed
+func (c *Consumer) Run(ctx context.Context) error {
+
+	topics := make([]string, 0, len(c.handlers))
+	for topic := range c.handlers {
+		topics = append(topics, topic)
+	}
+
+	subscription, err := c.broker.Subscribe(c.group, topics)
+	if err != nil {
+		return err
+	}
+	defer subscription.Close()
+
+	for {
+		records, lag, err := subscription.Fetch(ctx)
+		if ctx.Err() != nil || IsBrokerClosed(err) {
+			return nil
+		}
+		if err != nil {
+			log.Error().Err(err).Str("group", c.group).Msg("Messaging fetch failed")
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		for topic, behind := range lag {
+			serve.MessagingConsumerLag.WithLabelValues(c.group, topic).Set(float64(behind))
+		}
+		for _, record := range records {
+			if !c.dispatch(ctx, record) {
+				break
+			}
+		}
+		// a batch interrupted by the shutdown is not committed, it is processed again on restart
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err := subscription.Commit(ctx); err != nil {
+			log.Error().Err(err).Str("group", c.group).Msg("Messaging commit failed")
+		}
+	}
+}
+
+// dispatch hands the record to its handler within a consumer span, retrying with a doubling backoff; a record
+// failing every attempt is logged and skipped, so that it does not block its partition. It tells whether the
+// record is settled, i.e. not interrupted by the end of the context
+func (c *Consumer) dispatch(ctx context.Context, record Record) bool {
+
+	handler, ok := c.handlers[record.Topic]
+	if !ok {
+		return true
+	}
+
+	traceContext := otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(record.Headers))
+	handleContext, span := otel.Tracer(tracerName).Start(traceContext, "messaging.Process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", record.Topic),
+			attribute.String("messaging.consumer.group.name", c.group),
+			attribute.Int64("messaging.kafka.offset", record.Offset),
+		),
+	)
+	defer span.End()
+
+	if tenant := record.Headers[HEADER_TENANT]; tenant != "" {
+		handleContext = app_http.InjectOwnership(handleContext, serve.Ownership{Tenant: tenant})
+	}
+
+	var err error
+	backoff := c.retryBackoff
+	for attempt := 1; attempt <= consumerAttempts; attempt++ {
+		err = handler(handleContext, record)
+		if err == nil || IsSkipRecord(err) || attempt == consumerAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	if err != nil && ctx.Err() != nil {
+		return false
+	}
+
+	switch {
+	case err == nil:
+		serve.MessagingConsumedTotal.WithLabelValues(c.group, record.Topic, "handled").Inc()
+	case IsSkipRecord(err):
+		serve.MessagingConsumedTotal.WithLabelValues(c.group, record.Topic, "skipped").Inc()
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		serve.MessagingConsumedTotal.WithLabelValues(c.group, record.Topic, "failed").Inc()
+		log.Error().
+			Err(err).
+			Str("group", c.group).
+			Str("topic", record.Topic).
+			Int32("partition", record.Partition).
+			Int64("offset", record.Offset).
+			Msg("Messaging record skipped after failing every attempt")
+	}
+	return true
+}
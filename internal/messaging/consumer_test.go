@@ -0,0 +1,278 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/serve"
+)
+
+// runConsumer runs the consumer until the returned stop is called
+func runConsumer(t *testing.T, consumer *Consumer) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- consumer.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Error on Run: %s", err)
+		}
+	}
+}
+
+// eventually waits up to a second for the condition
+func eventually(t *testing.T, condition func() bool, format string, args ...any) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf(format, args...)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMessagingSuite(t *testing.T) {
+	t.Log("Test Messaging Suite")
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	traceId, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929b0e0e4736")
+	spanId, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	tracedContext := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceId,
+		SpanID:     spanId,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	}))
+
+	t.Run("Test Tenant And Trace Propagation", func(t *testing.T) {
+		t.Log("Testing Tenant And Trace Propagation")
+
+		broker := NewMemoryBroker()
+		defer broker.Close()
+
+		ctx := app_http.InjectOwnership(tracedContext, serve.Ownership{Tenant: "producer"})
+		if err := NewProducer(broker).Send(ctx, "propagation", []byte("key"), []byte("value"), nil); err != nil {
+			t.Fatalf("Error on Send: %s", err)
+		}
+
+		records := broker.Records("propagation")
+		if len(records) != 1 || records[0].Headers[HEADER_TENANT] != "producer" || records[0].Headers["traceparent"] == "" {
+			t.Fatalf("Expected the tenant and the trace in the headers, got %v", records)
+		}
+
+		type handled struct {
+			tenant  string
+			traceId trace.TraceID
+		}
+		received := make(chan handled, 1)
+		consumer := NewConsumer(broker, "propagation-group")
+		consumer.Handle("propagation", ForTenant(func(ctx context.Context, tenant string, record Record) error {
+			received <- handled{tenant: tenant, traceId: trace.SpanContextFromContext(ctx).TraceID()}
+			return nil
+		}))
+		stop := runConsumer(t, consumer)
+		defer stop()
+
+		select {
+		case got := <-received:
+			if got.tenant != "producer" {
+				t.Errorf("Expected the tenant of the producer, got %s", got.tenant)
+			}
+			if got.traceId != traceId {
+				t.Errorf("Expected the trace of the producer, got %s", got.traceId)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the record to be handled")
+		}
+		eventually(t, func() bool { return broker.Committed("propagation-group", "propagation") == 1 }, "Expected the offset to be committed")
+	})
+
+	t.Run("Test Records Without Tenant", func(t *testing.T) {
+		t.Log("Testing Records Without Tenant")
+
+		broker := NewMemoryBroker()
+		defer broker.Close()
+
+		if err := NewProducer(broker).Send(context.Background(), "anonymous", nil, []byte("value"), nil); err != nil {
+			t.Fatalf("Error on Send: %s", err)
+		}
+
+		var mu sync.Mutex
+		calls := 0
+		consumer := NewConsumer(broker, "anonymous-group")
+		consumer.Handle("anonymous", ForTenant(func(ctx context.Context, tenant string, record Record) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return nil
+		}))
+		stop := runConsumer(t, consumer)
+		defer stop()
+
+		eventually(t, func() bool { return broker.Committed("anonymous-group", "anonymous") == 1 }, "Expected the record to be skipped")
+		mu.Lock()
+		defer mu.Unlock()
+		if calls != 0 {
+			t.Errorf("Expected the tenant handler not to be called, got %d calls", calls)
+		}
+	})
+
+	t.Run("Test Retries", func(t *testing.T) {
+		t.Log("Testing Retries")
+
+		broker := NewMemoryBroker()
+		defer broker.Close()
+
+		producer := NewProducer(broker)
+		for _, value := range []string{"flaky", "broken"} {
+			if err := producer.Send(context.Background(), "retried", nil, []byte(value), nil); err != nil {
+				t.Fatalf("Error on Send: %s", err)
+			}
+		}
+
+		var mu sync.Mutex
+		attempts := map[string]int{}
+		consumer := NewConsumer(broker, "retried-group")
+		consumer.retryBackoff = time.Millisecond
+		consumer.Handle("retried", func(ctx context.Context, record Record) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts[string(record.Value)]++
+			if string(record.Value) == "broken" || attempts["flaky"] < 2 {
+				return errors.New("handler failed")
+			}
+			return nil
+		})
+		failedBefore := testutil.ToFloat64(serve.MessagingConsumedTotal.WithLabelValues("retried-group", "retried", "failed"))
+		stop := runConsumer(t, consumer)
+		defer stop()
+
+		eventually(t, func() bool { return broker.Committed("retried-group", "retried") == 2 }, "Expected the records to be committed")
+		mu.Lock()
+		defer mu.Unlock()
+		if attempts["flaky"] != 2 {
+			t.Errorf("Expected the flaky record to succeed on the second attempt, got %d attempts", attempts["flaky"])
+		}
+		if attempts["broken"] != consumerAttempts {
+			t.Errorf("Expected the broken record to be attempted %d times, got %d", consumerAttempts, attempts["broken"])
+		}
+		if failed := testutil.ToFloat64(serve.MessagingConsumedTotal.WithLabelValues("retried-group", "retried", "failed")); failed != failedBefore+1 {
+			t.Errorf("Expected the broken record to be counted as failed, got %f", failed-failedBefore)
+		}
+	})
+
+	t.Run("Test Shutdown In A Batch", func(t *testing.T) {
+		t.Log("Testing Shutdown In A Batch")
+
+		broker := NewMemoryBroker()
+		defer broker.Close()
+
+		producer := NewProducer(broker)
+		for _, value := range []string{"handled", "interrupted", "pending"} {
+			if err := producer.Send(context.Background(), "interrupted", nil, []byte(value), nil); err != nil {
+				t.Fatalf("Error on Send: %s", err)
+			}
+		}
+
+		started := make(chan struct{})
+		consumer := NewConsumer(broker, "interrupted-group")
+		consumer.Handle("interrupted", func(ctx context.Context, record Record) error {
+			if string(record.Value) != "interrupted" {
+				return nil
+			}
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		stop := runConsumer(t, consumer)
+
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the second record to be handled")
+		}
+		stop()
+
+		if committed := broker.Committed("interrupted-group", "interrupted"); committed != 0 {
+			t.Errorf("Expected the interrupted batch not to be committed, got offset %d", committed)
+		}
+	})
+
+	t.Run("Test Lag", func(t *testing.T) {
+		t.Log("Testing Lag")
+
+		broker := NewMemoryBroker()
+		defer broker.Close()
+
+		producer := NewProducer(broker)
+		for i := 0; i < memoryFetchLimit+50; i++ {
+			if err := producer.Send(context.Background(), "lagging", nil, []byte(fmt.Sprint(i)), nil); err != nil {
+				t.Fatalf("Error on Send: %s", err)
+			}
+		}
+
+		subscription, err := broker.Subscribe("lagging-group", []string{"lagging"})
+		if err != nil {
+			t.Fatalf("Error on Subscribe: %s", err)
+		}
+		records, lag, err := subscription.Fetch(context.Background())
+		if err != nil {
+			t.Fatalf("Error on Fetch: %s", err)
+		}
+		if len(records) != memoryFetchLimit || lag["lagging"] != 50 {
+			t.Errorf("Expected %d records and a lag of 50, got %d and %d", memoryFetchLimit, len(records), lag["lagging"])
+		}
+		subscription.Close()
+
+		release := make(chan struct{})
+		consumer := NewConsumer(broker, "lagging-group")
+		consumer.Handle("lagging", func(ctx context.Context, record Record) error {
+			if record.Offset == memoryFetchLimit-1 {
+				<-release
+			}
+			return nil
+		})
+		stop := runConsumer(t, consumer)
+		defer stop()
+
+		gauge := serve.MessagingConsumerLag.WithLabelValues("lagging-group", "lagging")
+		eventually(t, func() bool { return testutil.ToFloat64(gauge) == 50 }, "Expected the lag of the uncommitted fetch")
+		close(release)
+		eventually(t, func() bool { return testutil.ToFloat64(gauge) == 0 }, "Expected the consumer to catch up")
+		eventually(t, func() bool { return broker.Committed("lagging-group", "lagging") == memoryFetchLimit+50 }, "Expected every record to be committed")
+	})
+
+	t.Run("Test Health Check", func(t *testing.T) {
+		t.Log("Testing Health Check")
+
+		if CreateHealthCheck(nil) != nil {
+			t.Error("Expected no health check without a broker")
+		}
+
+		broker := NewMemoryBroker()
+		ctx := Inject(context.Background(), broker)
+		if Extract(ctx) != broker {
+			t.Fatal("Expected the broker of the context")
+		}
+
+		if label, status := CreateHealthCheck(broker)(ctx); label != "Kafka" || status != app_http.Active {
+			t.Errorf("Expected Kafka to be active, got %s %v", label, status)
+		}
+		broker.Close()
+		if _, status := CreateHealthCheck(broker)(ctx); status != app_http.Inactive {
+			t.Errorf("Expected Kafka to be inactive once closed, got %v", status)
+		}
+	})
+}
@@ -0,0 +1,39 @@
+package messaging
+
+import (
+	"context"
+	"time"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+)
+
+type ContextBrokerKey string
+
+const ctx_BROKER_KEY ContextBrokerKey = "messagingBroker"
+
+// Extract returns the broker of the context, nil when messaging is disabled
+func Extract(ctx context.Context) Broker {
+	rv, _ := ctx.Value(ctx_BROKER_KEY).(Broker)
+	return rv
+}
+
+func Inject(ctx context.Context, broker Broker) context.Context {
+	return context.WithValue(ctx, ctx_BROKER_KEY, broker)
+}
+
+// CreateHealthCheck pings the brokers, nil when messaging is disabled
+func CreateHealthCheck(broker Broker) app_http.HealthCheckFn {
+	if broker == nil {
+		return nil
+	}
+
+	return func(requestContext context.Context) (string, app_http.Status) {
+		timeoutContext, cancel := context.WithTimeout(requestContext, 5*time.Second)
+		defer cancel()
+
+		if err := broker.Ping(timeoutContext); err != nil {
+			return "Kafka", app_http.Inactive
+		}
+		return "Kafka", app_http.Active
+	}
+}
@@ -0,0 +1,132 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+// kafkaBroker produces with a client of its own, every subscription has its client joined to the group
+type kafkaBroker struct {
+	options  *options.MessagingOptions
+	producer *kgo.Client
+}
+
+// NewKafkaBroker connects lazily to the brokers of the options
+func NewKafkaBroker(messagingOptions *options.MessagingOptions) (Broker, error) {
+	producer, err := kgo.NewClient(
+		kgo.SeedBrokers(messagingOptions.Brokers...),
+		kgo.ClientID(messagingOptions.ClientId),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaBroker{
+		options:  messagingOptions,
+		producer: producer,
+	}, nil
+}
+
+func (b *kafkaBroker) Produce(ctx context.Context, records ...Record) error {
+	kafkaRecords := make([]*kgo.Record, len(records))
+	for i, record := range records {
+		kafkaRecords[i] = &kgo.Record{
+			Topic: record.Topic,
+			Key:   record.Key,
+			Value: record.Value,
+		}
+		for key, value := range record.Headers {
+			kafkaRecords[i].Headers = append(kafkaRecords[i].Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+		}
+	}
+	return b.producer.ProduceSync(ctx, kafkaRecords...).FirstErr()
+}
+
+func (b *kafkaBroker) Subscribe(group string, topics []string) (Subscription, error) {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(b.options.Brokers...),
+		kgo.ClientID(b.options.ClientId),
+		kgo.ConsumerGroup(group),
+		kgo.ConsumeTopics(topics...),
+		kgo.DisableAutoCommit(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaSubscription{client: client}, nil
+}
+
+func (b *kafkaBroker) Ping(ctx context.Context) error {
+	return b.producer.Ping(ctx)
+}
+
+func (b *kafkaBroker) Close() error {
+	b.producer.Close()
+	return nil
+}
+
+type kafkaSubscription struct {
+	client *kgo.Client
+}
+
+// Fetch fails only when no partition returned records, the errors of the others are retried by the client
+func (s *kafkaSubscription) Fetch(ctx context.Context) ([]Record, map[string]int64, error) {
+
+	fetches := s.client.PollFetches(ctx)
+	if fetches.IsClientClosed() {
+		return nil, nil, ErrBrokerClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var fetchErr error
+	fetches.EachError(func(topic string, partition int32, err error) {
+		if fetchErr == nil {
+			fetchErr = fmt.Errorf("fetch of %s/%d: %w", topic, partition, err)
+		}
+	})
+
+	rv := make([]Record, 0, fetches.NumRecords())
+	lag := make(map[string]int64)
+	fetches.EachPartition(func(partition kgo.FetchTopicPartition) {
+		if len(partition.Records) == 0 {
+			return
+		}
+		last := partition.Records[len(partition.Records)-1]
+		lag[partition.Topic] += partition.HighWatermark - last.Offset - 1
+
+		for _, record := range partition.Records {
+			headers := make(map[string]string, len(record.Headers))
+			for _, header := range record.Headers {
+				headers[header.Key] = string(header.Value)
+			}
+			rv = append(rv, Record{
+				Topic:     record.Topic,
+				Partition: record.Partition,
+				Offset:    record.Offset,
+				Key:       record.Key,
+				Value:     record.Value,
+				Headers:   headers,
+				Timestamp: record.Timestamp,
+			})
+		}
+	})
+
+	if len(rv) == 0 && fetchErr != nil {
+		return nil, nil, fetchErr
+	}
+	return rv, lag, nil
+}
+
+func (s *kafkaSubscription) Commit(ctx context.Context) error {
+	return s.client.CommitUncommittedOffsets(ctx)
+}
+
+func (s *kafkaSubscription) Close() error {
+	s.client.Close()
+	return nil
+}
@@ -0,0 +1,181 @@
+package messaging
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+// memoryFetchLimit bounds the records of a topic returned by a fetch, so that a backlog shows as lag
+const memoryFetchLimit = 100
+
+// MemoryBroker is an in-process broker with a single partition per topic; the members of a group share its
+// position, the records fetched and not committed are fetched again once a member closes its subscription
+type MemoryBroker struct {
+	mu     sync.Mutex
+	topics map[string][]Record
+	groups map[string]*memoryGroup
+	notify chan struct{}
+	closed bool
+}
+
+type memoryGroup struct {
+	committed map[string]int64
+	position  map[string]int64
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		topics: make(map[string][]Record),
+		groups: make(map[string]*memoryGroup),
+		notify: make(chan struct{}),
+	}
+}
+
+func (b *MemoryBroker) Produce(ctx context.Context, records ...Record) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+
+	now := time.Now().UTC()
+	for _, record := range records {
+		record.Offset = int64(len(b.topics[record.Topic]))
+		record.Partition = 0
+		record.Headers = maps.Clone(record.Headers)
+		record.Timestamp = now
+		b.topics[record.Topic] = append(b.topics[record.Topic], record)
+	}
+
+	close(b.notify)
+	b.notify = make(chan struct{})
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(group string, topics []string) (Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, ErrBrokerClosed
+	}
+	if _, ok := b.groups[group]; !ok {
+		b.groups[group] = &memoryGroup{
+			committed: make(map[string]int64),
+			position:  make(map[string]int64),
+		}
+	}
+	return &memorySubscription{
+		broker: b,
+		group:  group,
+		topics: topics,
+	}, nil
+}
+
+func (b *MemoryBroker) Ping(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrBrokerClosed
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.notify)
+	}
+	return nil
+}
+
+// Committed tells the committed offset of a group on a topic, i.e. the offset of its next record
+func (b *MemoryBroker) Committed(group string, topic string) int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if g, ok := b.groups[group]; ok {
+		return g.committed[topic]
+	}
+	return 0
+}
+
+// Records returns a copy of the log of a topic
+func (b *MemoryBroker) Records(topic string) []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Record(nil), b.topics[topic]...)
+}
+
+type memorySubscription struct {
+	broker *MemoryBroker
+	group  string
+	topics []string
+}
+
+func (s *memorySubscription) Fetch(ctx context.Context) ([]Record, map[string]int64, error) {
+	for {
+		s.broker.mu.Lock()
+		if s.broker.closed {
+			s.broker.mu.Unlock()
+			return nil, nil, ErrBrokerClosed
+		}
+
+		group := s.broker.groups[s.group]
+		rv := make([]Record, 0)
+		lag := make(map[string]int64)
+		for _, topic := range s.topics {
+			log := s.broker.topics[topic]
+			from := group.position[topic]
+			to := min(from+memoryFetchLimit, int64(len(log)))
+			if from >= to {
+				continue
+			}
+			rv = append(rv, log[from:to]...)
+			group.position[topic] = to
+			lag[topic] = int64(len(log)) - to
+		}
+		notify := s.broker.notify
+		s.broker.mu.Unlock()
+
+		if len(rv) > 0 {
+			return rv, lag, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-notify:
+		}
+	}
+}
+
+func (s *memorySubscription) Commit(ctx context.Context) error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	group := s.broker.groups[s.group]
+	for _, topic := range s.topics {
+		group.committed[topic] = group.position[topic]
+	}
+	return nil
+}
+
+func (s *memorySubscription) Close() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	group := s.broker.groups[s.group]
+	for _, topic := range s.topics {
+		group.position[topic] = group.committed[topic]
+	}
+	return nil
+}
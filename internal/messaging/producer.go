@@ -0,0 +1,62 @@
+package messaging
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/serve"
+)
+
+const tracerName = "github.com/morphy76/g-fe-server/internal/messaging"
+
+// Producer sends records within the trace and on behalf of the tenant of the context
+type Producer struct {
+	broker Broker
+}
+
+func NewProducer(broker Broker) *Producer {
+	return &Producer{broker: broker}
+}
+
+// Send produces a record to the topic; the trace context and the tenant of ctx travel in the headers unless the
+// given headers set them already
+func (p *Producer) Send(ctx context.Context, topic string, key []byte, value []byte, headers map[string]string) error {
+
+	sendContext, span := otel.Tracer(tracerName).Start(ctx, "messaging.Send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", topic),
+		),
+	)
+	defer span.End()
+
+	record := Record{
+		Topic:   topic,
+		Key:     key,
+		Value:   value,
+		Headers: make(map[string]string, len(headers)+3),
+	}
+	otel.GetTextMapPropagator().Inject(sendContext, propagation.MapCarrier(record.Headers))
+	if ownership, ok := app_http.LookupOwnership(ctx); ok && ownership.Tenant != "" {
+		record.Headers[HEADER_TENANT] = ownership.Tenant
+	}
+	for header, value := range headers {
+		record.Headers[header] = value
+	}
+
+	if err := p.broker.Produce(sendContext, record); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		serve.MessagingProducedTotal.WithLabelValues(topic, "failed").Inc()
+		return err
+	}
+	serve.MessagingProducedTotal.WithLabelValues(topic, "sent").Inc()
+	return nil
+}
@@ -1,21 +1,62 @@
 package options
 
+import "time"
+
 type RepositoryType int8
 
 const (
-	RepositoryTypeMemoryDB RepositoryType = iota
-	RepositoryTypeMongoDB  RepositoryType = 1
+	RepositoryTypeMemoryDB   RepositoryType = iota
+	RepositoryTypeMongoDB    RepositoryType = 1
+	RepositoryTypePostgreSQL RepositoryType = 2
+	RepositoryTypeSQLite     RepositoryType = 3
 )
 
+func (t RepositoryType) IsSQL() bool {
+	return t == RepositoryTypePostgreSQL || t == RepositoryTypeSQLite
+}
+
+type MemoryDbOptions struct {
+	// SeedFile is a JSON document of the initial stores, used when there is no snapshot to reload
+	SeedFile string
+	// SnapshotFile receives the stores every SnapshotInterval and on close, it is reloaded on start
+	SnapshotFile     string
+	SnapshotInterval time.Duration
+}
+
 type MongoDbOptions struct {
 	Url         string
 	User        string
 	Password    string
 	MaxPoolSize uint64
 	MinPoolSize uint64
+	// Migrate applies the pending schema migrations when the service starts
+	Migrate bool
+}
+
+type SqlDbOptions struct {
+	// Dsn is a PostgreSQL connection string or a SQLite file name
+	Dsn          string
+	MaxOpenConns int
+}
+
+type CacheOptions struct {
+	// Size bounds the entries of each in-process cache, 0 disables the in-process tier
+	Size int
+	TTL  time.Duration
+	// RedisUrl enables the shared tier, e.g. redis://<user>:<pass>@<host>:<port>/<db>
+	RedisUrl string
+	RedisTTL time.Duration
+}
+
+func (o CacheOptions) Enabled() bool {
+	return o.Size > 0 || o.RedisUrl != ""
 }
 
 type DbOptions struct {
+	MemoryDbOptions
 	MongoDbOptions
+	SqlDbOptions
 	Type RepositoryType
+	// Cache configures the read-through cache of the repositories, disabled by default
+	Cache CacheOptions
 }
@@ -0,0 +1,15 @@
+package options
+
+type MessagingOptions struct {
+	// Brokers are the kafka seed brokers, the messaging subsystem is disabled without them
+	Brokers  []string
+	ClientId string
+	// ConsumerGroup is the group of the consumers of the service, replicas share the partitions
+	ConsumerGroup string
+	// ExampleTopic receives the change events of the examples
+	ExampleTopic string
+}
+
+func (o MessagingOptions) Enabled() bool {
+	return len(o.Brokers) > 0
+}
@@ -1,9 +1,13 @@
 package options
 
 type OidcOptions struct {
-	Disabled     bool
-	Issuer       string
-	ClientId     string
-	ClientSecret string
-	Scopes       []string
+	Disabled      bool
+	Issuer        string
+	ClientId      string
+	ClientSecret  string
+	Scopes        []string
+	ProfileClaims map[string]string
+	// RolesField is the profile field, mapped by ProfileClaims, holding the roles of the user
+	RolesField string
+	AdminRole  string
 }
@@ -0,0 +1,25 @@
+package options
+
+import "time"
+
+type OutboxPublisher string
+
+const (
+	OutboxPublisherLog     OutboxPublisher = "log"
+	OutboxPublisherWebhook OutboxPublisher = "webhook"
+	OutboxPublisherKafka   OutboxPublisher = "kafka"
+)
+
+type OutboxOptions struct {
+	// Enabled makes the repositories append their changes to the outbox and the service relay them
+	Enabled   bool
+	Publisher OutboxPublisher
+	// WebhookUrl receives the events as JSON documents with the webhook publisher, the kafka publisher produces
+	// them on the example topic of the messaging options
+	WebhookUrl   string
+	PollInterval time.Duration
+	BatchSize    int
+	// RetryBackoff doubles after every failed attempt of an event, up to MaxBackoff
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+}
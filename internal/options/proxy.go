@@ -0,0 +1,50 @@
+package options
+
+import "regexp"
+
+// HeaderPlaceholders can be referenced as {name} in the values of added headers, they resolve
+// against the proxied request: tenant and subscription from the ownership, subject and
+// access_token from the HTTP session
+var HeaderPlaceholders = []string{"tenant", "subscription", "subject", "access_token"}
+
+// headerPlaceholder matches a {name} reference in the value of an added header
+var headerPlaceholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// PlaceholdersOf lists the names referenced by the value of an added header, known or not
+func PlaceholdersOf(value string) []string {
+	rv := make([]string, 0)
+	for _, match := range headerPlaceholder.FindAllStringSubmatch(value, -1) {
+		rv = append(rv, match[1])
+	}
+	return rv
+}
+
+// ExpandPlaceholders replaces the references in the value of an added header, it is not resolved when
+// any lookup is empty
+func ExpandPlaceholders(value string, lookup func(name string) string) (string, bool) {
+	resolved := true
+	rv := headerPlaceholder.ReplaceAllStringFunc(value, func(placeholder string) string {
+		expanded := lookup(placeholder[1 : len(placeholder)-1])
+		resolved = resolved && expanded != ""
+		return expanded
+	})
+	return rv, resolved
+}
+
+// HeaderPolicy filters the headers of one direction of the proxied exchange; when Allow is not
+// empty only the listed headers pass, Deny drops headers anyway, Add sets headers afterwards
+type HeaderPolicy struct {
+	Allow []string          `json:"allow,omitempty"`
+	Deny  []string          `json:"deny,omitempty"`
+	Add   map[string]string `json:"add,omitempty"`
+}
+
+type ResourceHeaderPolicy struct {
+	Request  HeaderPolicy `json:"request"`
+	Response HeaderPolicy `json:"response"`
+}
+
+type ProxyOptions struct {
+	// HeaderPolicies by announced resource, e.g. /example, the * resource applies to the others
+	HeaderPolicies map[string]ResourceHeaderPolicy
+}
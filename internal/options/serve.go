@@ -1,6 +1,9 @@
 package options
 
-import "net/http"
+import (
+	"net/http"
+	"time"
+)
 
 type ServeOptions struct {
 	ContextRoot          string
@@ -18,4 +21,7 @@ type ServeOptions struct {
 	AnnouncePort         string
 	AnnounceHost         string
 	CallbackUrl          string
+	ApiAuthRequired      bool
+	// WebSocketIdleTimeout closes upgraded connections without traffic, 0 keeps them open
+	WebSocketIdleTimeout time.Duration
 }
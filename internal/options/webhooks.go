@@ -0,0 +1,17 @@
+package options
+
+import "time"
+
+type WebhooksOptions struct {
+	// Enabled fans the events of the outbox out to the webhooks of their tenant and dispatches the deliveries
+	Enabled      bool
+	PollInterval time.Duration
+	BatchSize    int
+	// Timeout bounds a post, it must stay well below the lease of the claimed deliveries
+	Timeout time.Duration
+	// RetryBackoff doubles after every failed attempt of a delivery, up to MaxBackoff; a delivery failing
+	// MaxAttempts times is failed and waits for a replay
+	RetryBackoff time.Duration
+	MaxBackoff   time.Duration
+	MaxAttempts  int
+}
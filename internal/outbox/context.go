@@ -0,0 +1,27 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+type ContextOutboxOptionsKey string
+
+const ctx_OUTBOX_OPTIONS_KEY ContextOutboxOptionsKey = "outboxOptions"
+
+// ExtractOptions returns the outbox options of the context, nil when there are none
+func ExtractOptions(ctx context.Context) *options.OutboxOptions {
+	rv, _ := ctx.Value(ctx_OUTBOX_OPTIONS_KEY).(*options.OutboxOptions)
+	return rv
+}
+
+func InjectOptions(ctx context.Context, outboxOptions *options.OutboxOptions) context.Context {
+	return context.WithValue(ctx, ctx_OUTBOX_OPTIONS_KEY, outboxOptions)
+}
+
+// Enabled tells whether the repositories of the context append their changes to the outbox
+func Enabled(ctx context.Context) bool {
+	outboxOptions := ExtractOptions(ctx)
+	return outboxOptions != nil && outboxOptions.Enabled
+}
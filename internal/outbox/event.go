@@ -0,0 +1,57 @@
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	model "github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+// NewEvent describes the change of an entity on behalf of the tenant and the trace of the context, after is the
+// state of the entity after the change, nil for deletions; the event is due immediately
+func NewEvent(ctx context.Context, entity string, entityId string, operation string, version int64, after any) (model.Event, error) {
+
+	idBytes := make([]byte, 12)
+	if _, err := rand.Read(idBytes); err != nil {
+		return model.Event{}, err
+	}
+
+	now := time.Now().UTC()
+	rv := model.Event{
+		Message: model.Message{
+			Id:         hex.EncodeToString(idBytes),
+			Type:       entity + "." + operation,
+			Entity:     entity,
+			EntityId:   entityId,
+			Version:    version,
+			OccurredAt: now,
+		},
+		NextAttemptAt: now,
+	}
+
+	if after != nil {
+		payload, err := json.Marshal(after)
+		if err != nil {
+			return model.Event{}, err
+		}
+		rv.Payload = payload
+	}
+	if ownership, ok := app_http.LookupOwnership(ctx); ok {
+		rv.Tenant = ownership.Tenant
+	}
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) > 0 {
+		rv.TraceContext = carrier
+	}
+
+	return rv, nil
+}
@@ -0,0 +1,159 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/morphy76/g-fe-server/internal/messaging"
+	"github.com/morphy76/g-fe-server/internal/options"
+	model "github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+// webhookTimeout bounds a delivery, it must stay well below the lease of the claimed events
+const webhookTimeout = 10 * time.Second
+
+// NewPublisher builds the publisher chosen by the options, the kafka publisher requires the broker of the
+// messaging subsystem
+func NewPublisher(outboxOptions *options.OutboxOptions, messagingOptions *options.MessagingOptions, broker messaging.Broker) (model.Publisher, error) {
+	switch outboxOptions.Publisher {
+	case options.OutboxPublisherLog:
+		return &logPublisher{}, nil
+	case options.OutboxPublisherWebhook:
+		return NewWebhookPublisher(outboxOptions.WebhookUrl, &http.Client{Timeout: webhookTimeout}), nil
+	case options.OutboxPublisherKafka:
+		if broker == nil {
+			return nil, model.ErrMissingBroker
+		}
+		return NewKafkaPublisher(messaging.NewProducer(broker), messagingOptions.ExampleTopic), nil
+	default:
+		return nil, model.ErrUnknownPublisher
+	}
+}
+
+// logPublisher writes the events to the application log, handy to follow the outbox locally
+type logPublisher struct{}
+
+func (p *logPublisher) Name() string {
+	return string(options.OutboxPublisherLog)
+}
+
+func (p *logPublisher) Publish(ctx context.Context, e model.Event) error {
+	log.Info().
+		Str("id", e.Id).
+		Str("type", e.Type).
+		Str("entity_id", e.EntityId).
+		Str("tenant", e.Tenant).
+		Int64("version", e.Version).
+		RawJSON("payload", payloadOrNull(e.Payload)).
+		Msg("Outbox event")
+	return nil
+}
+
+func (p *logPublisher) Close() error {
+	return nil
+}
+
+// webhookPublisher posts the message of the events to a URL, any answer but 2xx is a failure
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookPublisher(url string, client *http.Client) model.Publisher {
+	return &webhookPublisher{
+		url:    url,
+		client: client,
+	}
+}
+
+func (p *webhookPublisher) Name() string {
+	return string(options.OutboxPublisherWebhook)
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, e model.Event) error {
+
+	body, err := json.Marshal(e.Message)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", e.Id)
+	req.Header.Set("X-Event-Type", e.Type)
+	for key, value := range e.TraceContext {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (p *webhookPublisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+// kafkaPublisher produces the message of the events keyed by entity id, so that the changes of an entity land
+// on the same partition; the producer propagates the trace of the publish span
+type kafkaPublisher struct {
+	producer *messaging.Producer
+	topic    string
+}
+
+func NewKafkaPublisher(producer *messaging.Producer, topic string) model.Publisher {
+	return &kafkaPublisher{
+		producer: producer,
+		topic:    topic,
+	}
+}
+
+func (p *kafkaPublisher) Name() string {
+	return string(options.OutboxPublisherKafka)
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, e model.Event) error {
+
+	value, err := json.Marshal(e.Message)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		messaging.HEADER_EVENT_ID:   e.Id,
+		messaging.HEADER_EVENT_TYPE: e.Type,
+	}
+	if e.Tenant != "" {
+		headers[messaging.HEADER_TENANT] = e.Tenant
+	}
+	return p.producer.Send(ctx, p.topic, []byte(e.EntityId), value, headers)
+}
+
+// Close leaves the broker open, it is shared with the other producers and consumers of the service
+func (p *kafkaPublisher) Close() error {
+	return nil
+}
+
+func payloadOrNull(payload json.RawMessage) []byte {
+	if len(payload) == 0 {
+		return []byte("null")
+	}
+	return payload
+}
@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/poll"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	model "github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+const tracerName = "github.com/morphy76/g-fe-server/internal/outbox"
+
+// RELAY_LEASE is how long a claimed event is hidden from the other relays, deliveries must end before it expires
+const RELAY_LEASE = time.Minute
+
+// Relay delivers the due events of the outbox to the publisher; an event is marked sent only after the publisher
+// accepted it, so that a crash in between delivers it again
+type Relay struct {
+	repository model.Repository
+	publisher  model.Publisher
+	options    options.OutboxOptions
+}
+
+func NewRelay(repository model.Repository, publisher model.Publisher, outboxOptions options.OutboxOptions) *Relay {
+	return &Relay{
+		repository: repository,
+		publisher:  publisher,
+		options:    outboxOptions,
+	}
+}
+
+// Run relays the outbox every poll interval until the context is done
+func (r *Relay) Run(ctx context.Context) {
+	poll.Run(ctx, r.options.PollInterval, r.options.BatchSize, r.RelayOnce, "Outbox relay failed")
+}
+
+// RelayOnce claims a batch of due events and delivers them, it returns the number of claimed events
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+
+	events, err := r.repository.Claim(ctx, time.Now().UTC(), RELAY_LEASE, r.options.BatchSize)
+	if err != nil {
+		return len(events), err
+	}
+
+	for _, e := range events {
+		if err := r.deliver(ctx, e); err != nil {
+			return len(events), err
+		}
+	}
+	return len(events), nil
+}
+
+// deliver publishes the event within the trace of the change and settles it, the error is the one of settling
+func (r *Relay) deliver(ctx context.Context, e model.Event) error {
+
+	traceContext := otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(e.TraceContext))
+	publishContext, span := otel.Tracer(tracerName).Start(traceContext, "outbox.Publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", r.publisher.Name()),
+			attribute.String("messaging.message.id", e.Id),
+			attribute.String("outbox.event.type", e.Type),
+			attribute.Int("outbox.event.attempts", e.Attempts),
+		),
+	)
+	defer span.End()
+
+	publishErr := r.publisher.Publish(publishContext, e)
+	now := time.Now().UTC()
+	if publishErr == nil {
+		serve.OutboxDeliveriesTotal.WithLabelValues(r.publisher.Name(), "sent").Inc()
+		return r.repository.MarkSent(ctx, e.Id, now)
+	}
+
+	span.RecordError(publishErr)
+	span.SetStatus(codes.Error, publishErr.Error())
+	serve.OutboxDeliveriesTotal.WithLabelValues(r.publisher.Name(), "failed").Inc()
+
+	nextAttemptAt := now.Add(r.backoff(e.Attempts + 1))
+	log.Warn().
+		Err(publishErr).
+		Str("id", e.Id).
+		Str("type", e.Type).
+		Int("attempts", e.Attempts+1).
+		Time("next_attempt_at", nextAttemptAt).
+		Msg("Outbox delivery failed")
+	return r.repository.MarkFailed(ctx, e.Id, nextAttemptAt, publishErr.Error())
+}
+
+// backoff spaces the deliveries of a failing event
+func (r *Relay) backoff(attempts int) time.Duration {
+	return poll.Backoff(r.options.RetryBackoff, r.options.MaxBackoff, attempts)
+}
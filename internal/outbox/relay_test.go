@@ -0,0 +1,137 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+	impl "github.com/morphy76/g-fe-server/internal/outbox/repository/impl"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	model "github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+// flakyPublisher fails the first failures publications
+type flakyPublisher struct {
+	failures  int
+	published []model.Event
+}
+
+func (p *flakyPublisher) Name() string {
+	return "flaky"
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, e model.Event) error {
+	if p.failures > 0 {
+		p.failures--
+		return errors.New("downstream unavailable")
+	}
+	p.published = append(p.published, e)
+	return nil
+}
+
+func (p *flakyPublisher) Close() error {
+	return nil
+}
+
+func TestRelaySuite(t *testing.T) {
+	t.Log("Test Relay Suite")
+
+	ctx := app_http.InjectOwnership(context.Background(), serve.Ownership{Tenant: "relayed"})
+	relayOptions := options.OutboxOptions{
+		PollInterval: time.Second,
+		BatchSize:    10,
+		RetryBackoff: time.Second,
+		MaxBackoff:   4 * time.Second,
+	}
+
+	t.Run("Test Backoff", func(t *testing.T) {
+		t.Log("Testing Backoff")
+
+		relay := NewRelay(nil, nil, relayOptions)
+		for attempts, expected := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+			if backoff := relay.backoff(attempts); backoff != expected {
+				t.Errorf("Expected %s after %d attempts, got %s", expected, attempts, backoff)
+			}
+		}
+	})
+
+	t.Run("Test Retries", func(t *testing.T) {
+		t.Log("Testing Retries")
+
+		memoryClient := db.NewMemoryDbClient()
+		repository, _ := impl.NewMemoryRepository(memoryClient)
+		table, _ := db.MemoryTableOf(memoryClient, impl.MEMORY_STORE, func(e model.Event) string { return e.Id })
+		publisher := &flakyPublisher{failures: 1}
+		relay := NewRelay(repository, publisher, relayOptions)
+
+		event, err := NewEvent(ctx, "example", "relayed", "create", 1, map[string]any{"name": "relayed"})
+		if err != nil {
+			t.Fatalf("Error on NewEvent: %s", err)
+		}
+		if event.Tenant != "relayed" || event.Type != "example.create" {
+			t.Fatalf("Expected the event of the context, got %#v", event)
+		}
+		if err := repository.Append(ctx, event); err != nil {
+			t.Fatalf("Error on Append: %s", err)
+		}
+
+		if claimed, err := relay.RelayOnce(ctx); err != nil || claimed != 1 {
+			t.Fatalf("Expected the event to be claimed, got %d: %v", claimed, err)
+		}
+		if claimed, _ := relay.RelayOnce(ctx); claimed != 0 {
+			t.Fatalf("Expected the failed event to wait for its retry, got %d", claimed)
+		}
+
+		failed := table.Rows[event.Id]
+		if failed.Attempts != 1 || failed.LastError != "downstream unavailable" || !failed.NextAttemptAt.After(time.Now()) {
+			t.Fatalf("Expected the failed attempt to be recorded, got %#v", failed)
+		}
+		// the retry is due
+		failed.NextAttemptAt = time.Now()
+		table.Rows[event.Id] = failed
+
+		if claimed, err := relay.RelayOnce(ctx); err != nil || claimed != 1 || len(publisher.published) != 1 {
+			t.Fatalf("Expected the event to be delivered, got %d: %v", claimed, err)
+		}
+		if sent := table.Rows[event.Id]; sent.SentAt == nil || sent.Attempts != 2 || sent.LastError != "" {
+			t.Errorf("Expected the sent event to be settled, got %#v", sent)
+		}
+	})
+
+	t.Run("Test Webhook", func(t *testing.T) {
+		t.Log("Testing Webhook")
+
+		var received model.Message
+		var eventType string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			eventType = r.Header.Get("X-Event-Type")
+			json.NewDecoder(r.Body).Decode(&received)
+			if received.EntityId == "rejected" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		publisher := NewWebhookPublisher(server.URL, server.Client())
+
+		event, _ := NewEvent(ctx, "example", "hooked", "update", 2, map[string]any{"name": "hooked"})
+		if err := publisher.Publish(ctx, event); err != nil {
+			t.Fatalf("Error on Publish: %s", err)
+		}
+		if received.Id != event.Id || eventType != "example.update" || string(received.Payload) != `{"name":"hooked"}` {
+			t.Errorf("Expected the message of the event, got %s %#v", eventType, received)
+		}
+
+		event, _ = NewEvent(ctx, "example", "rejected", "delete", 0, nil)
+		if err := publisher.Publish(ctx, event); err == nil {
+			t.Error("Expected an error status to fail the delivery")
+		}
+	})
+}
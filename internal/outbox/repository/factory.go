@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+	impl "github.com/morphy76/g-fe-server/internal/outbox/repository/impl"
+	model "github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+// NewRepository builds the outbox of the storage configured in the context and the transactor binding the writes
+// of the repositories of the same storage to the events they append; sql storages have no outbox yet
+func NewRepository(requestContext context.Context) (model.Repository, model.Transactor, error) {
+
+	dbOptions := db.ExtractDbOptions(requestContext)
+	dbClient := db.ExtractDb(requestContext)
+
+	switch dbOptions.Type {
+	case options.RepositoryTypeMemoryDB:
+		memoryClient, ok := dbClient.(*db.MemoryDbClient)
+		if !ok {
+			return nil, nil, errors.New("memory client not found in request context")
+		}
+
+		rv, err := impl.NewMemoryRepository(memoryClient)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return newTracedRepository(rv, "memory"), impl.MemoryTransactor, nil
+	case options.RepositoryTypeMongoDB:
+		if dbClient == nil {
+			return nil, nil, errors.New("MongoDB client not found in request context")
+		}
+
+		mongoClient := dbClient.(*mongo.Client)
+
+		var rv model.Repository = &impl.MongoRepository{
+			DbOptions: dbOptions,
+			Client:    mongoClient,
+		}
+
+		return newTracedRepository(rv, "mongodb"), impl.MongoTransactor(mongoClient), nil
+	default:
+		return nil, nil, model.ErrUnknownRepositoryType
+	}
+}
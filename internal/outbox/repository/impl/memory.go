@@ -0,0 +1,126 @@
+package outbox
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+// MEMORY_STORE names the outbox in the stores of the memory client
+const MEMORY_STORE = "outbox"
+
+func eventId(e outbox.Event) string {
+	return e.Id
+}
+
+// SENT_RETENTION is how long the sent events are kept, as by the TTL index of MongoDB
+const SENT_RETENTION = 7 * 24 * time.Hour
+
+// MemoryRepository keeps the sent events for SENT_RETENTION as well, so that snapshots tell what was delivered
+type MemoryRepository struct {
+	table *db.MemoryTable[string, outbox.Event]
+}
+
+func NewMemoryRepository(client *db.MemoryDbClient) (outbox.Repository, error) {
+
+	table, err := db.MemoryTableOf(client, MEMORY_STORE, eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemoryRepository{table: table}, nil
+}
+
+// MemoryTransactor runs fn as is, the memory client has no transactions
+func MemoryTransactor(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (r *MemoryRepository) Append(ctx context.Context, events ...outbox.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.table.Lock.Lock()
+	defer r.table.Lock.Unlock()
+
+	for _, e := range events {
+		r.table.Rows[e.Id] = e
+	}
+	return nil
+}
+
+func (r *MemoryRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]outbox.Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.table.Lock.Lock()
+	defer r.table.Lock.Unlock()
+
+	rv := make([]outbox.Event, 0)
+	for _, e := range r.table.Rows {
+		if e.SentAt == nil && !e.NextAttemptAt.After(now) {
+			rv = append(rv, e)
+		}
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		if rv[i].NextAttemptAt.Equal(rv[j].NextAttemptAt) {
+			return rv[i].OccurredAt.Before(rv[j].OccurredAt)
+		}
+		return rv[i].NextAttemptAt.Before(rv[j].NextAttemptAt)
+	})
+	if len(rv) > limit {
+		rv = rv[:limit]
+	}
+
+	for _, e := range rv {
+		e.NextAttemptAt = now.Add(lease)
+		r.table.Rows[e.Id] = e
+	}
+	return rv, nil
+}
+
+func (r *MemoryRepository) MarkSent(ctx context.Context, id string, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.table.Lock.Lock()
+	defer r.table.Lock.Unlock()
+
+	if e, ok := r.table.Rows[id]; ok {
+		e.Attempts++
+		e.SentAt = &at
+		e.LastError = ""
+		r.table.Rows[id] = e
+	}
+
+	expired := at.Add(-SENT_RETENTION)
+	for key, e := range r.table.Rows {
+		if e.SentAt != nil && e.SentAt.Before(expired) {
+			delete(r.table.Rows, key)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.table.Lock.Lock()
+	defer r.table.Lock.Unlock()
+
+	if e, ok := r.table.Rows[id]; ok {
+		e.Attempts++
+		e.NextAttemptAt = nextAttemptAt
+		e.LastError = reason
+		r.table.Rows[id] = e
+	}
+	return nil
+}
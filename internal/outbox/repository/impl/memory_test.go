@@ -0,0 +1,54 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+func TestMemoryRepositorySuite(t *testing.T) {
+	t.Log("Test MemoryRepository Suite")
+
+	ctx := context.Background()
+	client := db.NewMemoryDbClient()
+	repo, _ := NewMemoryRepository(client)
+	table, _ := db.MemoryTableOf(client, MEMORY_STORE, eventId)
+	t.Logf("Repository URL: memory")
+
+	now := time.Now().UTC()
+
+	t.Run("Test Sent Retention", func(t *testing.T) {
+		t.Log("Testing Memory Sent Retention")
+
+		events := make([]outbox.Event, 0, 3)
+		for _, id := range []string{"old", "new", "due"} {
+			events = append(events, outbox.Event{Message: outbox.Message{Id: id}, NextAttemptAt: now})
+		}
+		if err := repo.Append(ctx, events...); err != nil {
+			t.Fatalf("Error on Append: %s", err)
+		}
+
+		if err := repo.MarkSent(ctx, "old", now.Add(-SENT_RETENTION-time.Minute)); err != nil {
+			t.Fatalf("Error on MarkSent: %s", err)
+		}
+		if _, found := table.Rows["old"]; !found {
+			t.Fatalf("Expected the sent event to be kept within the retention")
+		}
+
+		if err := repo.MarkSent(ctx, "new", now); err != nil {
+			t.Fatalf("Error on MarkSent: %s", err)
+		}
+		if _, found := table.Rows["old"]; found {
+			t.Fatalf("Expected the event sent before the retention to be pruned")
+		}
+		if sent := table.Rows["new"]; sent.SentAt == nil {
+			t.Fatalf("Expected the sent event to be kept, got %#v", sent)
+		}
+		if due := table.Rows["due"]; due.SentAt != nil {
+			t.Fatalf("Expected the pending event to be kept, got %#v", due)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_opts "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+const MONGO_COLLECTION = "outbox"
+
+type MongoRepository struct {
+	DbOptions  *options.DbOptions
+	Client     *mongo.Client
+	collection *mongo.Collection
+}
+
+// MongoTransactor runs fn in a transaction of a new session, which requires MongoDB to run as a replica set;
+// fn is run again when the transaction fails with a transient error
+func MongoTransactor(client *mongo.Client) outbox.Transactor {
+	return func(ctx context.Context, fn func(ctx context.Context) error) error {
+
+		session, err := client.StartSession()
+		if err != nil {
+			return err
+		}
+		defer session.EndSession(context.WithoutCancel(ctx))
+
+		_, err = session.WithTransaction(ctx, func(sessionContext mongo.SessionContext) (any, error) {
+			return nil, fn(sessionContext)
+		})
+		return err
+	}
+}
+
+func (r *MongoRepository) Append(ctx context.Context, events ...outbox.Event) error {
+
+	if err := r.lazyBindCollection(); err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	documents := make([]any, len(events))
+	for i, e := range events {
+		documents[i] = e
+	}
+	_, err := r.collection.InsertMany(ctx, documents)
+	return err
+}
+
+// Claim leases the due events one at a time, each lease is an atomic update so that relays never claim the
+// same event while the lease holds
+func (r *MongoRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]outbox.Event, error) {
+
+	if err := r.lazyBindCollection(); err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{
+		{Key: "sent_at", Value: nil},
+		{Key: "next_attempt_at", Value: bson.D{{Key: "$lte", Value: now}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "next_attempt_at", Value: now.Add(lease)}}}}
+	findOptions := mongo_opts.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "occurred_at", Value: 1}})
+
+	rv := make([]outbox.Event, 0)
+	for len(rv) < limit {
+		e := outbox.Event{}
+		err := r.collection.FindOneAndUpdate(ctx, filter, update, findOptions).Decode(&e)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return rv, err
+		}
+		rv = append(rv, e)
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) MarkSent(ctx context.Context, id string, at time.Time) error {
+
+	if err := r.lazyBindCollection(); err != nil {
+		return err
+	}
+
+	update := bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+		{Key: "$set", Value: bson.D{{Key: "sent_at", Value: at}}},
+		{Key: "$unset", Value: bson.D{{Key: "last_error", Value: ""}}},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.D{{Key: "id", Value: id}}, update)
+	return err
+}
+
+func (r *MongoRepository) MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, reason string) error {
+
+	if err := r.lazyBindCollection(); err != nil {
+		return err
+	}
+
+	update := bson.D{
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+		{Key: "$set", Value: bson.D{{Key: "next_attempt_at", Value: nextAttemptAt}, {Key: "last_error", Value: reason}}},
+	}
+	_, err := r.collection.UpdateOne(ctx, bson.D{{Key: "id", Value: id}}, update)
+	return err
+}
+
+func (r *MongoRepository) lazyBindCollection() error {
+	if r.collection == nil {
+		database, err := db.MongoDatabase(r.Client, r.DbOptions)
+		if err != nil {
+			return err
+		}
+		r.collection = database.Collection(MONGO_COLLECTION)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	impl "github.com/morphy76/g-fe-server/internal/outbox/repository/impl"
+	model "github.com/morphy76/g-fe-server/pkg/outbox"
+)
+
+const tracerName = "github.com/morphy76/g-fe-server/internal/outbox/repository"
+
+type tracedRepository struct {
+	delegate model.Repository
+	system   string
+}
+
+func newTracedRepository(delegate model.Repository, system string) model.Repository {
+	return &tracedRepository{
+		delegate: delegate,
+		system:   system,
+	}
+}
+
+func (r *tracedRepository) Append(ctx context.Context, events ...model.Event) error {
+	ctx, span := r.start(ctx, "Append")
+	span.SetAttributes(attribute.Int("db.operation.batch.size", len(events)))
+	err := r.delegate.Append(ctx, events...)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.Event, error) {
+	ctx, span := r.start(ctx, "Claim")
+	span.SetAttributes(attribute.Int("db.query.limit", limit))
+	rv, err := r.delegate.Claim(ctx, now, lease, limit)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) MarkSent(ctx context.Context, id string, at time.Time) error {
+	ctx, span := r.start(ctx, "MarkSent")
+	err := r.delegate.MarkSent(ctx, id, at)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, reason string) error {
+	ctx, span := r.start(ctx, "MarkFailed")
+	err := r.delegate.MarkFailed(ctx, id, nextAttemptAt, reason)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "outbox."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", r.system),
+			attribute.String("db.collection.name", impl.MONGO_COLLECTION),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,45 @@
+package poll
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Batch handles a batch of at most the batch size of the poller, it returns how many items it claimed
+type Batch func(ctx context.Context) (int, error)
+
+// Run calls the batch every interval until the context is done, full batches are followed by another one
+// straight away; failures are logged with the message and wait for the next tick
+func Run(ctx context.Context, interval time.Duration, batchSize int, batch Batch, failure string) {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				claimed, err := batch(ctx)
+				if err != nil {
+					log.Error().Err(err).Msg(failure)
+				}
+				if err != nil || claimed < batchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Backoff is the delay after the given number of failed attempts, doubling from base up to max
+func Backoff(base time.Duration, max time.Duration, attempts int) time.Duration {
+	rv := base
+	for i := 1; i < attempts && rv < max; i++ {
+		rv *= 2
+	}
+	return min(rv, max)
+}
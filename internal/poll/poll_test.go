@@ -0,0 +1,72 @@
+package poll
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPollSuite(t *testing.T) {
+	t.Log("Test Poll Suite")
+
+	t.Run("Test Backoff", func(t *testing.T) {
+		t.Log("Testing Backoff")
+
+		for attempts, expected := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+			if backoff := Backoff(time.Second, 4*time.Second, attempts); backoff != expected {
+				t.Errorf("Expected %s after %d attempts, got %s", expected, attempts, backoff)
+			}
+		}
+		if backoff := Backoff(3*time.Second, 4*time.Second, 2); backoff != 4*time.Second {
+			t.Errorf("Expected the backoff to stop at the maximum, got %s", backoff)
+		}
+	})
+
+	t.Run("Test Run", func(t *testing.T) {
+		t.Log("Testing Run")
+
+		var mu sync.Mutex
+		// the first tick drains two full batches, the second one stops on the failure
+		claims := []int{2, 2, 1, 2, 2, -1, 0}
+		calls := 0
+		at := make([]time.Time, 0, len(claims))
+		interval := 50 * time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			Run(ctx, interval, 2, func(ctx context.Context) (int, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if calls == len(claims) {
+					cancel()
+					return 0, nil
+				}
+				claimed := claims[calls]
+				calls++
+				at = append(at, time.Now())
+				if claimed < 0 {
+					return 0, errors.New("claim failed")
+				}
+				return claimed, nil
+			}, "Test poll failed")
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected Run to return once the context is done")
+		}
+		if calls != len(claims) {
+			t.Fatalf("Expected every batch to be polled, got %d", calls)
+		}
+		if at[2].Sub(at[0]) >= interval {
+			t.Errorf("Expected full batches to be followed straight away, got %s", at[2].Sub(at[0]))
+		}
+		if at[3].Sub(at[2]) < interval/2 || at[6].Sub(at[5]) < interval/2 {
+			t.Errorf("Expected short and failed batches to wait for the next tick")
+		}
+	})
+}
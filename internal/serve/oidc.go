@@ -14,6 +14,7 @@ import (
 func SetupOIDC(
 	serveOptions *options.ServeOptions,
 	oidcOptions *options.OidcOptions,
+	nonceFn func(context.Context) string,
 ) (rp.RelyingParty, error) {
 
 	redirectURI := fmt.Sprintf(
@@ -44,8 +45,11 @@ func SetupOIDC(
 	}
 
 	oidcOpts := []rp.Option{
-		rp.WithCookieHandler(cookieHandler),
-		rp.WithVerifierOpts(rp.WithIssuedAtOffset(5 * time.Second)),
+		rp.WithPKCE(cookieHandler),
+		rp.WithVerifierOpts(
+			rp.WithIssuedAtOffset(5*time.Second),
+			rp.WithNonce(nonceFn),
+		),
 		rp.WithHTTPClient(httpClient),
 	}
 
@@ -55,6 +55,133 @@ var (
 		},
 		[]string{"method", "path"},
 	)
+	WebSocketConnectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "websocket_connections_total",
+			Help:      "Number of upgraded WebSocket connections",
+		},
+		[]string{"path"},
+	)
+	WebSocketActiveConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "websocket_active_connections",
+			Help:      "Number of open WebSocket connections",
+		},
+		[]string{"path"},
+	)
+	WebSocketConnectionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "websocket_connection_duration_seconds",
+			Help:      "Lifetime of WebSocket connections",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8),
+		},
+		[]string{"path"},
+	)
+	WebSocketClosedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "websocket_closed_total",
+			Help:      "Number of closed WebSocket connections",
+		},
+		[]string{"path", "reason"},
+	)
+	WebSocketRejectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "websocket_rejected_handshakes_total",
+			Help:      "Number of rejected WebSocket handshakes",
+		},
+		[]string{"path", "reason"},
+	)
+	CacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "cache_requests_total",
+			Help:      "Number of repository cache lookups by result: local_hit, remote_hit or miss",
+		},
+		[]string{"cache", "result"},
+	)
+	CacheCoalescedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "cache_coalesced_total",
+			Help:      "Number of repository cache misses served by the load of a concurrent miss",
+		},
+		[]string{"cache"},
+	)
+	CacheInvalidationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "cache_invalidations_total",
+			Help:      "Number of repository cache keys invalidated by writes",
+		},
+		[]string{"cache"},
+	)
+	CacheEvictionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "cache_evictions_total",
+			Help:      "Number of entries evicted from the in-process repository caches to make room",
+		},
+		[]string{"cache"},
+	)
+	OutboxDeliveriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "outbox_deliveries_total",
+			Help:      "Number of outbox event deliveries by publisher and result: sent or failed",
+		},
+		[]string{"publisher", "result"},
+	)
+	MessagingProducedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "messaging_produced_total",
+			Help:      "Number of produced messages by topic and result: sent or failed",
+		},
+		[]string{"topic", "result"},
+	)
+	MessagingConsumedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "messaging_consumed_total",
+			Help:      "Number of consumed messages by group, topic and result: handled, skipped or failed",
+		},
+		[]string{"group", "topic", "result"},
+	)
+	MessagingConsumerLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "messaging_consumer_lag",
+			Help:      "Number of messages of a topic not fetched by the consumer group yet, as of its last fetch",
+		},
+		[]string{"group", "topic"},
+	)
+	WebhookDeliveriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: PROMETHEUS_NAMESPACE,
+			Subsystem: PROMETHEUS_SUBSYSTEM,
+			Name:      "webhook_deliveries_total",
+			Help:      "Number of tenant webhook posts by result: succeeded, retried or failed",
+		},
+		[]string{"result"},
+	)
 )
 
 func init() {
@@ -64,5 +191,19 @@ func init() {
 		HttpErrorTotal,
 		HttpInFlightRequests,
 		HttpResponseSizeBytes,
+		WebSocketConnectionsTotal,
+		WebSocketActiveConnections,
+		WebSocketConnectionDuration,
+		WebSocketClosedTotal,
+		WebSocketRejectedTotal,
+		CacheRequestsTotal,
+		CacheCoalescedTotal,
+		CacheInvalidationsTotal,
+		CacheEvictionsTotal,
+		OutboxDeliveriesTotal,
+		MessagingProducedTotal,
+		MessagingConsumedTotal,
+		MessagingConsumerLag,
+		WebhookDeliveriesTotal,
 	)
 }
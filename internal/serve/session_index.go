@@ -0,0 +1,162 @@
+package serve
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"sort"
+	"sync"
+	"time"
+)
+
+type SessionInfo struct {
+	Handle     string    `json:"id"`
+	Subject    string    `json:"subject"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+type SessionIndex struct {
+	lock      sync.Mutex
+	maxIdle   time.Duration
+	bySubject map[string]map[string]*SessionInfo
+	subjects  map[string]string
+	revoked   map[string]time.Time
+}
+
+func NewSessionIndex(maxIdle time.Duration) *SessionIndex {
+	return &SessionIndex{
+		maxIdle:   maxIdle,
+		bySubject: make(map[string]map[string]*SessionInfo),
+		subjects:  make(map[string]string),
+		revoked:   make(map[string]time.Time),
+	}
+}
+
+func SessionHandle(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
+
+func (i *SessionIndex) Touch(sessionID string, subject string, device string, ip string, createdAt time.Time) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	now := time.Now().UTC()
+	sessions, ok := i.bySubject[subject]
+	if !ok {
+		sessions = make(map[string]*SessionInfo)
+		i.bySubject[subject] = sessions
+	}
+
+	entry, ok := sessions[sessionID]
+	if !ok {
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		entry = &SessionInfo{
+			Handle:    SessionHandle(sessionID),
+			Subject:   subject,
+			CreatedAt: createdAt.UTC(),
+		}
+		sessions[sessionID] = entry
+		i.subjects[sessionID] = subject
+	}
+	entry.Device = device
+	entry.IP = ip
+	entry.LastSeenAt = now
+}
+
+func (i *SessionIndex) Remove(sessionID string) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.remove(sessionID)
+	delete(i.revoked, sessionID)
+}
+
+func (i *SessionIndex) List(subject string) []SessionInfo {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	i.prune()
+
+	rv := make([]SessionInfo, 0, len(i.bySubject[subject]))
+	for _, entry := range i.bySubject[subject] {
+		rv = append(rv, *entry)
+	}
+	sort.Slice(rv, func(a, b int) bool {
+		return rv[a].LastSeenAt.After(rv[b].LastSeenAt)
+	})
+	return rv
+}
+
+func (i *SessionIndex) Revoke(subject string, handle string) bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	for sessionID, entry := range i.bySubject[subject] {
+		if entry.Handle == handle {
+			i.revoke(sessionID)
+			return true
+		}
+	}
+	return false
+}
+
+func (i *SessionIndex) RevokeAll(subject string) int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	count := 0
+	for sessionID := range i.bySubject[subject] {
+		i.revoke(sessionID)
+		count++
+	}
+	return count
+}
+
+func (i *SessionIndex) IsRevoked(sessionID string) bool {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	_, ok := i.revoked[sessionID]
+	return ok
+}
+
+func (i *SessionIndex) revoke(sessionID string) {
+	i.remove(sessionID)
+	i.revoked[sessionID] = time.Now().UTC()
+}
+
+func (i *SessionIndex) remove(sessionID string) {
+	subject, ok := i.subjects[sessionID]
+	if !ok {
+		return
+	}
+	delete(i.subjects, sessionID)
+	delete(i.bySubject[subject], sessionID)
+	if len(i.bySubject[subject]) == 0 {
+		delete(i.bySubject, subject)
+	}
+}
+
+func (i *SessionIndex) prune() {
+	if i.maxIdle <= 0 {
+		return
+	}
+
+	threshold := time.Now().UTC().Add(-i.maxIdle)
+	for sessionID, subject := range i.subjects {
+		if i.bySubject[subject][sessionID].LastSeenAt.Before(threshold) {
+			i.remove(sessionID)
+		}
+	}
+	for sessionID, revokedAt := range i.revoked {
+		if revokedAt.Before(threshold) {
+			delete(i.revoked, sessionID)
+		}
+	}
+}
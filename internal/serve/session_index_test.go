@@ -0,0 +1,80 @@
+package serve
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionIndexSuite(t *testing.T) {
+	t.Log("Test SessionIndex Suite")
+
+	t.Run("Test Touch and List", func(t *testing.T) {
+		t.Log("Test SessionIndex Touch and List")
+
+		index := NewSessionIndex(0)
+		index.Touch("s1", "alice", "firefox", "10.0.0.1", time.Now())
+		index.Touch("s2", "alice", "curl", "10.0.0.2", time.Time{})
+		index.Touch("s3", "bob", "chrome", "10.0.0.3", time.Now())
+
+		sessions := index.List("alice")
+		if len(sessions) != 2 {
+			t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+		}
+		if sessions[0].Handle == "s1" || sessions[0].Handle == "s2" {
+			t.Fatalf("Expected opaque handles, got %s", sessions[0].Handle)
+		}
+	})
+
+	t.Run("Test Revoke", func(t *testing.T) {
+		t.Log("Test SessionIndex Revoke")
+
+		index := NewSessionIndex(0)
+		index.Touch("s1", "alice", "firefox", "10.0.0.1", time.Now())
+		index.Touch("s2", "bob", "chrome", "10.0.0.3", time.Now())
+
+		if index.Revoke("bob", SessionHandle("s1")) {
+			t.Fatal("Expected revoke of another user's session to fail")
+		}
+		if !index.Revoke("alice", SessionHandle("s1")) {
+			t.Fatal("Expected revoke to succeed")
+		}
+		if !index.IsRevoked("s1") {
+			t.Fatal("Expected s1 to be revoked")
+		}
+		if len(index.List("alice")) != 0 {
+			t.Fatal("Expected no sessions left for alice")
+		}
+
+		index.Remove("s1")
+		if index.IsRevoked("s1") {
+			t.Fatal("Expected s1 to be forgotten")
+		}
+	})
+
+	t.Run("Test RevokeAll", func(t *testing.T) {
+		t.Log("Test SessionIndex RevokeAll")
+
+		index := NewSessionIndex(0)
+		index.Touch("s1", "alice", "firefox", "10.0.0.1", time.Now())
+		index.Touch("s2", "alice", "curl", "10.0.0.2", time.Now())
+
+		if count := index.RevokeAll("alice"); count != 2 {
+			t.Fatalf("Expected 2 revoked sessions, got %d", count)
+		}
+		if !index.IsRevoked("s1") || !index.IsRevoked("s2") {
+			t.Fatal("Expected all sessions to be revoked")
+		}
+	})
+
+	t.Run("Test Prune", func(t *testing.T) {
+		t.Log("Test SessionIndex Prune")
+
+		index := NewSessionIndex(time.Millisecond)
+		index.Touch("s1", "alice", "firefox", "10.0.0.1", time.Now())
+		time.Sleep(5 * time.Millisecond)
+
+		if len(index.List("alice")) != 0 {
+			t.Fatal("Expected idle session to be pruned")
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/sessions"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+
+	audit_repository "github.com/morphy76/g-fe-server/internal/audit/repository/impl"
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/example"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	"github.com/morphy76/g-fe-server/pkg/audit"
+)
+
+// introspection answers the access tokens of the users of the identity provider by their subject
+type introspection struct {
+	url   string
+	users map[string]string
+}
+
+func (i *introspection) IntrospectionURL() string {
+	return i.url
+}
+
+func (i *introspection) TokenEndpoint() string {
+	return ""
+}
+
+func (i *introspection) HttpClient() *http.Client {
+	return http.DefaultClient
+}
+
+func (i *introspection) AuthFn() (any, error) {
+	return nil, nil
+}
+
+func (i *introspection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	subject, found := i.users[r.FormValue("token")]
+	if !found {
+		json.NewEncoder(w).Encode(map[string]any{"active": false})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"active":    true,
+		"sub":       subject,
+		"client_id": "g-fe-server",
+		"tenant":    "acme",
+		"scope":     "example:read example:write",
+	})
+}
+
+// relyingParty is never called by the backend, it only stands for the OIDC client of the service
+type relyingParty struct {
+	rp.RelyingParty
+}
+
+func TestAuditActorSuite(t *testing.T) {
+	t.Log("Test Audit Actor Suite")
+
+	resourceServer := &introspection{users: map[string]string{"alice-token": "alice"}}
+	idp := httptest.NewServer(resourceServer)
+	defer idp.Close()
+	resourceServer.url = idp.URL
+
+	memoryClient := db.NewMemoryDbClient()
+	appContext := app_http.InjectServeOptions(context.Background(), &options.ServeOptions{ContextRoot: "/be"})
+	appContext = app_http.InjectOidcOptions(appContext, &options.OidcOptions{})
+	appContext = app_http.InjectRelyingParty(appContext, relyingParty{})
+	appContext = app_http.InjectOidcResource(appContext, resourceServer)
+	appContext = db.InjectDbOptions(appContext, &options.DbOptions{Type: options.RepositoryTypeMemoryDB})
+	appContext = db.InjectDb(appContext, memoryClient)
+
+	backendRouter := mux.NewRouter()
+	example.Handler(backendRouter, appContext)
+	backend := httptest.NewServer(backendRouter)
+	defer backend.Close()
+	target, _ := url.Parse(backend.URL + "/be/api/example")
+
+	store := sessions.NewCookieStore([]byte("test-session-key"))
+	create := func(policy options.ResourceHeaderPolicy, name string) int {
+		proxy := newReverseProxy("/fe", "/example", target, policy)
+
+		r := httptest.NewRequest(http.MethodPost, "/fe/api/example", strings.NewReader(`{"name":"`+name+`","age":1}`))
+		r.Header.Set("Content-Type", "application/json")
+		session := sessions.NewSession(store, "test")
+		session.Values["subject"] = "alice"
+		session.Values["access_token"] = "alice-token"
+		ctx := app_http.InjectSession(r.Context(), session)
+		ctx = app_http.InjectOwnership(ctx, serve.Ownership{Tenant: "acme"})
+
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, r.WithContext(ctx))
+		return w.Code
+	}
+	actorOf := func(tenant string, name string) string {
+		auditRepository, _ := audit_repository.NewMemoryRepository(memoryClient)
+		entries, _ := auditRepository.Find(context.Background(), audit.Query{Tenant: tenant, EntityId: name})
+		if len(entries) != 1 {
+			t.Fatalf("Expected a single audit entry of %s, got %v", name, entries)
+		}
+		return entries[0].Actor
+	}
+
+	t.Run("Test forwarded token", func(t *testing.T) {
+		t.Log("Testing forwarded token")
+
+		policy := options.ResourceHeaderPolicy{
+			Request: options.HeaderPolicy{
+				Add: map[string]string{"Authorization": "Bearer {access_token}"},
+			},
+		}
+		if code := create(policy, "forwarded"); code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d", http.StatusCreated, code)
+		}
+		if actor := actorOf("acme", "forwarded"); actor != "alice" {
+			t.Fatalf("Expected the user of the session to be the actor, got %s", actor)
+		}
+	})
+
+	t.Run("Test without token", func(t *testing.T) {
+		t.Log("Testing without token")
+
+		if code := create(options.ResourceHeaderPolicy{}, "anonymous"); code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d", http.StatusCreated, code)
+		}
+		if actor := actorOf("", "anonymous"); actor != "anonymous" {
+			t.Fatalf("Expected an anonymous actor without the forwarded token, got %s", actor)
+		}
+	})
+}
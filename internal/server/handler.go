@@ -22,6 +22,7 @@ import (
 	"github.com/morphy76/g-fe-server/internal/http/handlers/metrics"
 	"github.com/morphy76/g-fe-server/internal/http/handlers/static"
 	"github.com/morphy76/g-fe-server/internal/http/middleware"
+	"github.com/morphy76/g-fe-server/internal/options"
 	"github.com/morphy76/g-fe-server/internal/serve"
 )
 
@@ -31,6 +32,7 @@ func Handler(
 ) *mux.Router {
 	serveOptions := app_http.ExtractServeOptions(app_context)
 	sessionStore := app_http.ExtractSessionStore(app_context)
+	sessionIndex := app_http.ExtractSessionIndex(app_context)
 	oidcOptions := app_http.ExtractOidcOptions(app_context)
 
 	var relyingParty rp.RelyingParty
@@ -49,6 +51,7 @@ func Handler(
 	parent.Use(func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			useRequest := r.WithContext(app_http.InjectSessionStore(r.Context(), sessionStore))
+			useRequest = useRequest.WithContext(app_http.InjectSessionIndex(useRequest.Context(), sessionIndex))
 			useRequest = useRequest.WithContext(app_http.InjectServeOptions(useRequest.Context(), serveOptions))
 			useRequest = useRequest.WithContext(app_http.InjectOidcOptions(useRequest.Context(), oidcOptions))
 			if !oidcOptions.Disabled {
@@ -93,6 +96,7 @@ func Handler(
 	if !oidcOptions.Disabled {
 		authRouter := contextRouter.PathPrefix("/auth").Subrouter()
 		authRouter.Use(middleware.InjectSession)
+		authRouter.Use(middleware.CSRFProtection)
 		if log.Trace().Enabled() {
 			log.Trace().
 				Msg("Auth router registered")
@@ -109,6 +113,7 @@ func Handler(
 	staticRouter.Use(middleware.InjectSession)
 	staticRouter.Use(middleware.HttpSessionAuthenticationRequired)
 	staticRouter.Use(middleware.HttpSessionInspectAndRenew)
+	staticRouter.Use(middleware.CSRFProtection)
 	if log.Trace().Enabled() {
 		log.Trace().
 			Msg("Static router registered")
@@ -124,6 +129,9 @@ func Handler(
 	apiRouter.Use(mux.CORSMethodMiddleware(apiRouter))
 	apiRouter.Use(middleware.JSONResponse)
 	apiRouter.Use(middleware.PrometheusMiddleware)
+	apiRouter.Use(middleware.InjectSession)
+	apiRouter.Use(middleware.CSRFProtection)
+	apiRouter.Use(middleware.WebSocketHandshake)
 	// TODO: gw oriented auth, inspect and renew
 	// apiRouter.Use(middleware.MixedAuthenticationRequired)
 	// apiRouter.Use(middleware.MixedInspectAndRenew)
@@ -137,7 +145,7 @@ func Handler(
 
 var routeCounter map[string]int = make(map[string]int)
 
-func ProxyRoute(ctxRoot string, apiRouter *mux.Router, remoteRoute string) {
+func ProxyRoute(ctxRoot string, apiRouter *mux.Router, remoteRoute string, proxyOptions *options.ProxyOptions) {
 
 	after, found := strings.CutPrefix(remoteRoute, "unroute:")
 	if found {
@@ -154,7 +162,7 @@ func ProxyRoute(ctxRoot string, apiRouter *mux.Router, remoteRoute string) {
 		parts := strings.SplitAfterN(after, ":", 2)
 		resource := strings.TrimSuffix(parts[0], ":")
 		if routeCounter[resource] == 0 {
-			createProxy(ctxRoot, apiRouter, after, parts, resource)
+			createProxy(ctxRoot, apiRouter, after, parts, resource, proxyOptions)
 		}
 		routeCounter[resource] += 1
 		return
@@ -180,7 +188,7 @@ func removeProxy(ctxRoot string, apiRouter *mux.Router, resource string) {
 	}
 }
 
-func createProxy(ctxRoot string, apiRouter *mux.Router, remoteRoute string, parts []string, resource string) {
+func createProxy(ctxRoot string, apiRouter *mux.Router, remoteRoute string, parts []string, resource string, proxyOptions *options.ProxyOptions) {
 
 	if len(parts) != 2 {
 		log.Warn().
@@ -203,7 +211,7 @@ func createProxy(ctxRoot string, apiRouter *mux.Router, remoteRoute string, part
 		return
 	}
 
-	proxy := newReverseProxy(ctxRoot, resource, forwardURL)
+	proxy := newReverseProxy(ctxRoot, resource, forwardURL, resourceHeaderPolicy(proxyOptions, resource))
 
 	if route == nil || route.GetHandler() == nil {
 		apiRouter.NewRoute().Name(routeName).Handler(proxy)
@@ -216,27 +224,31 @@ func createProxy(ctxRoot string, apiRouter *mux.Router, remoteRoute string, part
 	}
 }
 
-func newReverseProxy(ctxRoot string, resource string, target *url.URL) *httputil.ReverseProxy {
+func newReverseProxy(ctxRoot string, resource string, target *url.URL, policy options.ResourceHeaderPolicy) *httputil.ReverseProxy {
+
+	requestPolicy := newHeaderPolicy(policy.Request, gatewayRequestHeaders...)
+	responsePolicy := newHeaderPolicy(policy.Response, gatewayResponseHeaders...)
 
 	rewriteFun := func(r *httputil.ProxyRequest) {
+		// the forwarding and trace context headers are set by the gateway, after the policy
+		requestPolicy.filter(r.Out.Header)
 		r.SetXForwarded()
 
+		// r.Out already lacks the hop-by-hop headers, except Connection and Upgrade of protocol upgrades
 		otelCarrier := propagation.HeaderCarrier(r.Out.Header)
 		otel.GetTextMapPropagator().Inject(r.In.Context(), otelCarrier)
 		log.Trace().
 			Any("carrier", otelCarrier).
 			Msg("OTEL propagation")
 
-		r.Out = r.In
-		r.Out = r.In.Clone(r.In.Context())
-
 		tgtFunctionalRoot := strings.Replace(target.Path, resource, "", 1)
 		r.Out.URL.Path = strings.Replace(r.In.URL.Path, ctxRoot+"/api", tgtFunctionalRoot, 1)
 
 		r.Out.URL.Host = target.Host
 		r.Out.URL.Scheme = target.Scheme
 		r.Out.URL.User = target.User
-		r.Out.Header = http.Header(otelCarrier)
+
+		requestPolicy.extend(r.In.Context(), r.Out.Header)
 
 		log.Trace().
 			Any("in", r.In.Header).
@@ -244,7 +256,30 @@ func newReverseProxy(ctxRoot string, resource string, target *url.URL) *httputil
 			Msg("...proxying...")
 	}
 
+	modifyResponseFun := func(res *http.Response) error {
+		responsePolicy.filter(res.Header)
+		responsePolicy.extend(res.Request.Context(), res.Header)
+		return nil
+	}
+
+	errorFun := func(w http.ResponseWriter, r *http.Request, err error) {
+		log.Warn().
+			Err(err).
+			Str("resource", resource).
+			Msg("Proxy failed")
+
+		status := http.StatusBadGateway
+		if r.Context().Err() != nil {
+			status = http.StatusGatewayTimeout
+		}
+		app_http.RespondProblem(w, r, status, "The upstream service is unavailable")
+	}
+
+	// text/event-stream responses are flushed on every write, the middlewares in between must support flushing;
+	// upgraded connections are hijacked through them, see middleware.WebSocketHandshake
 	return &httputil.ReverseProxy{
-		Rewrite: rewriteFun,
+		Rewrite:        rewriteFun,
+		ModifyResponse: modifyResponseFun,
+		ErrorHandler:   errorFun,
 	}
 }
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/http/middleware"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+// echoUpgrade switches to an echo protocol and reports the path and the upgrade it has been asked for
+func echoUpgrade(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !middleware.IsWebSocketUpgrade(r) {
+			http.Error(w, "upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("Hijack failed: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		brw.WriteString(r.URL.Path + "\n")
+		brw.Flush()
+		io.Copy(conn, brw)
+	}))
+}
+
+func dialUpgrade(t *testing.T, gateway *httptest.Server, path string) (net.Conn, *bufio.Reader) {
+	gatewayURL, _ := url.Parse(gateway.URL)
+	conn, err := net.Dial("tcp", gatewayURL.Host)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, gateway.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Write(conn)
+
+	reader := bufio.NewReader(conn)
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		t.Fatalf("Handshake failed: %v", err)
+	}
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected status %d, got %d", http.StatusSwitchingProtocols, res.StatusCode)
+	}
+	return conn, reader
+}
+
+func TestProxySuite(t *testing.T) {
+	t.Log("Test Proxy Suite")
+
+	upstream := echoUpgrade(t)
+	defer upstream.Close()
+
+	target, _ := url.Parse(upstream.URL + "/be/api/chat")
+	proxy := middleware.WebSocketHandshake(newReverseProxy("/fe", "/chat", target, options.ResourceHeaderPolicy{}))
+
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := app_http.InjectServeOptions(r.Context(), &options.ServeOptions{
+			ContextRoot:          "/fe",
+			WebSocketIdleTimeout: 200 * time.Millisecond,
+		})
+		ctx = app_http.InjectOidcOptions(ctx, &options.OidcOptions{Disabled: true})
+		ctx = app_http.InjectLogger(ctx, zerolog.Nop())
+		proxy.ServeHTTP(w, r.WithContext(ctx))
+	}))
+	defer gateway.Close()
+
+	t.Run("Test upgrade is proxied", func(t *testing.T) {
+		t.Log("Test Proxy upgrade")
+
+		conn, reader := dialUpgrade(t, gateway, "/fe/api/chat/room")
+		defer conn.Close()
+
+		path, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if strings.TrimSpace(path) != "/be/api/chat/room" {
+			t.Fatalf("Expected rewritten path, got %s", path)
+		}
+
+		conn.Write([]byte("hello\n"))
+		echo, err := reader.ReadString('\n')
+		if err != nil || echo != "hello\n" {
+			t.Fatalf("Expected echo, got %q: %v", echo, err)
+		}
+	})
+
+	t.Run("Test idle upgrade is closed", func(t *testing.T) {
+		t.Log("Test Proxy idle upgrade")
+
+		conn, reader := dialUpgrade(t, gateway, "/fe/api/chat/room")
+		defer conn.Close()
+		reader.ReadString('\n')
+
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, err := reader.ReadByte(); err != io.EOF {
+			t.Fatalf("Expected the gateway to close the idle connection, got %v", err)
+		}
+	})
+}
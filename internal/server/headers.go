@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"net/http"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/http/middleware"
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+const ANY_RESOURCE = "*"
+
+// gatewayRequestHeaders carry the credentials of the gateway, they never reach the backends whatever the policy;
+// tokens are forwarded on purpose only, e.g. adding Authorization: Bearer {access_token}
+var gatewayRequestHeaders = []string{"Cookie", middleware.CSRF_HEADER_NAME, "Authorization"}
+
+// gatewayResponseHeaders would let the backends set cookies on the domain of the gateway
+var gatewayResponseHeaders = []string{"Set-Cookie"}
+
+// headerPolicy is an options.HeaderPolicy with canonical header names
+type headerPolicy struct {
+	allow map[string]struct{}
+	deny  map[string]struct{}
+	add   map[string]string
+}
+
+// newHeaderPolicy denies the always denied headers besides the ones of the policy, the added headers are set anyway
+func newHeaderPolicy(policy options.HeaderPolicy, alwaysDenied ...string) headerPolicy {
+	rv := headerPolicy{
+		allow: make(map[string]struct{}, len(policy.Allow)),
+		deny:  make(map[string]struct{}, len(policy.Deny)+len(alwaysDenied)),
+		add:   make(map[string]string, len(policy.Add)),
+	}
+	for _, name := range alwaysDenied {
+		rv.deny[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	for _, name := range policy.Allow {
+		rv.allow[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	for _, name := range policy.Deny {
+		rv.deny[http.CanonicalHeaderKey(name)] = struct{}{}
+	}
+	for name, value := range policy.Add {
+		rv.add[http.CanonicalHeaderKey(name)] = value
+	}
+	return rv
+}
+
+func resourceHeaderPolicy(proxyOptions *options.ProxyOptions, resource string) options.ResourceHeaderPolicy {
+	if proxyOptions == nil {
+		return options.ResourceHeaderPolicy{}
+	}
+	if policy, found := proxyOptions.HeaderPolicies[resource]; found {
+		return policy
+	}
+	return proxyOptions.HeaderPolicies[ANY_RESOURCE]
+}
+
+// filter drops the headers out of the policy, Connection and Upgrade are left to the proxy
+// which keeps them on protocol upgrades only
+func (p headerPolicy) filter(header http.Header) {
+	for name := range header {
+		if name == "Connection" || name == "Upgrade" {
+			continue
+		}
+		if _, denied := p.deny[name]; denied {
+			header.Del(name)
+			continue
+		}
+		if _, allowed := p.allow[name]; len(p.allow) > 0 && !allowed {
+			header.Del(name)
+		}
+	}
+}
+
+// extend sets the added headers, a header is skipped when any of its placeholders is not resolved; the values
+// sent by the client are dropped anyway, so that they are never taken for the ones of the gateway
+func (p headerPolicy) extend(ctx context.Context, header http.Header) {
+	for name, value := range p.add {
+		header.Del(name)
+		expanded, resolved := options.ExpandPlaceholders(value, func(placeholder string) string {
+			return lookupPlaceholder(ctx, placeholder)
+		})
+		if resolved {
+			header.Set(name, expanded)
+		}
+	}
+}
+
+func lookupPlaceholder(ctx context.Context, name string) string {
+	switch name {
+	case "tenant", "subscription":
+		ownership, ok := app_http.LookupOwnership(ctx)
+		if !ok {
+			return ""
+		}
+		if name == "tenant" {
+			return ownership.Tenant
+		}
+		return ownership.Subscription
+	case "subject", "access_token":
+		session, ok := app_http.LookupSession(ctx)
+		if !ok {
+			return ""
+		}
+		rv, _ := session.Values[name].(string)
+		return rv
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/sessions"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/serve"
+)
+
+func TestHeaderPolicySuite(t *testing.T) {
+	t.Log("Test Header Policy Suite")
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Powered-By", "upstream")
+		w.Header().Set("X-Request-Id", "42")
+		w.Header().Set("Set-Cookie", "gofe.sid=forged")
+		json.NewEncoder(w).Encode(r.Header)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL + "/be/api/example")
+
+	store := sessions.NewCookieStore([]byte("test-session-key"))
+	// forged are pairs of header names and values sent by the client on top of the usual ones
+	proxied := func(policy options.ResourceHeaderPolicy, anonymous bool, forged ...string) (*httptest.ResponseRecorder, http.Header) {
+		proxy := newReverseProxy("/fe", "/example", target, policy)
+
+		r := httptest.NewRequest(http.MethodGet, "/fe/api/example", nil)
+		r.Header.Set("Accept", "application/json")
+		r.Header.Set("Cookie", "gofe.sid=secret")
+		r.Header.Set("X-XSRF-TOKEN", "xsrf")
+		r.Header.Set("Authorization", "Bearer user-token")
+		r.Header.Set("X-Tenant", "acme")
+		for i := 0; i+1 < len(forged); i += 2 {
+			r.Header.Set(forged[i], forged[i+1])
+		}
+
+		if !anonymous {
+			session := sessions.NewSession(store, "test")
+			session.Values["subject"] = "alice"
+			session.Values["access_token"] = "token"
+			ctx := app_http.InjectSession(r.Context(), session)
+			ctx = app_http.InjectOwnership(ctx, serve.Ownership{Tenant: "acme"})
+			r = r.WithContext(ctx)
+		}
+
+		w := httptest.NewRecorder()
+		proxy.ServeHTTP(w, r)
+
+		var received http.Header
+		json.NewDecoder(w.Body).Decode(&received)
+		return w, received
+	}
+
+	t.Run("Test without policy", func(t *testing.T) {
+		t.Log("Test Header Policy none")
+
+		w, received := proxied(resourceHeaderPolicy(&options.ProxyOptions{}, "/example"), false)
+
+		if received.Get("Accept") != "application/json" || received.Get("X-Tenant") != "acme" {
+			t.Fatalf("Expected the end-to-end headers, got %v", received)
+		}
+		if received.Get("Cookie") != "" || received.Get("X-Xsrf-Token") != "" || received.Get("Authorization") != "" {
+			t.Fatalf("Expected the gateway credentials to be dropped, got %v", received)
+		}
+		if w.Header().Get("Set-Cookie") != "" {
+			t.Fatalf("Expected the backend cookies to be dropped, got %v", w.Header())
+		}
+		if received.Get("X-Forwarded-Host") == "" {
+			t.Fatalf("Expected the forwarding headers, got %v", received)
+		}
+		if w.Header().Get("X-Powered-By") != "upstream" {
+			t.Fatalf("Expected every response header, got %v", w.Header())
+		}
+	})
+
+	t.Run("Test allow and deny", func(t *testing.T) {
+		t.Log("Test Header Policy allow and deny")
+
+		w, received := proxied(options.ResourceHeaderPolicy{
+			Request: options.HeaderPolicy{
+				Allow: []string{"accept", "x-tenant", "cookie"},
+			},
+			Response: options.HeaderPolicy{
+				Deny: []string{"X-Powered-By"},
+			},
+		}, false)
+
+		if received.Get("Accept") != "application/json" || received.Get("X-Tenant") != "acme" {
+			t.Fatalf("Expected the allowed headers, got %v", received)
+		}
+		if received.Get("Cookie") != "" || received.Get("User-Agent") != "" {
+			t.Fatalf("Expected the other headers to be dropped, got %v", received)
+		}
+		if received.Get("X-Forwarded-Host") == "" {
+			t.Fatalf("Expected the forwarding headers regardless of the allow list, got %v", received)
+		}
+		if w.Header().Get("X-Powered-By") != "" || w.Header().Get("X-Request-Id") != "42" {
+			t.Fatalf("Expected only the denied response header to be dropped, got %v", w.Header())
+		}
+	})
+
+	t.Run("Test added headers", func(t *testing.T) {
+		t.Log("Test Header Policy add")
+
+		policy := options.ResourceHeaderPolicy{
+			Request: options.HeaderPolicy{
+				Add: map[string]string{
+					"X-Tenant":      "{tenant}",
+					"X-User-Id":     "{subject}",
+					"Authorization": "Bearer {access_token}",
+				},
+			},
+			Response: options.HeaderPolicy{
+				Add: map[string]string{"X-Frame-Options": "DENY"},
+			},
+		}
+
+		w, received := proxied(policy, false)
+		if received.Get("X-Tenant") != "acme" || received.Get("X-User-Id") != "alice" || received.Get("Authorization") != "Bearer token" {
+			t.Fatalf("Expected the resolved headers, got %v", received)
+		}
+		if w.Header().Get("X-Frame-Options") != "DENY" {
+			t.Fatalf("Expected the added response header, got %v", w.Header())
+		}
+
+		_, received = proxied(policy, true, "X-Tenant", "forged", "X-User-Id", "admin")
+		if received.Get("X-Tenant") != "" || received.Get("X-User-Id") != "" || received.Get("Authorization") != "" {
+			t.Fatalf("Expected unresolved headers to be skipped and the forged ones dropped, got %v", received)
+		}
+	})
+
+	t.Run("Test resource lookup", func(t *testing.T) {
+		t.Log("Test Header Policy resource lookup")
+
+		proxyOptions := &options.ProxyOptions{
+			HeaderPolicies: map[string]options.ResourceHeaderPolicy{
+				"/example":   {Request: options.HeaderPolicy{Deny: []string{"Cookie"}}},
+				ANY_RESOURCE: {Request: options.HeaderPolicy{Deny: []string{"Accept"}}},
+			},
+		}
+
+		if policy := resourceHeaderPolicy(proxyOptions, "/example"); len(policy.Request.Deny) != 1 || policy.Request.Deny[0] != "Cookie" {
+			t.Fatalf("Expected the resource policy, got %v", policy)
+		}
+		if policy := resourceHeaderPolicy(proxyOptions, "/other"); len(policy.Request.Deny) != 1 || policy.Request.Deny[0] != "Accept" {
+			t.Fatalf("Expected the fallback policy, got %v", policy)
+		}
+		if policy := resourceHeaderPolicy(nil, "/example"); len(policy.Request.Deny) != 0 {
+			t.Fatalf("Expected no policy, got %v", policy)
+		}
+	})
+}
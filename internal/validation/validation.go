@@ -0,0 +1,173 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var ErrMalformedBody = errors.New("malformed request body")
+
+func IsMalformedBody(err error) bool {
+	return err == ErrMalformedBody
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fmt.Sprintf("%s %s", fieldError.Field, fieldError.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
+
+func IsInvalid(err error) (Errors, bool) {
+	var rv Errors
+	ok := errors.As(err, &rv)
+	return rv, ok
+}
+
+var (
+	validate     *validator.Validate
+	validateOnce sync.Once
+	patterns     sync.Map
+)
+
+func instance() *validator.Validate {
+	validateOnce.Do(func() {
+		validate = validator.New(validator.WithRequiredStructEnabled())
+		validate.RegisterTagNameFunc(jsonName)
+		// pattern=<regexp>, commas and pipes in the regexp must be escaped as 0x2C and 0x7C
+		validate.RegisterValidation("pattern", func(fl validator.FieldLevel) bool {
+			return compiled(fl.Param()).MatchString(fl.Field().String())
+		})
+	})
+	return validate
+}
+
+func compiled(pattern string) *regexp.Regexp {
+	if rv, ok := patterns.Load(pattern); ok {
+		return rv.(*regexp.Regexp)
+	}
+	rv := regexp.MustCompile(pattern)
+	patterns.Store(pattern, rv)
+	return rv
+}
+
+// DecodeJSON decodes a single JSON value rejecting unknown fields, type mismatches are reported as field errors
+func DecodeJSON(body io.Reader, target any) error {
+
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(target); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) && typeError.Field != "" {
+			return Errors{{
+				Field:   typeError.Field,
+				Rule:    "type",
+				Param:   typeError.Type.String(),
+				Message: fmt.Sprintf("must be a %s", typeError.Type.String()),
+			}}
+		}
+		if field, found := strings.CutPrefix(err.Error(), "json: unknown field "); found {
+			return Errors{{
+				Field:   strings.Trim(field, `"`),
+				Rule:    "unknown",
+				Message: "is not allowed",
+			}}
+		}
+		return ErrMalformedBody
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return ErrMalformedBody
+	}
+
+	return nil
+}
+
+// Struct enforces the validate tags of the struct
+func Struct(v any) error {
+	return translate(instance().Struct(v))
+}
+
+// Partial enforces the validate tags of the given fields only, fields are named after their JSON names
+func Partial(v any, fields ...string) error {
+
+	structType := reflect.Indirect(reflect.ValueOf(v)).Type()
+	names := make([]string, 0, len(fields))
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		for _, name := range fields {
+			if jsonName(field) == name {
+				names = append(names, field.Name)
+			}
+		}
+	}
+
+	return translate(instance().StructPartial(v, names...))
+}
+
+func translate(err error) error {
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	rv := make(Errors, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		rv = append(rv, FieldError{
+			Field:   fieldError.Field(),
+			Rule:    fieldError.Tag(),
+			Param:   fieldError.Param(),
+			Message: message(fieldError),
+		})
+	}
+	return rv
+}
+
+func message(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s", fieldError.Param())
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s", fieldError.Param())
+	case "pattern":
+		return fmt.Sprintf("must match %s", fieldError.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", fieldError.Param())
+	default:
+		return fmt.Sprintf("does not satisfy %s", fieldError.Tag())
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	default:
+		return name
+	}
+}
@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+type sample struct {
+	Name string `json:"name" validate:"required,max=8,pattern=^[a-z]+$"`
+	Age  int    `json:"age" validate:"required,min=1,max=200"`
+}
+
+func TestValidationSuite(t *testing.T) {
+	t.Log("Test Validation Suite")
+
+	t.Run("Test DecodeJSON", func(t *testing.T) {
+		t.Log("Testing DecodeJSON")
+
+		var s sample
+		if err := DecodeJSON(strings.NewReader(`{"name": "john", "age": 30}`), &s); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if s.Name != "john" || s.Age != 30 {
+			t.Errorf("Unexpected decoding: %#v", s)
+		}
+
+		errs, ok := IsInvalid(DecodeJSON(strings.NewReader(`{"name": "john", "nickname": "j"}`), &s))
+		if !ok || len(errs) != 1 || errs[0].Field != "nickname" || errs[0].Rule != "unknown" {
+			t.Errorf("Expected unknown field error, got %#v", errs)
+		}
+
+		errs, ok = IsInvalid(DecodeJSON(strings.NewReader(`{"age": "thirty"}`), &s))
+		if !ok || len(errs) != 1 || errs[0].Field != "age" || errs[0].Rule != "type" {
+			t.Errorf("Expected type error, got %#v", errs)
+		}
+
+		for _, body := range []string{`{"name": `, `{} {}`, ``} {
+			if err := DecodeJSON(strings.NewReader(body), &s); !IsMalformedBody(err) {
+				t.Errorf("Expected malformed body for %q, got %v", body, err)
+			}
+		}
+	})
+
+	t.Run("Test Struct", func(t *testing.T) {
+		t.Log("Testing Struct")
+
+		if err := Struct(sample{Name: "john", Age: 30}); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		errs, ok := IsInvalid(Struct(sample{}))
+		if !ok || len(errs) != 2 {
+			t.Fatalf("Expected two errors, got %#v", errs)
+		}
+		for _, fieldError := range errs {
+			if fieldError.Rule != "required" {
+				t.Errorf("Expected required, got %#v", fieldError)
+			}
+		}
+
+		errs, _ = IsInvalid(Struct(sample{Name: "John!", Age: 201}))
+		rules := map[string]string{}
+		for _, fieldError := range errs {
+			rules[fieldError.Field] = fieldError.Rule
+		}
+		if rules["name"] != "pattern" || rules["age"] != "max" {
+			t.Errorf("Expected pattern and max errors, got %#v", errs)
+		}
+	})
+
+	t.Run("Test Partial", func(t *testing.T) {
+		t.Log("Testing Partial")
+
+		if err := Partial(sample{Age: 30}, "age"); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+
+		errs, ok := IsInvalid(Partial(sample{Age: 0}, "age"))
+		if !ok || len(errs) != 1 || errs[0].Field != "age" {
+			t.Errorf("Expected age error only, got %#v", errs)
+		}
+	})
+}
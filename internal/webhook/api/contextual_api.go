@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/webhook/repository"
+	model "github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+type ContextualizedApiHandler func(zerolog.Logger, model.Repository) http.HandlerFunc
+
+func ContextualizedApi(apiHandler ContextualizedApiHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog := app_http.ExtractLogger(r.Context(), "webhook")
+		webhookRepository, err := repository.NewRepository(r.Context())
+		if err != nil {
+			useLog.Error().
+				Err(err).
+				Msg("Failed to create repository")
+			app_http.RespondProblem(w, r, http.StatusInternalServerError, "Failed to create repository")
+			return
+		}
+
+		apiHandler(useLog, webhookRepository)(w, r)
+	}
+}
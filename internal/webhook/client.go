@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+	model "github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+// NewClient is the client of the dispatcher: tenants choose the urls, so it only connects to public addresses,
+// checked once the host name is resolved, and does not follow redirects, which are failed attempts instead
+func NewClient(webhooksOptions options.WebhooksOptions) *http.Client {
+	return newClient(webhooksOptions.Timeout, model.IsPublicAddress)
+}
+
+func newClient(timeout time.Duration, allowed func(net.IP) bool) *http.Client {
+
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(network string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !allowed(net.ParseIP(host)) {
+				return model.ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would dial the webhooks in place of the dialer
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+	model "github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+func TestClientSuite(t *testing.T) {
+	t.Log("Test Client Suite")
+
+	redirected := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hook", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	})
+	mux.HandleFunc("/internal", func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Test private address", func(t *testing.T) {
+		t.Log("Testing private address")
+
+		client := NewClient(options.WebhooksOptions{Timeout: time.Second})
+		_, err := client.Post(server.URL+"/hook", "application/json", nil)
+		if !errors.Is(err, model.ErrPrivateAddress) {
+			t.Fatalf("Expected the loopback server to be refused, got %v", err)
+		}
+	})
+
+	t.Run("Test redirect", func(t *testing.T) {
+		t.Log("Testing redirect")
+
+		client := newClient(time.Second, func(net.IP) bool { return true })
+		resp, err := client.Post(server.URL+"/hook", "application/json", nil)
+		if err != nil {
+			t.Fatalf("Error on Post: %s", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusFound || redirected {
+			t.Fatalf("Expected the redirect not to be followed, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Test ValidateUrl", func(t *testing.T) {
+		t.Log("Testing ValidateUrl")
+
+		for _, rawUrl := range []string{
+			"http://127.0.0.1/hook",
+			"http://localhost:8080/hook",
+			"http://10.0.0.1/hook",
+			"http://172.16.3.4/hook",
+			"https://192.168.1.1/hook",
+			"http://169.254.169.254/latest/meta-data",
+			"http://[::1]/hook",
+			"http://[fd00::1]/hook",
+			"http://[::ffff:127.0.0.1]/hook",
+			"http://0.0.0.0/hook",
+		} {
+			if err := model.ValidateUrl(rawUrl); !model.IsPrivateAddress(err) {
+				t.Errorf("Expected %s to be refused, got %v", rawUrl, err)
+			}
+		}
+		for _, rawUrl := range []string{"https://hooks.example.com/hook", "http://8.8.8.8/hook"} {
+			if err := model.ValidateUrl(rawUrl); err != nil {
+				t.Errorf("Expected %s to be accepted, got %v", rawUrl, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+)
+
+type ContextWebhooksOptionsKey string
+
+const ctx_WEBHOOKS_OPTIONS_KEY ContextWebhooksOptionsKey = "webhooksOptions"
+
+// ExtractOptions returns the webhooks options of the context, nil when there are none
+func ExtractOptions(ctx context.Context) *options.WebhooksOptions {
+	rv, _ := ctx.Value(ctx_WEBHOOKS_OPTIONS_KEY).(*options.WebhooksOptions)
+	return rv
+}
+
+func InjectOptions(ctx context.Context, webhooksOptions *options.WebhooksOptions) context.Context {
+	return context.WithValue(ctx, ctx_WEBHOOKS_OPTIONS_KEY, webhooksOptions)
+}
+
+// Enabled tells whether tenants of the context can register webhooks
+func Enabled(ctx context.Context) bool {
+	webhooksOptions := ExtractOptions(ctx)
+	return webhooksOptions != nil && webhooksOptions.Enabled
+}
@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/poll"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	model "github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+const tracerName = "github.com/morphy76/g-fe-server/internal/webhook"
+
+// DISPATCH_LEASE is how long a claimed delivery is hidden from the other dispatchers, posts must end before it
+// expires
+const DISPATCH_LEASE = time.Minute
+
+// Dispatcher posts the due deliveries to the webhooks of their subscription, signed with its secret; every post
+// is logged on the delivery with the response code
+type Dispatcher struct {
+	repository model.Repository
+	client     *http.Client
+	options    options.WebhooksOptions
+}
+
+func NewDispatcher(repository model.Repository, client *http.Client, webhooksOptions options.WebhooksOptions) *Dispatcher {
+	return &Dispatcher{
+		repository: repository,
+		client:     client,
+		options:    webhooksOptions,
+	}
+}
+
+// Run dispatches the due deliveries every poll interval until the context is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	poll.Run(ctx, d.options.PollInterval, d.options.BatchSize, d.DispatchOnce, "Webhook dispatch failed")
+}
+
+// DispatchOnce claims a batch of due deliveries and posts them, it returns the number of claimed deliveries
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+
+	deliveries, err := d.repository.ClaimDeliveries(ctx, time.Now().UTC(), DISPATCH_LEASE, d.options.BatchSize)
+	if err != nil {
+		return len(deliveries), err
+	}
+
+	for _, delivery := range deliveries {
+		if err := d.deliver(ctx, delivery); err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// deliver posts the delivery within the trace of the change and records the attempt, the error is the one of
+// recording; the deliveries of deleted webhooks fail straight away
+func (d *Dispatcher) deliver(ctx context.Context, delivery model.Delivery) error {
+
+	subscription, err := d.repository.FindById(ctx, delivery.SubscriptionId)
+	if model.IsNotFound(err) || (err == nil && subscription.Tenant != delivery.Tenant) {
+		serve.WebhookDeliveriesTotal.WithLabelValues("failed").Inc()
+		attempt := model.Attempt{At: time.Now().UTC(), Error: "webhook deleted"}
+		return d.repository.RecordAttempt(ctx, delivery.Id, attempt, model.DeliveryFailed, attempt.At)
+	}
+	if err != nil {
+		return err
+	}
+
+	traceContext := otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(delivery.TraceContext))
+	postContext, span := otel.Tracer(tracerName).Start(traceContext, "webhook.Deliver",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.id", subscription.Id),
+			attribute.String("webhook.delivery.id", delivery.Id),
+			attribute.String("webhook.event.type", delivery.EventType),
+			attribute.Int("webhook.delivery.attempts", len(delivery.Attempts)),
+		),
+	)
+	defer span.End()
+
+	attempt := d.post(postContext, subscription, delivery)
+	span.SetAttributes(attribute.Int("http.response.status_code", attempt.ResponseCode))
+
+	if attempt.Error == "" {
+		serve.WebhookDeliveriesTotal.WithLabelValues("succeeded").Inc()
+		return d.repository.RecordAttempt(ctx, delivery.Id, attempt, model.DeliverySucceeded, attempt.At)
+	}
+
+	span.SetStatus(codes.Error, attempt.Error)
+
+	attempts := delivery.AttemptsSinceReplay() + 1
+	status := model.DeliveryPending
+	nextAttemptAt := attempt.At.Add(d.backoff(attempts))
+	if attempts >= d.options.MaxAttempts {
+		status = model.DeliveryFailed
+		nextAttemptAt = attempt.At
+	}
+	serve.WebhookDeliveriesTotal.WithLabelValues(map[model.DeliveryStatus]string{
+		model.DeliveryPending: "retried",
+		model.DeliveryFailed:  "failed",
+	}[status]).Inc()
+
+	log.Warn().
+		Str("id", delivery.Id).
+		Str("webhook", subscription.Id).
+		Str("tenant", delivery.Tenant).
+		Int("response_code", attempt.ResponseCode).
+		Str("error", attempt.Error).
+		Int("attempts", attempts).
+		Str("status", string(status)).
+		Time("next_attempt_at", nextAttemptAt).
+		Msg("Webhook delivery failed")
+	return d.repository.RecordAttempt(ctx, delivery.Id, attempt, status, nextAttemptAt)
+}
+
+// post sends the payload signed with the secret of the subscription, any answer but 2xx is a failure
+func (d *Dispatcher) post(ctx context.Context, subscription model.Subscription, delivery model.Delivery) (rv model.Attempt) {
+
+	start := time.Now().UTC()
+	rv.At = start
+	defer func() {
+		rv.DurationMs = time.Since(start).Milliseconds()
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		rv.Error = err.Error()
+		return rv
+	}
+	timestamp := start.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(model.HEADER_DELIVERY, delivery.Id)
+	req.Header.Set(model.HEADER_EVENT, delivery.EventType)
+	req.Header.Set(model.HEADER_TIMESTAMP, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(model.HEADER_SIGNATURE, model.Sign(subscription.Secret, timestamp, delivery.Payload))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		rv.Error = err.Error()
+		return rv
+	}
+	defer resp.Body.Close()
+	// drained, so that the connection is reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	rv.ResponseCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		rv.Error = fmt.Sprintf("webhook answered %d", resp.StatusCode)
+	}
+	return rv
+}
+
+// backoff spaces the posts of a failing delivery
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	return poll.Backoff(d.options.RetryBackoff, d.options.MaxBackoff, attempts)
+}
@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/outbox"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	impl "github.com/morphy76/g-fe-server/internal/webhook/repository/impl"
+	outbox_model "github.com/morphy76/g-fe-server/pkg/outbox"
+	model "github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+// recordingPublisher stands for the publisher of the outbox relay
+type recordingPublisher struct {
+	published []outbox_model.Event
+}
+
+func (p *recordingPublisher) Name() string {
+	return "recording"
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, e outbox_model.Event) error {
+	p.published = append(p.published, e)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+// receiver answers the posts with its status and keeps the last one
+type receiver struct {
+	mu      sync.Mutex
+	status  int
+	headers http.Header
+	body    []byte
+	posts   int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.posts++
+	rc.headers = r.Header.Clone()
+	rc.body, _ = io.ReadAll(r.Body)
+	w.WriteHeader(rc.status)
+}
+
+func (rc *receiver) answer(status int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.status = status
+}
+
+func TestDispatcherSuite(t *testing.T) {
+	t.Log("Test Dispatcher Suite")
+
+	ctx := app_http.InjectOwnership(context.Background(), serve.Ownership{Tenant: "hooked"})
+	webhooksOptions := options.WebhooksOptions{
+		PollInterval: time.Second,
+		BatchSize:    10,
+		Timeout:      time.Second,
+		RetryBackoff: time.Second,
+		MaxBackoff:   4 * time.Second,
+		MaxAttempts:  2,
+	}
+
+	memoryClient := db.NewMemoryDbClient()
+	repository, _ := impl.NewMemoryRepository(memoryClient)
+	deliveries, _ := db.MemoryTableOf(memoryClient, impl.MEMORY_DELIVERIES_STORE, func(d model.Delivery) string { return d.Id })
+
+	target := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(target)
+	defer server.Close()
+
+	for _, s := range []model.Subscription{
+		{Id: "creations", Tenant: "hooked", Url: server.URL, EventTypes: []string{"example.create"}, Secret: "s3cr3t"},
+		{Id: "others", Tenant: "other", Url: server.URL, EventTypes: []string{model.EVENT_TYPE_ANY}, Secret: "other"},
+	} {
+		if err := repository.Save(ctx, s); err != nil {
+			t.Fatalf("Error on Save: %s", err)
+		}
+	}
+
+	dispatcher := NewDispatcher(repository, server.Client(), webhooksOptions)
+
+	t.Run("Test Backoff", func(t *testing.T) {
+		t.Log("Testing Backoff")
+
+		for attempts, expected := range []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+			if backoff := dispatcher.backoff(attempts); backoff != expected {
+				t.Errorf("Expected %s after %d attempts, got %s", expected, attempts, backoff)
+			}
+		}
+	})
+
+	var created outbox_model.Event
+
+	t.Run("Test Fanout", func(t *testing.T) {
+		t.Log("Testing Fanout")
+
+		delegate := &recordingPublisher{}
+		publisher := NewFanoutPublisher(delegate, repository)
+
+		var err error
+		created, err = outbox.NewEvent(ctx, "example", "created", "create", 1, map[string]any{"name": "created"})
+		if err != nil {
+			t.Fatalf("Error on NewEvent: %s", err)
+		}
+		updated, _ := outbox.NewEvent(ctx, "example", "created", "update", 2, map[string]any{"name": "created"})
+		anonymous, _ := outbox.NewEvent(context.Background(), "example", "anonymous", "create", 1, nil)
+
+		for _, e := range []outbox_model.Event{created, created, updated, anonymous} {
+			if err := publisher.Publish(ctx, e); err != nil {
+				t.Fatalf("Error on Publish: %s", err)
+			}
+		}
+		if len(delegate.published) != 4 {
+			t.Fatalf("Expected every event to reach the delegate, got %d", len(delegate.published))
+		}
+
+		if len(deliveries.Rows) != 1 {
+			t.Fatalf("Expected a single delivery of the creation to the webhook of its tenant, got %d", len(deliveries.Rows))
+		}
+		d, err := repository.FindDelivery(ctx, model.DeliveryId("creations", created.Id))
+		if err != nil {
+			t.Fatalf("Error on FindDelivery: %s", err)
+		}
+		if d.Tenant != "hooked" || d.EventType != "example.create" || d.Status != model.DeliveryPending {
+			t.Errorf("Expected a pending delivery of the creation, got %#v", d)
+		}
+	})
+
+	t.Run("Test Signed Delivery", func(t *testing.T) {
+		t.Log("Testing Signed Delivery")
+
+		if claimed, err := dispatcher.DispatchOnce(ctx); err != nil || claimed != 1 {
+			t.Fatalf("Expected the delivery to be claimed, got %d: %v", claimed, err)
+		}
+
+		timestamp, _ := strconv.ParseInt(target.headers.Get(model.HEADER_TIMESTAMP), 10, 64)
+		if !model.Verify("s3cr3t", timestamp, target.body, target.headers.Get(model.HEADER_SIGNATURE)) {
+			t.Errorf("Expected the post to be signed with the secret of the webhook, got %s", target.headers.Get(model.HEADER_SIGNATURE))
+		}
+		if model.Verify("other", timestamp, target.body, target.headers.Get(model.HEADER_SIGNATURE)) {
+			t.Error("Expected the signature not to match another secret")
+		}
+		if target.headers.Get(model.HEADER_EVENT) != "example.create" || target.headers.Get(model.HEADER_DELIVERY) != model.DeliveryId("creations", created.Id) {
+			t.Errorf("Expected the event and the delivery in the headers, got %v", target.headers)
+		}
+
+		d, _ := repository.FindDelivery(ctx, model.DeliveryId("creations", created.Id))
+		if d.Status != model.DeliverySucceeded || d.DeliveredAt == nil || len(d.Attempts) != 1 || d.Attempts[0].ResponseCode != http.StatusOK {
+			t.Errorf("Expected the delivery to succeed with the response code logged, got %#v", d)
+		}
+	})
+
+	t.Run("Test Retries And Replay", func(t *testing.T) {
+		t.Log("Testing Retries And Replay")
+
+		target.answer(http.StatusServiceUnavailable)
+		e, _ := outbox.NewEvent(ctx, "example", "retried", "create", 1, nil)
+		if err := NewFanoutPublisher(&recordingPublisher{}, repository).Publish(ctx, e); err != nil {
+			t.Fatalf("Error on Publish: %s", err)
+		}
+		id := model.DeliveryId("creations", e.Id)
+
+		if claimed, _ := dispatcher.DispatchOnce(ctx); claimed != 1 {
+			t.Fatalf("Expected the delivery to be claimed, got %d", claimed)
+		}
+		if claimed, _ := dispatcher.DispatchOnce(ctx); claimed != 0 {
+			t.Fatalf("Expected the failed delivery to wait for its retry, got %d", claimed)
+		}
+
+		retried := deliveries.Rows[id]
+		if retried.Status != model.DeliveryPending || len(retried.Attempts) != 1 || retried.Attempts[0].ResponseCode != http.StatusServiceUnavailable || !retried.NextAttemptAt.After(time.Now()) {
+			t.Fatalf("Expected the failed attempt to be logged and retried later, got %#v", retried)
+		}
+		// the retry is due
+		retried.NextAttemptAt = time.Now()
+		deliveries.Rows[id] = retried
+
+		if claimed, _ := dispatcher.DispatchOnce(ctx); claimed != 1 {
+			t.Fatalf("Expected the retry to be claimed, got %d", claimed)
+		}
+		if failed := deliveries.Rows[id]; failed.Status != model.DeliveryFailed || len(failed.Attempts) != webhooksOptions.MaxAttempts {
+			t.Fatalf("Expected the delivery to fail after the maximum attempts, got %#v", failed)
+		}
+
+		target.answer(http.StatusNoContent)
+		if _, err := repository.Replay(ctx, id, time.Now().UTC()); err != nil {
+			t.Fatalf("Error on Replay: %s", err)
+		}
+		if claimed, _ := dispatcher.DispatchOnce(ctx); claimed != 1 {
+			t.Fatalf("Expected the replay to be claimed, got %d", claimed)
+		}
+		if replayed := deliveries.Rows[id]; replayed.Status != model.DeliverySucceeded || len(replayed.Attempts) != 3 || replayed.Attempts[2].ResponseCode != http.StatusNoContent {
+			t.Errorf("Expected the replay to succeed and keep the log, got %#v", replayed)
+		}
+	})
+
+	t.Run("Test Deleted Webhook", func(t *testing.T) {
+		t.Log("Testing Deleted Webhook")
+
+		e, _ := outbox.NewEvent(ctx, "example", "orphan", "create", 1, nil)
+		if err := NewFanoutPublisher(&recordingPublisher{}, repository).Publish(ctx, e); err != nil {
+			t.Fatalf("Error on Publish: %s", err)
+		}
+		if err := repository.Delete(ctx, "creations"); err != nil {
+			t.Fatalf("Error on Delete: %s", err)
+		}
+
+		posts := target.posts
+		if claimed, _ := dispatcher.DispatchOnce(ctx); claimed != 1 {
+			t.Fatalf("Expected the delivery to be claimed, got %d", claimed)
+		}
+		if target.posts != posts {
+			t.Error("Expected no post for a deleted webhook")
+		}
+		if orphan := deliveries.Rows[model.DeliveryId("creations", e.Id)]; orphan.Status != model.DeliveryFailed {
+			t.Errorf("Expected the delivery of a deleted webhook to fail, got %#v", orphan)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	outbox_model "github.com/morphy76/g-fe-server/pkg/outbox"
+	model "github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+// fanoutPublisher appends a delivery for every webhook of the tenant of an event accepting its type, then hands
+// the event to the delegate; the deliveries of an event delivered again are appended once
+type fanoutPublisher struct {
+	delegate   outbox_model.Publisher
+	repository model.Repository
+}
+
+// NewFanoutPublisher decorates the publisher of the outbox relay
+func NewFanoutPublisher(delegate outbox_model.Publisher, repository model.Repository) outbox_model.Publisher {
+	return &fanoutPublisher{
+		delegate:   delegate,
+		repository: repository,
+	}
+}
+
+func (p *fanoutPublisher) Name() string {
+	return p.delegate.Name()
+}
+
+func (p *fanoutPublisher) Publish(ctx context.Context, e outbox_model.Event) error {
+	if err := p.fanout(ctx, e); err != nil {
+		return err
+	}
+	return p.delegate.Publish(ctx, e)
+}
+
+func (p *fanoutPublisher) Close() error {
+	return p.delegate.Close()
+}
+
+func (p *fanoutPublisher) fanout(ctx context.Context, e outbox_model.Event) error {
+
+	if e.Tenant == "" {
+		return nil
+	}
+
+	subscriptions, err := p.repository.FindByEventType(ctx, e.Tenant, e.Type)
+	if err != nil || len(subscriptions) == 0 {
+		return err
+	}
+
+	payload, err := json.Marshal(e.Message)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]model.Delivery, len(subscriptions))
+	for i, subscription := range subscriptions {
+		deliveries[i] = model.Delivery{
+			Id:             model.DeliveryId(subscription.Id, e.Id),
+			SubscriptionId: subscription.Id,
+			Tenant:         e.Tenant,
+			EventId:        e.Id,
+			EventType:      e.Type,
+			Payload:        payload,
+			TraceContext:   e.TraceContext,
+			Status:         model.DeliveryPending,
+			Attempts:       []model.Attempt{},
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}
+	}
+	return p.repository.AppendDeliveries(ctx, deliveries...)
+}
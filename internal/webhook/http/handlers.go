@@ -0,0 +1,387 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	apikey_http "github.com/morphy76/g-fe-server/internal/apikey/http"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/webhook/api"
+	"github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+const (
+	SCOPE_MANAGE = "webhooks:manage"
+
+	pathParamWebhookId  = "webhookId"
+	pathParamDeliveryId = "deliveryId"
+
+	queryParamLimit = "limit"
+)
+
+type createRequest struct {
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+func WebhookHandlers(functionalRouter *mux.Router, app_context context.Context) {
+
+	serveOptions := app_http.ExtractServeOptions(app_context)
+	ctxRoot := serveOptions.ContextRoot
+
+	var (
+		apiRoot               = fmt.Sprintf("%s/api/webhooks", ctxRoot)
+		apiParamWebhookId     = fmt.Sprintf("{%s}", pathParamWebhookId)
+		apiParamDeliveryId    = fmt.Sprintf("{%s}", pathParamDeliveryId)
+		apiResourceWebhookId  = fmt.Sprintf("%s/%s", apiRoot, apiParamWebhookId)
+		apiDeliveries         = fmt.Sprintf("%s/deliveries", apiResourceWebhookId)
+		apiResourceDeliveryId = fmt.Sprintf("%s/%s", apiDeliveries, apiParamDeliveryId)
+
+		webhookRouter = functionalRouter.PathPrefix("/webhooks").Subrouter()
+	)
+	webhookRouter.Use(apikey_http.RequirePrincipal)
+	webhookRouter.Use(apikey_http.RequireScope(SCOPE_MANAGE))
+
+	webhookRouter.Methods(http.MethodGet).HandlerFunc(onList).Path("").Name("GET " + apiRoot)
+	webhookRouter.Methods(http.MethodPost).HandlerFunc(onCreate).Path("").Name("POST " + apiRoot)
+	webhookRouter.Methods(http.MethodGet).HandlerFunc(onGet).Path("/" + apiParamWebhookId).Name("GET " + apiResourceWebhookId)
+	webhookRouter.Methods(http.MethodDelete).HandlerFunc(onDelete).Path("/" + apiParamWebhookId).Name("DELETE " + apiResourceWebhookId)
+	webhookRouter.Methods(http.MethodGet).HandlerFunc(onListDeliveries).Path("/" + apiParamWebhookId + "/deliveries").Name("GET " + apiDeliveries)
+	webhookRouter.Methods(http.MethodGet).HandlerFunc(onGetDelivery).Path("/" + apiParamWebhookId + "/deliveries/" + apiParamDeliveryId).Name("GET " + apiResourceDeliveryId)
+	webhookRouter.Methods(http.MethodPost).HandlerFunc(onReplay).Path("/" + apiParamWebhookId + "/deliveries/" + apiParamDeliveryId + "/replay").Name("POST " + apiResourceDeliveryId + "/replay")
+}
+
+var onList = api.ContextualizedApi(onContextualizedList)
+var onCreate = api.ContextualizedApi(onContextualizedCreate)
+var onGet = api.ContextualizedApi(onContextualizedGet)
+var onDelete = api.ContextualizedApi(onContextualizedDelete)
+var onListDeliveries = api.ContextualizedApi(onContextualizedListDeliveries)
+var onGetDelivery = api.ContextualizedApi(onContextualizedGetDelivery)
+var onReplay = api.ContextualizedApi(onContextualizedReplay)
+
+func onContextualizedList(
+	useLog zerolog.Logger,
+	repository webhook.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start listing webhooks")
+		defer func() {
+			useLog.Info().Msg("End listing webhooks")
+		}()
+
+		ownership := app_http.ExtractOwnership(r.Context())
+
+		subscriptions, err := repository.FindAll(r.Context(), ownership.Tenant)
+		if err != nil {
+			respondError(w, r, useLog, "FindAll failed", err)
+			return
+		}
+		for i := range subscriptions {
+			subscriptions[i].Secret = ""
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(subscriptions)
+	}
+}
+
+// onContextualizedCreate registers a webhook, the secret signing its deliveries is answered this time only
+func onContextualizedCreate(
+	useLog zerolog.Logger,
+	repository webhook.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start creating webhook")
+		defer func() {
+			useLog.Info().Msg("End creating webhook")
+		}()
+
+		ownership := app_http.ExtractOwnership(r.Context())
+		principal, _ := app_http.ExtractPrincipal(r.Context())
+
+		var req createRequest
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			useLog.Error().Msg(err.Error())
+			app_http.RespondProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := webhook.ValidateUrl(req.Url); err != nil {
+			app_http.RespondProblem(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(req.EventTypes) == 0 {
+			app_http.RespondProblem(w, r, http.StatusBadRequest, "event types are required")
+			return
+		}
+		for _, eventType := range req.EventTypes {
+			if eventType == "" {
+				app_http.RespondProblem(w, r, http.StatusBadRequest, "event types can not be empty")
+				return
+			}
+		}
+		if ownership.Tenant == "" {
+			app_http.RespondProblem(w, r, http.StatusBadRequest, "tenant is required")
+			return
+		}
+
+		id, err := webhook.GenerateId()
+		if err != nil {
+			respondError(w, r, useLog, "Create failed", err)
+			return
+		}
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			respondError(w, r, useLog, "Create failed", err)
+			return
+		}
+
+		s := webhook.Subscription{
+			Id:         id,
+			Tenant:     ownership.Tenant,
+			Url:        req.Url,
+			EventTypes: req.EventTypes,
+			Secret:     secret,
+			CreatedBy:  principal.Subject,
+			CreatedAt:  time.Now().UTC(),
+		}
+
+		err = repository.Save(r.Context(), s)
+		if err != nil {
+			if webhook.IsAlreadyExists(err) {
+				app_http.RespondProblem(w, r, http.StatusConflict, err.Error())
+				return
+			}
+			respondError(w, r, useLog, "Create failed", err)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(s)
+	}
+}
+
+func onContextualizedGet(
+	useLog zerolog.Logger,
+	repository webhook.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start fetching webhook")
+		defer func() {
+			useLog.Info().Msg("End fetching webhook")
+		}()
+
+		s, ok := findOwned(w, r, useLog, repository)
+		if !ok {
+			return
+		}
+		s.Secret = ""
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(s)
+	}
+}
+
+// onContextualizedDelete stops the deliveries of the webhook, the pending ones fail when they are due
+func onContextualizedDelete(
+	useLog zerolog.Logger,
+	repository webhook.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start deleting webhook")
+		defer func() {
+			useLog.Info().Msg("End deleting webhook")
+		}()
+
+		s, ok := findOwned(w, r, useLog, repository)
+		if !ok {
+			return
+		}
+
+		err := repository.Delete(r.Context(), s.Id)
+		if err != nil {
+			if webhook.IsNotFound(err) {
+				app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+			respondError(w, r, useLog, "Delete failed", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// onContextualizedListDeliveries answers the delivery log of the webhook, newest first
+func onContextualizedListDeliveries(
+	useLog zerolog.Logger,
+	repository webhook.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start listing webhook deliveries")
+		defer func() {
+			useLog.Info().Msg("End listing webhook deliveries")
+		}()
+
+		limit := webhook.DefaultDeliveriesLimit
+		if value := r.URL.Query().Get(queryParamLimit); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				app_http.RespondProblem(w, r, http.StatusBadRequest, "limit must be a positive number")
+				return
+			}
+			limit = min(parsed, webhook.MaxDeliveriesLimit)
+		}
+
+		s, ok := findOwned(w, r, useLog, repository)
+		if !ok {
+			return
+		}
+
+		deliveries, err := repository.FindDeliveries(r.Context(), s.Id, limit)
+		if err != nil {
+			respondError(w, r, useLog, "FindDeliveries failed", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(deliveries)
+	}
+}
+
+func onContextualizedGetDelivery(
+	useLog zerolog.Logger,
+	repository webhook.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start fetching webhook delivery")
+		defer func() {
+			useLog.Info().Msg("End fetching webhook delivery")
+		}()
+
+		d, ok := findOwnedDelivery(w, r, useLog, repository)
+		if !ok {
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(d)
+	}
+}
+
+// onContextualizedReplay makes a delivery due again, whatever its outcome; pending deliveries are due already
+func onContextualizedReplay(
+	useLog zerolog.Logger,
+	repository webhook.Repository,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		useLog.Trace().Msg("Start replaying webhook delivery")
+		defer func() {
+			useLog.Info().Msg("End replaying webhook delivery")
+		}()
+
+		d, ok := findOwnedDelivery(w, r, useLog, repository)
+		if !ok {
+			return
+		}
+		if d.Status == webhook.DeliveryPending {
+			app_http.RespondProblem(w, r, http.StatusConflict, "delivery is pending")
+			return
+		}
+
+		d, err := repository.Replay(r.Context(), d.Id, time.Now().UTC())
+		if err != nil {
+			if webhook.IsNotFound(err) {
+				app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+				return
+			}
+			respondError(w, r, useLog, "Replay failed", err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(d)
+	}
+}
+
+func findOwned(
+	w http.ResponseWriter,
+	r *http.Request,
+	useLog zerolog.Logger,
+	repository webhook.Repository,
+) (webhook.Subscription, bool) {
+
+	ownership := app_http.ExtractOwnership(r.Context())
+	webhookId := mux.Vars(r)[pathParamWebhookId]
+
+	s, err := repository.FindById(r.Context(), webhookId)
+	if err == nil && s.Tenant != ownership.Tenant {
+		err = webhook.ErrNotFound
+	}
+	if err != nil {
+		if webhook.IsNotFound(err) {
+			app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+			return s, false
+		}
+		respondError(w, r, useLog, "Get failed", err)
+		return s, false
+	}
+
+	return s, true
+}
+
+// findOwnedDelivery finds a delivery of a webhook of the tenant of the caller
+func findOwnedDelivery(
+	w http.ResponseWriter,
+	r *http.Request,
+	useLog zerolog.Logger,
+	repository webhook.Repository,
+) (webhook.Delivery, bool) {
+
+	s, ok := findOwned(w, r, useLog, repository)
+	if !ok {
+		return webhook.Delivery{}, false
+	}
+
+	d, err := repository.FindDelivery(r.Context(), mux.Vars(r)[pathParamDeliveryId])
+	if err == nil && d.SubscriptionId != s.Id {
+		err = webhook.ErrNotFound
+	}
+	if err != nil {
+		if webhook.IsNotFound(err) {
+			app_http.RespondProblem(w, r, http.StatusNotFound, err.Error())
+			return d, false
+		}
+		respondError(w, r, useLog, "Get failed", err)
+		return d, false
+	}
+
+	return d, true
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, useLog zerolog.Logger, status string, err error) {
+
+	span := trace.SpanFromContext(r.Context())
+	span.SetStatus(codes.Error, status)
+	span.RecordError(err)
+
+	useLog.Error().Msg(err.Error())
+	app_http.RespondProblem(w, r, http.StatusInternalServerError, err.Error())
+}
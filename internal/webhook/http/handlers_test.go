@@ -0,0 +1,145 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	app_http "github.com/morphy76/g-fe-server/internal/http"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/internal/serve"
+	impl "github.com/morphy76/g-fe-server/internal/webhook/repository/impl"
+	"github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+func TestHandlersSuite(t *testing.T) {
+	t.Log("Test Handlers Suite")
+
+	appContext := app_http.InjectServeOptions(context.Background(), &options.ServeOptions{ContextRoot: "/be"})
+	dbOptions := &options.DbOptions{Type: options.RepositoryTypeMemoryDB}
+	memoryClient := db.NewMemoryDbClient()
+	repository, _ := impl.NewMemoryRepository(memoryClient)
+
+	router := mux.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := r.Header.Get("X-Test-Tenant")
+			useContext := app_http.InjectLogger(r.Context(), zerolog.Nop())
+			useContext = db.InjectDb(db.InjectDbOptions(useContext, dbOptions), memoryClient)
+			useContext = app_http.InjectOwnership(useContext, serve.Ownership{Tenant: tenant})
+			useContext = app_http.InjectPrincipal(useContext, app_http.Principal{
+				Kind:    app_http.PrincipalApiKey,
+				Subject: "tester",
+				Tenant:  tenant,
+				Scopes:  []string{SCOPE_MANAGE},
+			})
+			next.ServeHTTP(w, r.WithContext(useContext))
+		})
+	})
+	WebhookHandlers(router.PathPrefix("/be/api").Subrouter(), appContext)
+
+	call := func(tenant string, method string, path string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/be/api/webhooks"+path, strings.NewReader(body))
+		r.Header.Set("X-Test-Tenant", tenant)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	var created webhook.Subscription
+
+	t.Run("Test Create", func(t *testing.T) {
+		t.Log("Testing Create")
+
+		if w := call("t1", http.MethodPost, "", `{"url":"ftp://hooks.example.com","event_types":["*"]}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d for a non http url, got %d", http.StatusBadRequest, w.Code)
+		}
+		if w := call("t1", http.MethodPost, "", `{"url":"http://169.254.169.254/latest","event_types":["*"]}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d for a link-local url, got %d", http.StatusBadRequest, w.Code)
+		}
+		if w := call("t1", http.MethodPost, "", `{"url":"https://hooks.example.com/hook","event_types":[]}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d without event types, got %d", http.StatusBadRequest, w.Code)
+		}
+
+		w := call("t1", http.MethodPost, "", `{"url":"https://hooks.example.com/hook","event_types":["example.create"]}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d", http.StatusCreated, w.Code)
+		}
+		json.NewDecoder(w.Body).Decode(&created)
+		if created.Id == "" || created.Secret == "" || created.Tenant != "t1" || created.CreatedBy != "tester" {
+			t.Fatalf("Expected the webhook of the tenant with its secret, got %#v", created)
+		}
+	})
+
+	t.Run("Test Get", func(t *testing.T) {
+		t.Log("Testing Get")
+
+		w := call("t1", http.MethodGet, "", "")
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), created.Id) || strings.Contains(w.Body.String(), created.Secret) {
+			t.Fatalf("Expected the webhooks of the tenant without their secret, got %d %s", w.Code, w.Body.String())
+		}
+		if w := call("t1", http.MethodGet, "/"+created.Id, ""); w.Code != http.StatusOK || strings.Contains(w.Body.String(), created.Secret) {
+			t.Fatalf("Expected the webhook without its secret, got %d %s", w.Code, w.Body.String())
+		}
+		if w := call("t2", http.MethodGet, "/"+created.Id, ""); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected the webhook to be hidden from other tenants, got %d", w.Code)
+		}
+	})
+
+	t.Run("Test Deliveries And Replay", func(t *testing.T) {
+		t.Log("Testing Deliveries And Replay")
+
+		now := time.Now().UTC()
+		ctx := context.Background()
+		repository.AppendDeliveries(ctx,
+			webhook.Delivery{Id: "pending", SubscriptionId: created.Id, Tenant: "t1", Status: webhook.DeliveryPending, NextAttemptAt: now, CreatedAt: now},
+			webhook.Delivery{Id: "failed", SubscriptionId: created.Id, Tenant: "t1", Status: webhook.DeliveryPending, NextAttemptAt: now, CreatedAt: now.Add(time.Second)},
+		)
+		repository.RecordAttempt(ctx, "failed", webhook.Attempt{At: now, ResponseCode: http.StatusBadGateway}, webhook.DeliveryFailed, now)
+
+		w := call("t1", http.MethodGet, "/"+created.Id+"/deliveries?limit=1", "")
+		var log []webhook.Delivery
+		json.NewDecoder(w.Body).Decode(&log)
+		if w.Code != http.StatusOK || len(log) != 1 || log[0].Id != "failed" || log[0].Attempts[0].ResponseCode != http.StatusBadGateway {
+			t.Fatalf("Expected the latest delivery with its response codes, got %d %v", w.Code, log)
+		}
+		if w := call("t1", http.MethodGet, "/"+created.Id+"/deliveries?limit=none", ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+
+		if w := call("t1", http.MethodPost, "/"+created.Id+"/deliveries/pending/replay", ""); w.Code != http.StatusConflict {
+			t.Fatalf("Expected status %d for a pending delivery, got %d", http.StatusConflict, w.Code)
+		}
+		if w := call("t2", http.MethodPost, "/"+created.Id+"/deliveries/failed/replay", ""); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d for another tenant, got %d", http.StatusNotFound, w.Code)
+		}
+		w = call("t1", http.MethodPost, "/"+created.Id+"/deliveries/failed/replay", "")
+		var replayed webhook.Delivery
+		json.NewDecoder(w.Body).Decode(&replayed)
+		if w.Code != http.StatusAccepted || replayed.Status != webhook.DeliveryPending || len(replayed.Attempts) != 1 {
+			t.Fatalf("Expected the delivery to be pending again, got %d %#v", w.Code, replayed)
+		}
+	})
+
+	t.Run("Test Delete", func(t *testing.T) {
+		t.Log("Testing Delete")
+
+		if w := call("t2", http.MethodDelete, "/"+created.Id, ""); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d for another tenant, got %d", http.StatusNotFound, w.Code)
+		}
+		if w := call("t1", http.MethodDelete, "/"+created.Id, ""); w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+		if w := call("t1", http.MethodGet, "/"+created.Id+"/deliveries", ""); w.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
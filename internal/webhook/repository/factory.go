@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+	impl "github.com/morphy76/g-fe-server/internal/webhook/repository/impl"
+	model "github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+// NewRepository builds the webhooks of the storage configured in the context; webhooks are fed by the outbox,
+// so sql storages have none yet
+func NewRepository(requestContext context.Context) (model.Repository, error) {
+
+	dbOptions := db.ExtractDbOptions(requestContext)
+	dbClient := db.ExtractDb(requestContext)
+
+	switch dbOptions.Type {
+	case options.RepositoryTypeMemoryDB:
+		memoryClient, ok := dbClient.(*db.MemoryDbClient)
+		if !ok {
+			return nil, errors.New("memory client not found in request context")
+		}
+
+		rv, err := impl.NewMemoryRepository(memoryClient)
+		if err != nil {
+			return nil, err
+		}
+
+		return newTracedRepository(rv, "memory"), nil
+	case options.RepositoryTypeMongoDB:
+		if dbClient == nil {
+			return nil, errors.New("MongoDB client not found in request context")
+		}
+
+		mongoClient := dbClient.(*mongo.Client)
+
+		var rv model.Repository = &impl.MongoRepository{
+			DbOptions: dbOptions,
+			Client:    mongoClient,
+		}
+
+		return newTracedRepository(rv, "mongodb"), nil
+	default:
+		return nil, model.ErrUnknownRepositoryType
+	}
+}
@@ -0,0 +1,241 @@
+package webhook
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+// the names of the subscriptions and of the deliveries in the stores of the memory client
+const (
+	MEMORY_STORE            = "webhooks"
+	MEMORY_DELIVERIES_STORE = "webhook_deliveries"
+)
+
+type MemoryRepository struct {
+	subscriptions *db.MemoryTable[string, webhook.Subscription]
+	deliveries    *db.MemoryTable[string, webhook.Delivery]
+}
+
+func NewMemoryRepository(client *db.MemoryDbClient) (webhook.Repository, error) {
+
+	subscriptions, err := db.MemoryTableOf(client, MEMORY_STORE, func(s webhook.Subscription) string { return s.Id })
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := db.MemoryTableOf(client, MEMORY_DELIVERIES_STORE, func(d webhook.Delivery) string { return d.Id })
+	if err != nil {
+		return nil, err
+	}
+
+	return &MemoryRepository{
+		subscriptions: subscriptions,
+		deliveries:    deliveries,
+	}, nil
+}
+
+func (r *MemoryRepository) FindAll(ctx context.Context, tenant string) ([]webhook.Subscription, error) {
+	return r.filter(ctx, func(s webhook.Subscription) bool { return s.Tenant == tenant })
+}
+
+func (r *MemoryRepository) FindById(ctx context.Context, id string) (webhook.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return webhook.Subscription{}, err
+	}
+
+	r.subscriptions.Lock.RLock()
+	defer r.subscriptions.Lock.RUnlock()
+
+	rv, ok := r.subscriptions.Rows[id]
+	if !ok {
+		return rv, webhook.ErrNotFound
+	}
+	return rv, nil
+}
+
+func (r *MemoryRepository) FindByEventType(ctx context.Context, tenant string, eventType string) ([]webhook.Subscription, error) {
+	return r.filter(ctx, func(s webhook.Subscription) bool { return s.Tenant == tenant && s.Accepts(eventType) })
+}
+
+func (r *MemoryRepository) Save(ctx context.Context, s webhook.Subscription) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.subscriptions.Lock.Lock()
+	defer r.subscriptions.Lock.Unlock()
+
+	if _, ok := r.subscriptions.Rows[s.Id]; ok {
+		return webhook.ErrAlreadyExists
+	}
+	r.subscriptions.Rows[s.Id] = s
+	return nil
+}
+
+func (r *MemoryRepository) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.subscriptions.Lock.Lock()
+	defer r.subscriptions.Lock.Unlock()
+
+	if _, ok := r.subscriptions.Rows[id]; !ok {
+		return webhook.ErrNotFound
+	}
+	delete(r.subscriptions.Rows, id)
+	return nil
+}
+
+func (r *MemoryRepository) AppendDeliveries(ctx context.Context, deliveries ...webhook.Delivery) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.deliveries.Lock.Lock()
+	defer r.deliveries.Lock.Unlock()
+
+	for _, d := range deliveries {
+		if _, ok := r.deliveries.Rows[d.Id]; !ok {
+			r.deliveries.Rows[d.Id] = d
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) FindDelivery(ctx context.Context, id string) (webhook.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return webhook.Delivery{}, err
+	}
+
+	r.deliveries.Lock.RLock()
+	defer r.deliveries.Lock.RUnlock()
+
+	rv, ok := r.deliveries.Rows[id]
+	if !ok {
+		return rv, webhook.ErrNotFound
+	}
+	return rv, nil
+}
+
+func (r *MemoryRepository) FindDeliveries(ctx context.Context, subscriptionId string, limit int) ([]webhook.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.deliveries.Lock.RLock()
+	defer r.deliveries.Lock.RUnlock()
+
+	rv := make([]webhook.Delivery, 0)
+	for _, d := range r.deliveries.Rows {
+		if d.SubscriptionId == subscriptionId {
+			rv = append(rv, d)
+		}
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		if rv[i].CreatedAt.Equal(rv[j].CreatedAt) {
+			return rv[i].Id > rv[j].Id
+		}
+		return rv[i].CreatedAt.After(rv[j].CreatedAt)
+	})
+	if limit > 0 && len(rv) > limit {
+		rv = rv[:limit]
+	}
+	return rv, nil
+}
+
+func (r *MemoryRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.deliveries.Lock.Lock()
+	defer r.deliveries.Lock.Unlock()
+
+	rv := make([]webhook.Delivery, 0)
+	for _, d := range r.deliveries.Rows {
+		if d.Status == webhook.DeliveryPending && !d.NextAttemptAt.After(now) {
+			rv = append(rv, d)
+		}
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		if rv[i].NextAttemptAt.Equal(rv[j].NextAttemptAt) {
+			return rv[i].CreatedAt.Before(rv[j].CreatedAt)
+		}
+		return rv[i].NextAttemptAt.Before(rv[j].NextAttemptAt)
+	})
+	if len(rv) > limit {
+		rv = rv[:limit]
+	}
+
+	for _, d := range rv {
+		d.NextAttemptAt = now.Add(lease)
+		r.deliveries.Rows[d.Id] = d
+	}
+	return rv, nil
+}
+
+func (r *MemoryRepository) RecordAttempt(ctx context.Context, id string, attempt webhook.Attempt, status webhook.DeliveryStatus, nextAttemptAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r.deliveries.Lock.Lock()
+	defer r.deliveries.Lock.Unlock()
+
+	d, ok := r.deliveries.Rows[id]
+	if !ok {
+		return webhook.ErrNotFound
+	}
+	d.Attempts = append(slices.Clip(d.Attempts), attempt)
+	d.Status = status
+	d.NextAttemptAt = nextAttemptAt
+	if status == webhook.DeliverySucceeded {
+		d.DeliveredAt = &attempt.At
+	}
+	r.deliveries.Rows[id] = d
+	return nil
+}
+
+func (r *MemoryRepository) Replay(ctx context.Context, id string, now time.Time) (webhook.Delivery, error) {
+	if err := ctx.Err(); err != nil {
+		return webhook.Delivery{}, err
+	}
+
+	r.deliveries.Lock.Lock()
+	defer r.deliveries.Lock.Unlock()
+
+	d, ok := r.deliveries.Rows[id]
+	if !ok {
+		return d, webhook.ErrNotFound
+	}
+	d.Status = webhook.DeliveryPending
+	d.NextAttemptAt = now
+	d.ReplayedAt = &now
+	r.deliveries.Rows[id] = d
+	return d, nil
+}
+
+func (r *MemoryRepository) filter(ctx context.Context, accept func(webhook.Subscription) bool) ([]webhook.Subscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	r.subscriptions.Lock.RLock()
+	defer r.subscriptions.Lock.RUnlock()
+
+	rv := make([]webhook.Subscription, 0)
+	for _, s := range r.subscriptions.Rows {
+		if accept(s) {
+			rv = append(rv, s)
+		}
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].CreatedAt.Before(rv[j].CreatedAt)
+	})
+	return rv, nil
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+func TestMemoryRepositorySuite(t *testing.T) {
+	t.Log("Test MemoryRepository Suite")
+
+	ctx := context.Background()
+	repo, _ := NewMemoryRepository(db.NewMemoryDbClient())
+	t.Logf("Repository URL: memory")
+
+	now := time.Now().UTC()
+
+	t.Run("Test Subscriptions", func(t *testing.T) {
+		t.Log("Testing Memory Subscriptions")
+
+		for _, s := range []webhook.Subscription{
+			{Id: "w1", Tenant: "t1", Url: "http://localhost/w1", EventTypes: []string{"example.create"}, CreatedAt: now},
+			{Id: "w2", Tenant: "t1", Url: "http://localhost/w2", EventTypes: []string{webhook.EVENT_TYPE_ANY}, CreatedAt: now.Add(time.Second)},
+			{Id: "w3", Tenant: "t2", Url: "http://localhost/w3", EventTypes: []string{"example.create"}, CreatedAt: now},
+		} {
+			if err := repo.Save(ctx, s); err != nil {
+				t.Fatalf("Error on Save: %s", err)
+			}
+		}
+		if err := repo.Save(ctx, webhook.Subscription{Id: "w1"}); !webhook.IsAlreadyExists(err) {
+			t.Fatalf("Expected ErrAlreadyExists, got %v", err)
+		}
+
+		if items, _ := repo.FindAll(ctx, "t1"); len(items) != 2 || items[0].Id != "w1" {
+			t.Fatalf("Expected the webhooks of the tenant by creation, got %v", items)
+		}
+		if items, _ := repo.FindByEventType(ctx, "t1", "example.create"); len(items) != 2 {
+			t.Fatalf("Expected 2 webhooks accepting the creations, got %d", len(items))
+		}
+		if items, _ := repo.FindByEventType(ctx, "t1", "example.delete"); len(items) != 1 || items[0].Id != "w2" {
+			t.Fatalf("Expected the webhook of any event to accept the deletions, got %v", items)
+		}
+
+		if err := repo.Delete(ctx, "w3"); err != nil {
+			t.Fatalf("Error on Delete: %s", err)
+		}
+		if _, err := repo.FindById(ctx, "w3"); !webhook.IsNotFound(err) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+		if err := repo.Delete(ctx, "w3"); !webhook.IsNotFound(err) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Test Deliveries", func(t *testing.T) {
+		t.Log("Testing Memory Deliveries")
+
+		first := webhook.Delivery{Id: "d1", SubscriptionId: "w1", Status: webhook.DeliveryPending, NextAttemptAt: now, CreatedAt: now}
+		second := webhook.Delivery{Id: "d2", SubscriptionId: "w1", Status: webhook.DeliveryPending, NextAttemptAt: now, CreatedAt: now.Add(time.Second)}
+		if err := repo.AppendDeliveries(ctx, first, second); err != nil {
+			t.Fatalf("Error on AppendDeliveries: %s", err)
+		}
+		if err := repo.AppendDeliveries(ctx, webhook.Delivery{Id: "d1", SubscriptionId: "w1", Status: webhook.DeliveryFailed}); err != nil {
+			t.Fatalf("Error on AppendDeliveries: %s", err)
+		}
+		if d, _ := repo.FindDelivery(ctx, "d1"); d.Status != webhook.DeliveryPending {
+			t.Fatal("Expected the appended delivery to be kept")
+		}
+
+		if items, _ := repo.FindDeliveries(ctx, "w1", 1); len(items) != 1 || items[0].Id != "d2" {
+			t.Fatalf("Expected the newest delivery, got %v", items)
+		}
+
+		claimed, err := repo.ClaimDeliveries(ctx, now, time.Minute, 10)
+		if err != nil {
+			t.Fatalf("Error on ClaimDeliveries: %s", err)
+		}
+		if len(claimed) != 2 || claimed[0].Id != "d1" {
+			t.Fatalf("Expected the due deliveries by creation, got %v", claimed)
+		}
+		if claimed, _ := repo.ClaimDeliveries(ctx, now, time.Minute, 10); len(claimed) != 0 {
+			t.Fatalf("Expected the leased deliveries to be hidden, got %d", len(claimed))
+		}
+
+		attempt := webhook.Attempt{At: now, ResponseCode: 200}
+		if err := repo.RecordAttempt(ctx, "d1", attempt, webhook.DeliverySucceeded, now); err != nil {
+			t.Fatalf("Error on RecordAttempt: %s", err)
+		}
+		d, _ := repo.FindDelivery(ctx, "d1")
+		if d.Status != webhook.DeliverySucceeded || d.DeliveredAt == nil || len(d.Attempts) != 1 {
+			t.Fatalf("Expected the delivery to succeed with its attempt, got %v", d)
+		}
+
+		if err := repo.RecordAttempt(ctx, "d2", webhook.Attempt{At: now, ResponseCode: 500}, webhook.DeliveryFailed, now); err != nil {
+			t.Fatalf("Error on RecordAttempt: %s", err)
+		}
+
+		replayedAt := now.Add(time.Hour)
+		d, err = repo.Replay(ctx, "d1", replayedAt)
+		if err != nil {
+			t.Fatalf("Error on Replay: %s", err)
+		}
+		if d.Status != webhook.DeliveryPending || len(d.Attempts) != 1 || d.AttemptsSinceReplay() != 0 {
+			t.Fatalf("Expected a pending delivery keeping its log, got %v", d)
+		}
+		if claimed, _ := repo.ClaimDeliveries(ctx, replayedAt, time.Minute, 10); len(claimed) != 1 || claimed[0].Id != "d1" {
+			t.Fatalf("Expected the replayed delivery to be due, got %v", claimed)
+		}
+
+		if _, err := repo.Replay(ctx, "unknown", now); !webhook.IsNotFound(err) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
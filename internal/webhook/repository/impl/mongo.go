@@ -0,0 +1,277 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	mongo_opts "go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/morphy76/g-fe-server/internal/db"
+	"github.com/morphy76/g-fe-server/internal/options"
+	"github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+const (
+	MONGO_COLLECTION            = "webhooks"
+	MONGO_DELIVERIES_COLLECTION = "webhook_deliveries"
+)
+
+type MongoRepository struct {
+	DbOptions     *options.DbOptions
+	Client        *mongo.Client
+	subscriptions *mongo.Collection
+	deliveries    *mongo.Collection
+}
+
+func (r *MongoRepository) FindAll(ctx context.Context, tenant string) ([]webhook.Subscription, error) {
+	return r.find(ctx, bson.D{{Key: "tenant", Value: tenant}})
+}
+
+func (r *MongoRepository) FindById(ctx context.Context, id string) (webhook.Subscription, error) {
+
+	rv := webhook.Subscription{}
+	if err := r.lazyBindCollections(); err != nil {
+		return rv, err
+	}
+
+	err := r.subscriptions.FindOne(ctx, bson.D{{Key: "id", Value: id}}).Decode(&rv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return rv, webhook.ErrNotFound
+		}
+		return rv, err
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) FindByEventType(ctx context.Context, tenant string, eventType string) ([]webhook.Subscription, error) {
+	return r.find(ctx, bson.D{
+		{Key: "tenant", Value: tenant},
+		{Key: "event_types", Value: bson.D{{Key: "$in", Value: bson.A{eventType, webhook.EVENT_TYPE_ANY}}}},
+	})
+}
+
+func (r *MongoRepository) Save(ctx context.Context, s webhook.Subscription) error {
+
+	if err := r.lazyBindCollections(); err != nil {
+		return err
+	}
+
+	_, err := r.subscriptions.InsertOne(ctx, s)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return webhook.ErrAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) Delete(ctx context.Context, id string) error {
+
+	if err := r.lazyBindCollections(); err != nil {
+		return err
+	}
+
+	deleteResult, err := r.subscriptions.DeleteOne(ctx, bson.D{{Key: "id", Value: id}})
+	if err != nil {
+		return err
+	}
+	if deleteResult.DeletedCount == 0 {
+		return webhook.ErrNotFound
+	}
+
+	return nil
+}
+
+// AppendDeliveries inserts unordered, so that the deliveries appended already fail alone on the unique id
+func (r *MongoRepository) AppendDeliveries(ctx context.Context, deliveries ...webhook.Delivery) error {
+
+	if err := r.lazyBindCollections(); err != nil {
+		return err
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	documents := make([]any, len(deliveries))
+	for i, d := range deliveries {
+		documents[i] = d
+	}
+	_, err := r.deliveries.InsertMany(ctx, documents, mongo_opts.InsertMany().SetOrdered(false))
+	if err != nil && !onlyDuplicates(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *MongoRepository) FindDelivery(ctx context.Context, id string) (webhook.Delivery, error) {
+
+	rv := webhook.Delivery{}
+	if err := r.lazyBindCollections(); err != nil {
+		return rv, err
+	}
+
+	err := r.deliveries.FindOne(ctx, bson.D{{Key: "id", Value: id}}).Decode(&rv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return rv, webhook.ErrNotFound
+		}
+		return rv, err
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) FindDeliveries(ctx context.Context, subscriptionId string, limit int) ([]webhook.Delivery, error) {
+
+	if err := r.lazyBindCollections(); err != nil {
+		return nil, err
+	}
+
+	findOptions := mongo_opts.Find().SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "id", Value: -1}})
+	if limit > 0 {
+		findOptions.SetLimit(int64(limit))
+	}
+	cur, err := r.deliveries.Find(ctx, bson.D{{Key: "subscription_id", Value: subscriptionId}}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	rv := make([]webhook.Delivery, 0)
+	if err := cur.All(ctx, &rv); err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// ClaimDeliveries leases the due deliveries one at a time, each lease is an atomic update so that dispatchers
+// never claim the same delivery while the lease holds
+func (r *MongoRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Delivery, error) {
+
+	if err := r.lazyBindCollections(); err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{
+		{Key: "status", Value: webhook.DeliveryPending},
+		{Key: "next_attempt_at", Value: bson.D{{Key: "$lte", Value: now}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "next_attempt_at", Value: now.Add(lease)}}}}
+	findOptions := mongo_opts.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "created_at", Value: 1}})
+
+	rv := make([]webhook.Delivery, 0)
+	for len(rv) < limit {
+		d := webhook.Delivery{}
+		err := r.deliveries.FindOneAndUpdate(ctx, filter, update, findOptions).Decode(&d)
+		if err == mongo.ErrNoDocuments {
+			break
+		}
+		if err != nil {
+			return rv, err
+		}
+		rv = append(rv, d)
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) RecordAttempt(ctx context.Context, id string, attempt webhook.Attempt, status webhook.DeliveryStatus, nextAttemptAt time.Time) error {
+
+	if err := r.lazyBindCollections(); err != nil {
+		return err
+	}
+
+	set := bson.D{{Key: "status", Value: status}, {Key: "next_attempt_at", Value: nextAttemptAt}}
+	if status == webhook.DeliverySucceeded {
+		set = append(set, bson.E{Key: "delivered_at", Value: attempt.At})
+	}
+	update := bson.D{
+		{Key: "$push", Value: bson.D{{Key: "attempts", Value: attempt}}},
+		{Key: "$set", Value: set},
+	}
+	updateResult, err := r.deliveries.UpdateOne(ctx, bson.D{{Key: "id", Value: id}}, update)
+	if err != nil {
+		return err
+	}
+	if updateResult.MatchedCount == 0 {
+		return webhook.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *MongoRepository) Replay(ctx context.Context, id string, now time.Time) (webhook.Delivery, error) {
+
+	rv := webhook.Delivery{}
+	if err := r.lazyBindCollections(); err != nil {
+		return rv, err
+	}
+
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "status", Value: webhook.DeliveryPending}, {Key: "next_attempt_at", Value: now}, {Key: "replayed_at", Value: now}}}}
+	updateOptions := mongo_opts.FindOneAndUpdate().SetReturnDocument(mongo_opts.After)
+	err := r.deliveries.FindOneAndUpdate(ctx, bson.D{{Key: "id", Value: id}}, update, updateOptions).Decode(&rv)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return rv, webhook.ErrNotFound
+		}
+		return rv, err
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) find(ctx context.Context, filter bson.D) ([]webhook.Subscription, error) {
+
+	if err := r.lazyBindCollections(); err != nil {
+		return nil, err
+	}
+
+	findOptions := mongo_opts.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cur, err := r.subscriptions.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	rv := make([]webhook.Subscription, 0)
+	if err := cur.All(ctx, &rv); err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (r *MongoRepository) lazyBindCollections() error {
+	if r.subscriptions == nil {
+		database, err := db.MongoDatabase(r.Client, r.DbOptions)
+		if err != nil {
+			return err
+		}
+		r.subscriptions = database.Collection(MONGO_COLLECTION)
+		r.deliveries = database.Collection(MONGO_DELIVERIES_COLLECTION)
+	}
+	return nil
+}
+
+// onlyDuplicates tells a bulk write failing on duplicated keys alone
+func onlyDuplicates(err error) bool {
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return false
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if !mongo.IsDuplicateKeyError(writeErr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	impl "github.com/morphy76/g-fe-server/internal/webhook/repository/impl"
+	model "github.com/morphy76/g-fe-server/pkg/webhook"
+)
+
+const tracerName = "github.com/morphy76/g-fe-server/internal/webhook/repository"
+
+type tracedRepository struct {
+	delegate model.Repository
+	system   string
+}
+
+func newTracedRepository(delegate model.Repository, system string) model.Repository {
+	return &tracedRepository{
+		delegate: delegate,
+		system:   system,
+	}
+}
+
+func (r *tracedRepository) FindAll(ctx context.Context, tenant string) ([]model.Subscription, error) {
+	ctx, span := r.start(ctx, impl.MONGO_COLLECTION, "FindAll")
+	rv, err := r.delegate.FindAll(ctx, tenant)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) FindById(ctx context.Context, id string) (model.Subscription, error) {
+	ctx, span := r.start(ctx, impl.MONGO_COLLECTION, "FindById")
+	rv, err := r.delegate.FindById(ctx, id)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) FindByEventType(ctx context.Context, tenant string, eventType string) ([]model.Subscription, error) {
+	ctx, span := r.start(ctx, impl.MONGO_COLLECTION, "FindByEventType")
+	rv, err := r.delegate.FindByEventType(ctx, tenant, eventType)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) Save(ctx context.Context, s model.Subscription) error {
+	ctx, span := r.start(ctx, impl.MONGO_COLLECTION, "Save")
+	err := r.delegate.Save(ctx, s)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) Delete(ctx context.Context, id string) error {
+	ctx, span := r.start(ctx, impl.MONGO_COLLECTION, "Delete")
+	err := r.delegate.Delete(ctx, id)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) AppendDeliveries(ctx context.Context, deliveries ...model.Delivery) error {
+	ctx, span := r.start(ctx, impl.MONGO_DELIVERIES_COLLECTION, "AppendDeliveries")
+	span.SetAttributes(attribute.Int("db.operation.batch.size", len(deliveries)))
+	err := r.delegate.AppendDeliveries(ctx, deliveries...)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) FindDelivery(ctx context.Context, id string) (model.Delivery, error) {
+	ctx, span := r.start(ctx, impl.MONGO_DELIVERIES_COLLECTION, "FindDelivery")
+	rv, err := r.delegate.FindDelivery(ctx, id)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) FindDeliveries(ctx context.Context, subscriptionId string, limit int) ([]model.Delivery, error) {
+	ctx, span := r.start(ctx, impl.MONGO_DELIVERIES_COLLECTION, "FindDeliveries")
+	span.SetAttributes(attribute.Int("db.query.limit", limit))
+	rv, err := r.delegate.FindDeliveries(ctx, subscriptionId, limit)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]model.Delivery, error) {
+	ctx, span := r.start(ctx, impl.MONGO_DELIVERIES_COLLECTION, "ClaimDeliveries")
+	span.SetAttributes(attribute.Int("db.query.limit", limit))
+	rv, err := r.delegate.ClaimDeliveries(ctx, now, lease, limit)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) RecordAttempt(ctx context.Context, id string, attempt model.Attempt, status model.DeliveryStatus, nextAttemptAt time.Time) error {
+	ctx, span := r.start(ctx, impl.MONGO_DELIVERIES_COLLECTION, "RecordAttempt")
+	err := r.delegate.RecordAttempt(ctx, id, attempt, status, nextAttemptAt)
+	end(span, err)
+	return err
+}
+
+func (r *tracedRepository) Replay(ctx context.Context, id string, now time.Time) (model.Delivery, error) {
+	ctx, span := r.start(ctx, impl.MONGO_DELIVERIES_COLLECTION, "Replay")
+	rv, err := r.delegate.Replay(ctx, id, now)
+	end(span, err)
+	return rv, err
+}
+
+func (r *tracedRepository) start(ctx context.Context, collection string, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "webhook."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", r.system),
+			attribute.String("db.collection.name", collection),
+			attribute.String("db.operation.name", operation),
+		),
+	)
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !model.IsNotFound(err) && !model.IsAlreadyExists(err) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,28 @@
+package apikey
+
+import (
+	"context"
+	"time"
+)
+
+type ApiKey struct {
+	Id        string     `json:"id" bson:"id" db:"id"`
+	Name      string     `json:"name" bson:"name" db:"name"`
+	Tenant    string     `json:"tenant" bson:"tenant" db:"tenant"`
+	Scopes    []string   `json:"scopes" bson:"scopes" db:"scopes"`
+	Hash      string     `json:"-" bson:"hash" db:"hash"`
+	CreatedBy string     `json:"created_by" bson:"created_by" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at" db:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+func (k ApiKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+type Repository interface {
+	FindAll(ctx context.Context, tenant string) ([]ApiKey, error)
+	FindById(ctx context.Context, id string) (ApiKey, error)
+	Save(ctx context.Context, k ApiKey) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+}
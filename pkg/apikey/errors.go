@@ -0,0 +1,24 @@
+package apikey
+
+import "errors"
+
+var ErrNotFound = errors.New("not found")
+var ErrAlreadyExists = errors.New("already exists")
+var ErrUnknownRepositoryType = errors.New("unknown repository type")
+var ErrInvalidKey = errors.New("invalid api key")
+
+func IsNotFound(err error) bool {
+	return err == ErrNotFound
+}
+
+func IsAlreadyExists(err error) bool {
+	return err == ErrAlreadyExists
+}
+
+func IsUnknownRepositoryType(err error) bool {
+	return err == ErrUnknownRepositoryType
+}
+
+func IsInvalidKey(err error) bool {
+	return err == ErrInvalidKey
+}
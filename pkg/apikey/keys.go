@@ -0,0 +1,45 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const keySeparator = "."
+
+func GenerateKey() (id string, secret string, key string, err error) {
+	idBytes := make([]byte, 12)
+	if _, err = rand.Read(idBytes); err != nil {
+		return
+	}
+	secretBytes := make([]byte, 32)
+	if _, err = rand.Read(secretBytes); err != nil {
+		return
+	}
+
+	id = hex.EncodeToString(idBytes)
+	secret = base64.RawURLEncoding.EncodeToString(secretBytes)
+	key = id + keySeparator + secret
+	return
+}
+
+func ParseKey(key string) (id string, secret string, err error) {
+	id, secret, found := strings.Cut(key, keySeparator)
+	if !found || id == "" || secret == "" {
+		return "", "", ErrInvalidKey
+	}
+	return id, secret, nil
+}
+
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func (k ApiKey) Matches(secret string) bool {
+	return subtle.ConstantTimeCompare([]byte(k.Hash), []byte(HashSecret(secret))) == 1
+}
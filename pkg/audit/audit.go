@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"context"
+	"time"
+)
+
+type Operation string
+
+const (
+	OpCreate  Operation = "create"
+	OpUpdate  Operation = "update"
+	OpPatch   Operation = "patch"
+	OpDelete  Operation = "delete"
+	OpRestore Operation = "restore"
+)
+
+// Entry records a change of an entity, entries are never updated nor removed
+type Entry struct {
+	Id        string    `json:"id" bson:"id" db:"id"`
+	At        time.Time `json:"at" bson:"at" db:"at"`
+	Actor     string    `json:"actor" bson:"actor" db:"actor"`
+	ActorKind string    `json:"actor_kind" bson:"actor_kind" db:"actor_kind"`
+	Tenant    string    `json:"tenant" bson:"tenant" db:"tenant"`
+	Entity    string    `json:"entity" bson:"entity" db:"entity"`
+	EntityId  string    `json:"entity_id" bson:"entity_id" db:"entity_id"`
+	Operation Operation `json:"operation" bson:"operation" db:"operation"`
+	Changes   []Change  `json:"changes" bson:"changes" db:"changes"`
+	TraceId   string    `json:"trace_id,omitempty" bson:"trace_id,omitempty" db:"trace_id"`
+}
+
+// Query selects the entries of a tenant, newest first; empty members match everything
+type Query struct {
+	Tenant    string
+	Entity    string
+	EntityId  string
+	Actor     string
+	Operation Operation
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+func (q Query) EffectiveLimit() int {
+	if q.Limit <= 0 {
+		return DefaultLimit
+	}
+	return min(q.Limit, MaxLimit)
+}
+
+func (q Query) Matches(e Entry) bool {
+	switch {
+	case e.Tenant != q.Tenant,
+		q.Entity != "" && e.Entity != q.Entity,
+		q.EntityId != "" && e.EntityId != q.EntityId,
+		q.Actor != "" && e.Actor != q.Actor,
+		q.Operation != "" && e.Operation != q.Operation,
+		!q.Since.IsZero() && e.At.Before(q.Since),
+		!q.Until.IsZero() && !e.At.Before(q.Until):
+		return false
+	default:
+		return true
+	}
+}
+
+// Repository is append-only; Watch streams the entries appended from now on which match the query,
+// the channel is closed when the context is done or when the watcher can not keep up
+type Repository interface {
+	Append(ctx context.Context, e Entry) error
+	Find(ctx context.Context, query Query) ([]Entry, error)
+	Watch(ctx context.Context, query Query) (<-chan Entry, error)
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// Change is the before and after value of a JSON member, a nil side means the member is absent
+type Change struct {
+	Field  string `json:"field" bson:"field"`
+	Before any    `json:"before" bson:"before"`
+	After  any    `json:"after" bson:"after"`
+}
+
+// Diff compares the JSON representations of two states of an entity, nil stands for a missing state
+func Diff(before any, after any) ([]Change, error) {
+
+	beforeMembers, err := members(before)
+	if err != nil {
+		return nil, err
+	}
+	afterMembers, err := members(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(beforeMembers)+len(afterMembers))
+	for field := range beforeMembers {
+		fields = append(fields, field)
+	}
+	for field := range afterMembers {
+		if _, found := beforeMembers[field]; !found {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	rv := make([]Change, 0, len(fields))
+	for _, field := range fields {
+		if !reflect.DeepEqual(beforeMembers[field], afterMembers[field]) {
+			rv = append(rv, Change{
+				Field:  field,
+				Before: beforeMembers[field],
+				After:  afterMembers[field],
+			})
+		}
+	}
+	return rv, nil
+}
+
+func members(state any) (map[string]any, error) {
+	rv := make(map[string]any)
+	if state == nil || reflect.ValueOf(state).Kind() == reflect.Pointer && reflect.ValueOf(state).IsNil() {
+		return rv, nil
+	}
+
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(raw, &rv)
+	return rv, err
+}
@@ -0,0 +1,14 @@
+package audit
+
+import "errors"
+
+var ErrUnknownRepositoryType = errors.New("unknown repository type")
+var ErrInvalidQuery = errors.New("invalid query")
+
+func IsUnknownRepositoryType(err error) bool {
+	return err == ErrUnknownRepositoryType
+}
+
+func IsInvalidQuery(err error) bool {
+	return err == ErrInvalidQuery
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"context"
+	"strconv"
+)
+
+// Kind describes a struct type with an id field to the generic repositories and handlers
+type Kind[T any, ID comparable] struct {
+	// Name is the singular name of the entities, it names the routes, the logger and the spans
+	Name       string
+	Collection string
+	// IdField and VersionField are the stored names of the id and version fields
+	IdField      string
+	VersionField string
+	Id           func(T) ID
+	SetId        func(T, ID) T
+	// ParseId reads the id from a path parameter, it fails with ErrInvalidId
+	ParseId func(string) (ID, error)
+	// Version and SetVersion enable optimistic concurrency, kinds without a version leave them nil
+	Version    func(T) int64
+	SetVersion func(T, int64) T
+}
+
+func (k Kind[T, ID]) Versioned() bool {
+	return k.Version != nil && k.SetVersion != nil
+}
+
+func (k Kind[T, ID]) VersionOf(e T) int64 {
+	if !k.Versioned() {
+		return 0
+	}
+	return k.Version(e)
+}
+
+func (k Kind[T, ID]) WithVersion(e T, version int64) T {
+	if !k.Versioned() {
+		return e
+	}
+	return k.SetVersion(e, version)
+}
+
+// Conflicts tells whether a write expecting the given version must be refused, 0 expects any version
+func (k Kind[T, ID]) Conflicts(current T, expected int64) bool {
+	return k.Versioned() && expected != 0 && expected != k.Version(current)
+}
+
+func StringId(value string) (string, error) {
+	if value == "" {
+		return "", ErrInvalidId
+	}
+	return value, nil
+}
+
+func Int64Id(value string) (int64, error) {
+	rv, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidId
+	}
+	return rv, nil
+}
+
+// Repository stores the entities of a kind; for versioned kinds Save starts at version 1,
+// Update and Delete fail with ErrVersionConflict unless the given version is current or 0
+type Repository[T any, ID comparable] interface {
+	FindAll(ctx context.Context) ([]T, error)
+	FindById(ctx context.Context, id ID) (T, error)
+	Save(ctx context.Context, e T) error
+	Update(ctx context.Context, e T) (T, error)
+	Delete(ctx context.Context, id ID, version int64) error
+}
@@ -0,0 +1,29 @@
+package entity
+
+import "errors"
+
+var ErrNotFound = errors.New("not found")
+var ErrAlreadyExists = errors.New("already exists")
+var ErrVersionConflict = errors.New("version conflict")
+var ErrUnknownRepositoryType = errors.New("unknown repository type")
+var ErrInvalidId = errors.New("invalid id")
+
+func IsNotFound(err error) bool {
+	return err == ErrNotFound
+}
+
+func IsAlreadyExists(err error) bool {
+	return err == ErrAlreadyExists
+}
+
+func IsVersionConflict(err error) bool {
+	return err == ErrVersionConflict
+}
+
+func IsUnknownRepositoryType(err error) bool {
+	return err == ErrUnknownRepositoryType
+}
+
+func IsInvalidId(err error) bool {
+	return err == ErrInvalidId
+}
@@ -0,0 +1,58 @@
+package example
+
+// MaxBulkSize bounds the items of a single bulk operation
+const MaxBulkSize = 1000
+
+type BulkStatus string
+
+const (
+	BulkCreated  BulkStatus = "created"
+	BulkUpdated  BulkStatus = "updated"
+	BulkDeleted  BulkStatus = "deleted"
+	BulkConflict BulkStatus = "conflict"
+	BulkNotFound BulkStatus = "not_found"
+	BulkInvalid  BulkStatus = "invalid"
+	BulkSkipped  BulkStatus = "skipped"
+)
+
+// BulkResult is the outcome of the item at Index of a bulk operation, Version is the stored version on success
+type BulkResult struct {
+	Index   int        `json:"index"`
+	Name    string     `json:"name,omitempty"`
+	Status  BulkStatus `json:"status"`
+	Version int64      `json:"version,omitempty"`
+}
+
+func (r BulkResult) Succeeded() bool {
+	switch r.Status {
+	case BulkCreated, BulkUpdated, BulkDeleted:
+		return true
+	default:
+		return false
+	}
+}
+
+// NewBulkResults prepares one result per item, every item is skipped until its outcome is known
+func NewBulkResults(items []Example) []BulkResult {
+	rv := make([]BulkResult, len(items))
+	for i, e := range items {
+		rv[i] = BulkResult{
+			Index:  i,
+			Name:   e.Name,
+			Status: BulkSkipped,
+		}
+	}
+	return rv
+}
+
+// BulkStatusOf maps the error of a single item write to the status of the item, ok is false for storage failures
+func BulkStatusOf(err error) (status BulkStatus, ok bool) {
+	switch err {
+	case ErrAlreadyExists, ErrVersionConflict:
+		return BulkConflict, true
+	case ErrNotFound:
+		return BulkNotFound, true
+	default:
+		return "", false
+	}
+}
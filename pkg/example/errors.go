@@ -1,10 +1,21 @@
 package example
 
-import "errors"
+import (
+	"errors"
 
-var ErrNotFound = errors.New("not found")
-var ErrAlreadyExists = errors.New("already exists")
-var ErrUnknownRepositoryType = errors.New("unknown repository type")
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
+// the storage errors are the generic ones, so that the generic handlers map them as well
+var ErrNotFound = entity.ErrNotFound
+var ErrAlreadyExists = entity.ErrAlreadyExists
+var ErrUnknownRepositoryType = entity.ErrUnknownRepositoryType
+var ErrInvalidQuery = errors.New("invalid query")
+var ErrVersionConflict = entity.ErrVersionConflict
+var ErrMalformedPatch = errors.New("malformed patch document")
+var ErrUnsupportedPatch = errors.New("unsupported patch document")
+var ErrInvalidPatch = errors.New("invalid patch")
+var ErrPatchTestFailed = errors.New("patch test failed")
 
 func IsNotFound(err error) bool {
 	return err == ErrNotFound
@@ -17,3 +28,27 @@ func IsAlreadyExists(err error) bool {
 func IsUnknownRepositoryType(err error) bool {
 	return err == ErrUnknownRepositoryType
 }
+
+func IsInvalidQuery(err error) bool {
+	return err == ErrInvalidQuery
+}
+
+func IsVersionConflict(err error) bool {
+	return err == ErrVersionConflict
+}
+
+func IsMalformedPatch(err error) bool {
+	return err == ErrMalformedPatch
+}
+
+func IsUnsupportedPatch(err error) bool {
+	return err == ErrUnsupportedPatch
+}
+
+func IsInvalidPatch(err error) bool {
+	return err == ErrInvalidPatch
+}
+
+func IsPatchTestFailed(err error) bool {
+	return err == ErrPatchTestFailed
+}
@@ -1,14 +1,55 @@
 package example
 
+import (
+	"context"
+	"time"
+
+	"github.com/morphy76/g-fe-server/pkg/entity"
+)
+
 type Example struct {
-	Name string `json:"name" db:"name" validate:"required"`
-	Age  int    `json:"age" db:"age" validate:"required"`
+	Name    string `json:"name" db:"name" validate:"required,max=64,pattern=^[A-Za-z0-9.-][A-Za-z0-9_.-]*$"`
+	Age     int    `json:"age" db:"age" validate:"required,min=1,max=200"`
+	Version int64  `json:"version" db:"version"`
+	// DeletedAt marks soft deleted examples, it is managed by the repository only
+	DeletedAt *time.Time `json:"deleted_at,omitempty" bson:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+func (e Example) IsDeleted() bool {
+	return e.DeletedAt != nil
+}
+
+// Kind describes examples to the generic handlers, examples are named after their id
+var Kind = entity.Kind[Example, string]{
+	Name:         "example",
+	Collection:   "examples",
+	IdField:      "name",
+	VersionField: "version",
+	Id:           func(e Example) string { return e.Name },
+	SetId: func(e Example, id string) Example {
+		e.Name = id
+		return e
+	},
+	ParseId: entity.StringId,
+	Version: func(e Example) int64 { return e.Version },
+	SetVersion: func(e Example, version int64) Example {
+		e.Version = version
+		return e
+	},
 }
 
+// Repository stores examples with optimistic concurrency: Save starts at version 1,
+// Update and Delete fail with ErrVersionConflict unless the given version is current or 0.
+// Delete is soft, deleted examples are only visible to Find with Query.Deleted and to Restore;
+// their names stay taken until they are restored.
 type Repository interface {
-	FindAll() ([]Example, error)
-	FindById(id string) (Example, error)
-	Save(e Example) error
-	Update(e Example) error
-	Delete(id string) error
+	entity.Repository[Example, string]
+	Find(ctx context.Context, query Query) (Page, error)
+	Restore(ctx context.Context, id string, version int64) (Example, error)
+	Patch(ctx context.Context, id string, patch Patch, version int64) (Example, error)
+	// SaveMany, UpdateMany and DeleteMany report the outcome of each item in input order; ordered
+	// operations stop at the first item which fails, the following ones are reported as skipped
+	SaveMany(ctx context.Context, items []Example, ordered bool) ([]BulkResult, error)
+	UpdateMany(ctx context.Context, items []Example, ordered bool) ([]BulkResult, error)
+	DeleteMany(ctx context.Context, items []Example, ordered bool) ([]BulkResult, error)
 }
@@ -0,0 +1,238 @@
+package example
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	MergePatchMediaType = "application/merge-patch+json"
+	JSONPatchMediaType  = "application/json-patch+json"
+)
+
+type PatchKind int8
+
+const (
+	MergePatch PatchKind = iota
+	JSONPatch
+)
+
+// Validator checks the given fields of the example, all of them when none is given
+type Validator func(e Example, fields ...string) error
+
+type Patch struct {
+	Kind      PatchKind
+	Document  []byte
+	Validator Validator
+	ops       jsonpatch.Patch
+	merge     map[string]json.RawMessage
+}
+
+func NewPatch(mediaType string, document []byte) (Patch, error) {
+
+	rv := Patch{Document: document}
+
+	switch mediaType {
+	case MergePatchMediaType:
+		rv.Kind = MergePatch
+		// only objects make sense as a merge patch of a resource
+		if err := json.Unmarshal(document, &rv.merge); err != nil || rv.merge == nil {
+			return rv, ErrMalformedPatch
+		}
+	case JSONPatchMediaType:
+		rv.Kind = JSONPatch
+		ops, err := jsonpatch.DecodePatch(document)
+		if err != nil {
+			return rv, ErrMalformedPatch
+		}
+		for _, op := range ops {
+			if _, err := op.Path(); err != nil {
+				return rv, ErrMalformedPatch
+			}
+		}
+		rv.ops = ops
+	default:
+		return rv, ErrUnsupportedPatch
+	}
+
+	return rv, nil
+}
+
+// Apply patches the example as a JSON document; the identity can not change, the version and the deletion mark are kept as is
+func (p Patch) Apply(e Example) (Example, error) {
+
+	current, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+
+	var patched []byte
+	switch p.Kind {
+	case MergePatch:
+		patched, err = jsonpatch.MergePatch(current, p.Document)
+	default:
+		patched, err = p.ops.Apply(current)
+	}
+	if err != nil {
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return e, ErrPatchTestFailed
+		}
+		return e, ErrInvalidPatch
+	}
+
+	rv := Example{}
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rv); err != nil || rv.Name != e.Name {
+		return e, ErrInvalidPatch
+	}
+	rv.Version = e.Version
+	rv.DeletedAt = e.DeletedAt
+
+	if p.Validator != nil {
+		if err := p.Validator(rv); err != nil {
+			return e, err
+		}
+	}
+
+	return rv, nil
+}
+
+// Assignments reduces the patch to field assignments guarded by equality tests, so that storages can apply it
+// in place; ok is false when the patch needs the current document, e.g. move and copy operations
+func (p Patch) Assignments(id string) (set map[string]any, tests map[string]any, ok bool, err error) {
+
+	set = make(map[string]any)
+	tests = make(map[string]any)
+
+	if p.Kind == MergePatch {
+		for field, raw := range p.merge {
+			// removing an unknown member is a no-op
+			if _, known := patchableFields[field]; !known && string(raw) == "null" {
+				continue
+			}
+			value, err := patchValue(id, field, raw)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if value != nil {
+				set[field] = value
+			}
+		}
+		return set, tests, true, p.validateAssignments(id, set)
+	}
+
+	for _, op := range p.ops {
+		path, _ := op.Path()
+		field := strings.TrimPrefix(path, "/")
+		if _, known := patchableFields[field]; !known || strings.Contains(field, "/") {
+			return nil, nil, false, nil
+		}
+
+		var raw json.RawMessage
+		if value, found := op["value"]; found && value != nil {
+			raw = *value
+		}
+
+		switch op.Kind() {
+		case "test":
+			// a test following an assignment tests the patched value
+			if _, assigned := set[field]; assigned {
+				return nil, nil, false, nil
+			}
+			value, err := testValue(field, raw)
+			if err != nil {
+				return nil, nil, false, nil
+			}
+			tests[field] = value
+		case "add", "replace", "remove":
+			if op.Kind() == "remove" {
+				raw = nil
+			}
+			value, err := patchValue(id, field, raw)
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if value != nil {
+				set[field] = value
+			}
+		default:
+			return nil, nil, false, nil
+		}
+	}
+
+	return set, tests, true, p.validateAssignments(id, set)
+}
+
+func (p Patch) validateAssignments(id string, set map[string]any) error {
+	if p.Validator == nil || len(set) == 0 {
+		return nil
+	}
+
+	e := Example{Name: id}
+	fields := make([]string, 0, len(set))
+	for field, value := range set {
+		e.SetFieldValue(field, value)
+		fields = append(fields, field)
+	}
+	return p.Validator(e, fields...)
+}
+
+var patchableFields = map[string]struct{}{
+	"name":    {},
+	"age":     {},
+	"version": {},
+}
+
+// patchValue returns the value to assign, nil when the field is left untouched; null resets to the zero value
+func patchValue(id string, field string, raw json.RawMessage) (any, error) {
+
+	isNull := len(raw) == 0 || string(raw) == "null"
+
+	switch field {
+	case "version":
+		return nil, nil
+	case "name":
+		var name string
+		if isNull || json.Unmarshal(raw, &name) != nil || name != id {
+			return nil, ErrInvalidPatch
+		}
+		return nil, nil
+	case "age":
+		if isNull {
+			return 0, nil
+		}
+		var age int
+		if err := json.Unmarshal(raw, &age); err != nil {
+			return nil, ErrInvalidPatch
+		}
+		return age, nil
+	default:
+		return nil, ErrInvalidPatch
+	}
+}
+
+func testValue(field string, raw json.RawMessage) (any, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, ErrInvalidPatch
+	}
+
+	switch field {
+	case "name":
+		var rv string
+		err := json.Unmarshal(raw, &rv)
+		return rv, err
+	case "age":
+		var rv int
+		err := json.Unmarshal(raw, &rv)
+		return rv, err
+	default:
+		var rv int64
+		err := json.Unmarshal(raw, &rv)
+		return rv, err
+	}
+}
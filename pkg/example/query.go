@@ -0,0 +1,263 @@
+package example
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+type Operator string
+
+const (
+	OpEq     Operator = "="
+	OpNe     Operator = "!="
+	OpGt     Operator = ">"
+	OpGte    Operator = ">="
+	OpLt     Operator = "<"
+	OpLte    Operator = "<="
+	OpPrefix Operator = "^="
+)
+
+// longest operators first, so that ">=" is not matched as ">"
+var operators = []Operator{OpPrefix, OpNe, OpGte, OpLte, OpEq, OpGt, OpLt}
+
+type FieldKind int8
+
+const (
+	StringField FieldKind = iota
+	IntField
+)
+
+var Fields = map[string]FieldKind{
+	"name": StringField,
+	"age":  IntField,
+}
+
+type Filter struct {
+	Field string
+	Op    Operator
+	Value any
+}
+
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+type Query struct {
+	Limit   int
+	Offset  int
+	Cursor  string
+	Sort    []SortField
+	Filters []Filter
+	// Deleted selects the soft deleted examples instead of the live ones
+	Deleted bool
+}
+
+type Page struct {
+	Items      []Example
+	Total      int64
+	NextCursor string
+}
+
+func ParseFilter(expr string) (Filter, error) {
+
+	at := strings.IndexAny(expr, "!<>=^")
+	if at <= 0 {
+		return Filter{}, ErrInvalidQuery
+	}
+
+	var op Operator
+	for _, candidate := range operators {
+		if strings.HasPrefix(expr[at:], string(candidate)) {
+			op = candidate
+			break
+		}
+	}
+	if op == "" {
+		return Filter{}, ErrInvalidQuery
+	}
+
+	field, value := expr[:at], expr[at+len(op):]
+	kind, known := Fields[field]
+	if !known {
+		return Filter{}, ErrInvalidQuery
+	}
+
+	switch kind {
+	case IntField:
+		if op == OpPrefix {
+			return Filter{}, ErrInvalidQuery
+		}
+		intValue, err := strconv.Atoi(value)
+		if err != nil {
+			return Filter{}, ErrInvalidQuery
+		}
+		return Filter{Field: field, Op: op, Value: intValue}, nil
+	default:
+		return Filter{Field: field, Op: op, Value: value}, nil
+	}
+}
+
+func ParseSort(expr string) ([]SortField, error) {
+
+	rv := make([]SortField, 0)
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		desc := strings.HasPrefix(part, "-")
+		field := strings.TrimPrefix(strings.TrimPrefix(part, "-"), "+")
+		if _, known := Fields[field]; !known {
+			return nil, ErrInvalidQuery
+		}
+		rv = append(rv, SortField{Field: field, Desc: desc})
+	}
+
+	return rv, nil
+}
+
+func (q Query) EffectiveLimit() int {
+	if q.Limit <= 0 {
+		return DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		return MaxLimit
+	}
+	return q.Limit
+}
+
+// EffectiveSort appends the unique name as tiebreaker so that cursors are stable
+func (q Query) EffectiveSort() []SortField {
+	rv := make([]SortField, 0, len(q.Sort)+1)
+	for _, s := range q.Sort {
+		rv = append(rv, s)
+		if s.Field == "name" {
+			return rv
+		}
+	}
+	return append(rv, SortField{Field: "name"})
+}
+
+func (e Example) FieldValue(field string) any {
+	switch field {
+	case "name":
+		return e.Name
+	case "age":
+		return e.Age
+	default:
+		return nil
+	}
+}
+
+func (e *Example) SetFieldValue(field string, value any) {
+	switch field {
+	case "name":
+		e.Name = value.(string)
+	case "age":
+		e.Age = value.(int)
+	}
+}
+
+func (f Filter) Matches(e Example) bool {
+
+	actual := e.FieldValue(f.Field)
+	if f.Op == OpPrefix {
+		return strings.HasPrefix(actual.(string), f.Value.(string))
+	}
+
+	cmp := Compare(actual, f.Value)
+	switch f.Op {
+	case OpEq:
+		return cmp == 0
+	case OpNe:
+		return cmp != 0
+	case OpGt:
+		return cmp > 0
+	case OpGte:
+		return cmp >= 0
+	case OpLt:
+		return cmp < 0
+	case OpLte:
+		return cmp <= 0
+	default:
+		return false
+	}
+}
+
+func Compare(a any, b any) int {
+	switch av := a.(type) {
+	case int:
+		bv := b.(int)
+		if av < bv {
+			return -1
+		} else if av > bv {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(av, b.(string))
+	default:
+		return 0
+	}
+}
+
+func EncodeCursor(e Example, sort []SortField) string {
+	values := make([]any, len(sort))
+	for i, s := range sort {
+		values[i] = e.FieldValue(s.Field)
+	}
+	raw, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(cursor string, sort []SortField) ([]any, error) {
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidQuery
+	}
+
+	var values []any
+	if err := json.Unmarshal(raw, &values); err != nil || len(values) != len(sort) {
+		return nil, ErrInvalidQuery
+	}
+
+	for i, s := range sort {
+		switch Fields[s.Field] {
+		case IntField:
+			number, ok := values[i].(float64)
+			if !ok {
+				return nil, ErrInvalidQuery
+			}
+			values[i] = int(number)
+		default:
+			if _, ok := values[i].(string); !ok {
+				return nil, ErrInvalidQuery
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// AfterCursor tells whether e comes strictly after the cursor position in the given sort order
+func AfterCursor(e Example, sort []SortField, cursor []any) bool {
+	for i, s := range sort {
+		cmp := Compare(e.FieldValue(s.Field), cursor[i])
+		if s.Desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp > 0
+		}
+	}
+	return false
+}
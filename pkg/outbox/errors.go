@@ -0,0 +1,19 @@
+package outbox
+
+import "errors"
+
+var ErrUnknownRepositoryType = errors.New("unknown repository type")
+var ErrUnknownPublisher = errors.New("unknown outbox publisher")
+var ErrMissingBroker = errors.New("kafka outbox publisher requires the kafka brokers")
+
+func IsUnknownRepositoryType(err error) bool {
+	return err == ErrUnknownRepositoryType
+}
+
+func IsUnknownPublisher(err error) bool {
+	return err == ErrUnknownPublisher
+}
+
+func IsMissingBroker(err error) bool {
+	return err == ErrMissingBroker
+}
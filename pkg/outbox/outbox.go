@@ -0,0 +1,52 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Message is what downstream consumers receive of an event
+type Message struct {
+	Id       string `json:"id" bson:"id"`
+	Type     string `json:"type" bson:"type"`
+	Entity   string `json:"entity" bson:"entity"`
+	EntityId string `json:"entity_id" bson:"entity_id"`
+	Tenant   string `json:"tenant" bson:"tenant"`
+	// Version is the version of the entity after the change, 0 when it is not known
+	Version int64 `json:"version,omitempty" bson:"version,omitempty"`
+	// Payload is the JSON document of the entity after the change, empty for deletions
+	Payload json.RawMessage `json:"payload,omitempty" bson:"payload,omitempty"`
+	// TraceContext carries the trace of the change, e.g. traceparent, to the consumers
+	TraceContext map[string]string `json:"trace_context,omitempty" bson:"trace_context,omitempty"`
+	OccurredAt   time.Time         `json:"occurred_at" bson:"occurred_at"`
+}
+
+// Event is a message waiting in the outbox, it is appended together with the change it describes, delivered at
+// least once and kept as sent afterwards
+type Event struct {
+	Message       `bson:",inline"`
+	Attempts      int        `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" bson:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty" bson:"last_error,omitempty"`
+}
+
+// Repository stores the events; Claim leases up to limit events due at now, so that concurrent relays do not
+// deliver them at the same time, a lease expires when the relay does not settle the event
+type Repository interface {
+	Append(ctx context.Context, events ...Event) error
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Event, error)
+	MarkSent(ctx context.Context, id string, at time.Time) error
+	MarkFailed(ctx context.Context, id string, nextAttemptAt time.Time, reason string) error
+}
+
+// Transactor runs fn so that the writes done with the context it is given commit or abort together
+type Transactor func(ctx context.Context, fn func(ctx context.Context) error) error
+
+// Publisher delivers an event downstream, an error makes the relay retry it later
+type Publisher interface {
+	Name() string
+	Publish(ctx context.Context, e Event) error
+	Close() error
+}
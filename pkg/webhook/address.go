@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// reservedNetworks are not covered by the predicates of net.IP but are not reachable on the internet either
+var reservedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+}
+
+// IsPublicAddress tells whether webhooks may be posted to the address: loopback, private (RFC 1918 and unique
+// local), link-local (among them the cloud metadata endpoint 169.254.169.254), unspecified, multicast and
+// reserved addresses are refused
+func IsPublicAddress(ip net.IP) bool {
+	if ip == nil ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func ValidateUrl(rawUrl string) error {
+	useUrl, err := url.Parse(rawUrl)
+	if err != nil || useUrl.Host == "" {
+		return ErrInvalidUrl
+	}
+	if scheme := strings.ToLower(useUrl.Scheme); scheme != "http" && scheme != "https" {
+		return ErrInvalidUrl
+	}
+	// host names are checked by the dispatcher once resolved, literal addresses are refused straight away
+	host := strings.ToLower(useUrl.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrPrivateAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && !IsPublicAddress(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, rv, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return rv
+}
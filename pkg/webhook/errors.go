@@ -0,0 +1,29 @@
+package webhook
+
+import "errors"
+
+var ErrNotFound = errors.New("not found")
+var ErrAlreadyExists = errors.New("already exists")
+var ErrUnknownRepositoryType = errors.New("unknown repository type")
+var ErrInvalidUrl = errors.New("webhook url must be an absolute http or https url")
+var ErrPrivateAddress = errors.New("webhook url must not target a loopback, private or link-local address")
+
+func IsNotFound(err error) bool {
+	return err == ErrNotFound
+}
+
+func IsAlreadyExists(err error) bool {
+	return err == ErrAlreadyExists
+}
+
+func IsUnknownRepositoryType(err error) bool {
+	return err == ErrUnknownRepositoryType
+}
+
+func IsInvalidUrl(err error) bool {
+	return err == ErrInvalidUrl
+}
+
+func IsPrivateAddress(err error) bool {
+	return err == ErrPrivateAddress
+}
@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+)
+
+// the headers of the posts of a delivery
+const (
+	HEADER_SIGNATURE = "X-Webhook-Signature"
+	HEADER_TIMESTAMP = "X-Webhook-Timestamp"
+	HEADER_DELIVERY  = "X-Webhook-Delivery"
+	HEADER_EVENT     = "X-Webhook-Event"
+)
+
+const signaturePrefix = "sha256="
+
+func GenerateId() (string, error) {
+	idBytes := make([]byte, 12)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(idBytes), nil
+}
+
+func GenerateSecret() (string, error) {
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secretBytes), nil
+}
+
+// DeliveryId is the same for every fan out of an event to a subscription
+func DeliveryId(subscriptionId string, eventId string) string {
+	sum := sha256.Sum256([]byte(subscriptionId + ":" + eventId))
+	return hex.EncodeToString(sum[:12])
+}
+
+// Sign is the HMAC-SHA256 of "<timestamp>.<body>" keyed by the secret, as sent in HEADER_SIGNATURE; the timestamp
+// is in unix seconds and lets receivers reject replayed posts
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify tells whether the signature is the one of the body, for receivers written in Go
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+// EVENT_TYPE_ANY subscribes a webhook to every event type
+const EVENT_TYPE_ANY = "*"
+
+const (
+	DefaultDeliveriesLimit = 50
+	MaxDeliveriesLimit     = 500
+)
+
+// Subscription is a webhook URL of a tenant and the event types it receives; the secret signs the deliveries,
+// it is returned on registration only
+type Subscription struct {
+	Id         string    `json:"id" bson:"id"`
+	Tenant     string    `json:"tenant" bson:"tenant"`
+	Url        string    `json:"url" bson:"url"`
+	EventTypes []string  `json:"event_types" bson:"event_types"`
+	Secret     string    `json:"secret,omitempty" bson:"secret"`
+	CreatedBy  string    `json:"created_by" bson:"created_by"`
+	CreatedAt  time.Time `json:"created_at" bson:"created_at"`
+}
+
+func (s Subscription) Accepts(eventType string) bool {
+	return slices.Contains(s.EventTypes, EVENT_TYPE_ANY) || slices.Contains(s.EventTypes, eventType)
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Attempt is a post of a delivery, ResponseCode is 0 when the webhook did not answer
+type Attempt struct {
+	At           time.Time `json:"at" bson:"at"`
+	ResponseCode int       `json:"response_code" bson:"response_code"`
+	Error        string    `json:"error,omitempty" bson:"error,omitempty"`
+	DurationMs   int64     `json:"duration_ms" bson:"duration_ms"`
+}
+
+// Delivery is an event to post to a webhook and the log of its attempts; a pending delivery is due at
+// NextAttemptAt, a failed one gave up and waits for a replay
+type Delivery struct {
+	Id             string          `json:"id" bson:"id"`
+	SubscriptionId string          `json:"subscription_id" bson:"subscription_id"`
+	Tenant         string          `json:"tenant" bson:"tenant"`
+	EventId        string          `json:"event_id" bson:"event_id"`
+	EventType      string          `json:"event_type" bson:"event_type"`
+	Payload        json.RawMessage `json:"payload" bson:"payload"`
+	// TraceContext carries the trace of the change to the posts, e.g. traceparent
+	TraceContext  map[string]string `json:"trace_context,omitempty" bson:"trace_context,omitempty"`
+	Status        DeliveryStatus    `json:"status" bson:"status"`
+	Attempts      []Attempt         `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time         `json:"next_attempt_at" bson:"next_attempt_at"`
+	CreatedAt     time.Time         `json:"created_at" bson:"created_at"`
+	DeliveredAt   *time.Time        `json:"delivered_at,omitempty" bson:"delivered_at,omitempty"`
+	// ReplayedAt is the time of the last replay, the attempts before it do not count towards the maximum
+	ReplayedAt *time.Time `json:"replayed_at,omitempty" bson:"replayed_at,omitempty"`
+}
+
+// AttemptsSinceReplay counts the attempts of the delivery after its last replay
+func (d Delivery) AttemptsSinceReplay() int {
+	if d.ReplayedAt == nil {
+		return len(d.Attempts)
+	}
+	rv := 0
+	for _, attempt := range d.Attempts {
+		if !attempt.At.Before(*d.ReplayedAt) {
+			rv++
+		}
+	}
+	return rv
+}
+
+// Repository stores the subscriptions and their deliveries; Claim leases up to limit pending deliveries due at
+// now, so that concurrent dispatchers do not post them at the same time
+type Repository interface {
+	FindAll(ctx context.Context, tenant string) ([]Subscription, error)
+	FindById(ctx context.Context, id string) (Subscription, error)
+	// FindByEventType lists the subscriptions of the tenant accepting the event type
+	FindByEventType(ctx context.Context, tenant string, eventType string) ([]Subscription, error)
+	Save(ctx context.Context, s Subscription) error
+	Delete(ctx context.Context, id string) error
+
+	// AppendDeliveries ignores the deliveries appended already, so that an event fanned out twice is posted once
+	AppendDeliveries(ctx context.Context, deliveries ...Delivery) error
+	FindDelivery(ctx context.Context, id string) (Delivery, error)
+	// FindDeliveries lists the latest deliveries of a subscription, newest first
+	FindDeliveries(ctx context.Context, subscriptionId string, limit int) ([]Delivery, error)
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error)
+	// RecordAttempt logs an attempt and moves the delivery to the status, nextAttemptAt matters to pending ones
+	RecordAttempt(ctx context.Context, id string, attempt Attempt, status DeliveryStatus, nextAttemptAt time.Time) error
+	// Replay makes a delivery pending and due at now, keeping the log of its attempts
+	Replay(ctx context.Context, id string, now time.Time) (Delivery, error)
+}
@@ -1 +0,0 @@
-db.createCollection('examples', {});
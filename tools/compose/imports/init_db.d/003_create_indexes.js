@@ -1 +0,0 @@
-db.examples.createIndex({ name: 1 }, { unique: true });
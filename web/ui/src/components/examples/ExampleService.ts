@@ -5,6 +5,7 @@ import { loggerFor } from '../../features/react-logger';
 export type Example = {
   name: string;
   age: number;
+  version?: number;
 };
 
 export const listExamples = async (): Promise<Example[]> => {
@@ -50,7 +51,8 @@ export const useCreateExample = () => {
 
 export const replaceExample = async (name: string, example: Example): Promise<void> => {
   loggerFor('ExampleService').debug('replaceExample', name, example);
-  await ps_client.put(`/example/${name}`, example);
+  const headers = example.version ? { 'If-Match': `"${example.version}"` } : undefined;
+  await ps_client.put(`/example/${name}`, example, { headers });
 };
 
 export const useReplaceExample = (name: string) => {
@@ -9,6 +9,7 @@ export const ps_client = axios.create({
   baseURL: localDev ? 'http://localhost:3010' : `${contextRoot}/api`,
   headers: {
     'Content-Type': 'application/json',
+    'X-Requested-With': 'XMLHttpRequest',
     'X-Tenant': 'almawave.com',
     'X-Subscription': 'labrd'
   },
@@ -18,6 +19,7 @@ export const auth_client = axios.create({
   baseURL: localDev ? 'http://localhost:3010' : `${contextRoot}/auth`,
   headers: {
     'Content-Type': 'application/json',
+    'X-Requested-With': 'XMLHttpRequest',
     'X-Tenant': 'almawave.com',
     'X-Subscription': 'labrd'
   },
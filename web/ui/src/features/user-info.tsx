@@ -3,12 +3,16 @@ import { useQuery } from 'react-query';
 import { auth_client } from '@features/axios';
 
 export type UserInfo = {
-  email:string;
-  family_name:string;
-  given_name:string;
-  name:string;
-  preferred_username:string;
+  email?:string;
+  family_name?:string;
+  given_name?:string;
+  name?:string;
+  preferred_username?:string;
   logout_url:string;
+  csrf_token:string;
+  roles?:string[];
+  groups?:string[];
+  tenant?:string;
 };
 
 const fetchUserInfo = async (): Promise<UserInfo> => {